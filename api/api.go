@@ -0,0 +1,14 @@
+// Package api embeds the JSON schemas of the juno-txbuild file formats.
+package api
+
+import "embed"
+
+// Schema file names.
+const (
+	TxOutputsSchema = "txoutputs.schema.json"
+	TxPlanV0Schema  = "txplan.v0.schema.json"
+	TxPlanV1Schema  = "txplan.v1.schema.json"
+)
+
+//go:embed *.schema.json
+var Schemas embed.FS
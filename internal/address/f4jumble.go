@@ -0,0 +1,75 @@
+package address
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// F4Jumble (ZIP-316) bounds on the message length in bytes.
+const (
+	f4MinLen = 48
+	f4MaxLen = 4194368
+)
+
+const f4HashLen = 64
+
+func f4H(i byte, u []byte, n int) []byte {
+	var p [16]byte
+	copy(p[:], "UA_F4Jumble_H")
+	p[13] = i
+	return blake2bPersonal(n, p, u)
+}
+
+func f4G(i byte, u []byte, n int) []byte {
+	out := make([]byte, 0, n+f4HashLen)
+	for j := 0; len(out) < n; j++ {
+		var p [16]byte
+		copy(p[:], "UA_F4Jumble_G")
+		p[13] = i
+		binary.LittleEndian.PutUint16(p[14:], uint16(j))
+		out = append(out, blake2bPersonal(f4HashLen, p, u)...)
+	}
+	return out[:n]
+}
+
+func xorInto(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+func f4Split(n int) int {
+	return min(f4HashLen, n/2)
+}
+
+// f4Jumble applies the F4Jumble permutation.
+func f4Jumble(m []byte) ([]byte, error) {
+	if len(m) < f4MinLen || len(m) > f4MaxLen {
+		return nil, errors.New("invalid f4jumble length")
+	}
+	l := f4Split(len(m))
+	a := append([]byte(nil), m[:l]...)
+	b := append([]byte(nil), m[l:]...)
+
+	xorInto(b, f4G(0, a, len(b))) // x
+	xorInto(a, f4H(0, b, len(a))) // y
+	xorInto(b, f4G(1, a, len(b))) // d
+	xorInto(a, f4H(1, b, len(a))) // c
+	return append(a, b...), nil
+}
+
+// f4JumbleInv inverts f4Jumble.
+func f4JumbleInv(m []byte) ([]byte, error) {
+	if len(m) < f4MinLen || len(m) > f4MaxLen {
+		return nil, errors.New("invalid f4jumble length")
+	}
+	l := f4Split(len(m))
+	c := append([]byte(nil), m[:l]...)
+	d := append([]byte(nil), m[l:]...)
+
+	xorInto(c, f4H(1, d, len(c))) // y
+	xorInto(d, f4G(1, c, len(d))) // x
+	xorInto(c, f4H(0, d, len(c))) // a
+	xorInto(d, f4G(0, c, len(d))) // b
+	return append(c, d...), nil
+}
@@ -0,0 +1,86 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OrchardFVKLen is the length of a raw Orchard full viewing key (ak || nk ||
+// rivk).
+const OrchardFVKLen = 96
+
+var fvkLen = map[uint64]int{
+	TypeP2PKH:   65,
+	TypeSapling: 128,
+	TypeOrchard: OrchardFVKLen,
+}
+
+// UnifiedFVK is a decoded unified full viewing key.
+type UnifiedFVK struct {
+	HRP  string
+	Keys []Item
+}
+
+// Orchard returns the raw Orchard full viewing key, if present.
+func (k UnifiedFVK) Orchard() ([]byte, bool) {
+	for _, it := range k.Keys {
+		if it.Typecode == TypeOrchard {
+			return it.Data, true
+		}
+	}
+	return nil, false
+}
+
+// UFVKHRP returns the unified full viewing key HRP for a chain name.
+func UFVKHRP(chain string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(chain)) {
+	case "main":
+		return "jview", nil
+	case "test":
+		return "jviewtest", nil
+	case "regtest":
+		return "jviewregtest", nil
+	default:
+		return "", fmt.Errorf("unknown chain %q", chain)
+	}
+}
+
+// DecodeUFVK decodes a unified full viewing key for chain and returns its
+// Orchard full viewing key.
+func DecodeUFVK(s, chain string) ([]byte, error) {
+	want, err := UFVKHRP(chain)
+	if err != nil {
+		return nil, err
+	}
+	hrp, items, err := decodeUnifiedEncoding(strings.TrimSpace(s), fvkLen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unified full viewing key: %w", err)
+	}
+	if hrp != want {
+		return nil, fmt.Errorf("unexpected viewing key prefix %q (want %q)", hrp, want)
+	}
+	for _, it := range items {
+		if it.Typecode == TypeP2SH {
+			return nil, errors.New("invalid unified full viewing key: P2SH item")
+		}
+	}
+	fvk, ok := UnifiedFVK{HRP: hrp, Keys: items}.Orchard()
+	if !ok {
+		return nil, errors.New("viewing key has no Orchard component")
+	}
+	return fvk, nil
+}
+
+// OrchardAddress encodes a raw Orchard receiver as an Orchard-only unified
+// address for chain.
+func OrchardAddress(receiver []byte, chain string) (string, error) {
+	if len(receiver) != OrchardReceiverLen {
+		return "", errors.New("invalid orchard receiver length")
+	}
+	hrp, err := UnifiedHRP(chain)
+	if err != nil {
+		return "", err
+	}
+	return EncodeUnified(hrp, []Item{{Typecode: TypeOrchard, Data: receiver}})
+}
@@ -0,0 +1,262 @@
+// Package address decodes and validates ZIP-316 unified addresses.
+package address
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Receiver and viewing key typecodes (ZIP-316).
+const (
+	TypeP2PKH   = 0x00
+	TypeP2SH    = 0x01
+	TypeSapling = 0x02
+	TypeOrchard = 0x03
+)
+
+// OrchardReceiverLen is the length of a raw Orchard receiver (diversifier ||
+// pk_d).
+const OrchardReceiverLen = 43
+
+var receiverLen = map[uint64]int{
+	TypeP2PKH:   20,
+	TypeP2SH:    20,
+	TypeSapling: 43,
+	TypeOrchard: OrchardReceiverLen,
+}
+
+// Item is a typed item of a unified encoding: a receiver of a unified address
+// or a key of a unified viewing key.
+type Item struct {
+	Typecode uint64
+	Data     []byte
+}
+
+// UnifiedAddress is a decoded unified address.
+type UnifiedAddress struct {
+	HRP       string
+	Receivers []Item
+}
+
+// Orchard returns the raw Orchard receiver, if present.
+func (ua UnifiedAddress) Orchard() ([]byte, bool) {
+	for _, r := range ua.Receivers {
+		if r.Typecode == TypeOrchard {
+			return r.Data, true
+		}
+	}
+	return nil, false
+}
+
+// UnifiedHRP returns the unified address HRP for a chain name as reported by
+// getblockchaininfo ("main", "test" or "regtest").
+func UnifiedHRP(chain string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(chain)) {
+	case "main":
+		return "j", nil
+	case "test":
+		return "jtest", nil
+	case "regtest":
+		return "jregtest", nil
+	default:
+		return "", fmt.Errorf("unknown chain %q", chain)
+	}
+}
+
+func chainForHRP(hrp string) string {
+	for _, c := range []string{"main", "test", "regtest"} {
+		if h, _ := UnifiedHRP(c); h == hrp {
+			return c
+		}
+	}
+	return ""
+}
+
+// DecodeUnified decodes a unified address: Bech32m, F4Jumble and the
+// receiver encoding are all checked.
+func DecodeUnified(s string) (UnifiedAddress, error) {
+	hrp, items, err := decodeUnifiedEncoding(s, receiverLen)
+	if err != nil {
+		return UnifiedAddress{}, err
+	}
+	transparent, shielded := 0, 0
+	for _, r := range items {
+		switch r.Typecode {
+		case TypeP2PKH, TypeP2SH:
+			transparent++
+		default:
+			shielded++
+		}
+	}
+	if transparent > 1 {
+		return UnifiedAddress{}, errors.New("both P2PKH and P2SH receivers")
+	}
+	if shielded == 0 {
+		return UnifiedAddress{}, errors.New("transparent receivers only")
+	}
+	return UnifiedAddress{HRP: hrp, Receivers: items}, nil
+}
+
+// decodeUnifiedEncoding decodes the ZIP-316 encoding shared by unified
+// addresses and viewing keys. itemLen holds the required lengths of known
+// typecodes.
+func decodeUnifiedEncoding(s string, itemLen map[uint64]int) (string, []Item, error) {
+	hrp, data, err := bech32mDecode(s)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid bech32m: %w", err)
+	}
+	if len(hrp) > 16 {
+		return "", nil, errors.New("prefix too long")
+	}
+	raw, err := convertBits(data, 5, 8, false)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid bech32m: %w", err)
+	}
+	raw, err = f4JumbleInv(raw)
+	if err != nil {
+		return "", nil, errors.New("invalid length")
+	}
+
+	var pad [16]byte
+	copy(pad[:], hrp)
+	if !bytes.Equal(raw[len(raw)-16:], pad[:]) {
+		return "", nil, errors.New("invalid padding")
+	}
+	raw = raw[:len(raw)-16]
+
+	var items []Item
+	for len(raw) > 0 {
+		typecode, n, err := readCompactSize(raw)
+		if err != nil {
+			return "", nil, err
+		}
+		raw = raw[n:]
+		length, n, err := readCompactSize(raw)
+		if err != nil {
+			return "", nil, err
+		}
+		raw = raw[n:]
+		if length > uint64(len(raw)) {
+			return "", nil, errors.New("truncated item")
+		}
+
+		if k := len(items); k > 0 && typecode <= items[k-1].Typecode {
+			return "", nil, errors.New("items out of order or duplicated")
+		}
+		if want, ok := itemLen[typecode]; ok && int(length) != want {
+			return "", nil, fmt.Errorf("invalid length for type %d", typecode)
+		}
+		if typecode >= 0xE0 && typecode <= 0xFC {
+			return "", nil, fmt.Errorf("unsupported metadata type %d", typecode)
+		}
+		items = append(items, Item{Typecode: typecode, Data: append([]byte(nil), raw[:length]...)})
+		raw = raw[length:]
+	}
+	if len(items) == 0 {
+		return "", nil, errors.New("no items")
+	}
+	return hrp, items, nil
+}
+
+// EncodeUnified encodes receivers (in ascending typecode order) as a unified
+// address with the given HRP.
+func EncodeUnified(hrp string, receivers []Item) (string, error) {
+	if hrp == "" || len(hrp) > 16 {
+		return "", errors.New("invalid prefix")
+	}
+	var raw []byte
+	for i, r := range receivers {
+		if i > 0 && r.Typecode <= receivers[i-1].Typecode {
+			return "", errors.New("receivers out of order or duplicated")
+		}
+		raw = appendCompactSize(raw, r.Typecode)
+		raw = appendCompactSize(raw, uint64(len(r.Data)))
+		raw = append(raw, r.Data...)
+	}
+	var pad [16]byte
+	copy(pad[:], hrp)
+	raw = append(raw, pad[:]...)
+
+	jumbled, err := f4Jumble(raw)
+	if err != nil {
+		return "", err
+	}
+	data, err := convertBits(jumbled, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32mEncode(hrp, data), nil
+}
+
+// ValidateOrchard checks that s is a unified address for chain with an
+// Orchard receiver.
+func ValidateOrchard(s, chain string) error {
+	want, err := UnifiedHRP(chain)
+	if err != nil {
+		return err
+	}
+	ua, err := DecodeUnified(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid unified address: %w", err)
+	}
+	if ua.HRP != want {
+		if c := chainForHRP(ua.HRP); c != "" {
+			return fmt.Errorf("address is for chain %q, node is on %q", c, chain)
+		}
+		return fmt.Errorf("unexpected address prefix %q (want %q)", ua.HRP, want)
+	}
+	if _, ok := ua.Orchard(); !ok {
+		return errors.New("address has no Orchard receiver")
+	}
+	return nil
+}
+
+func readCompactSize(b []byte) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, errors.New("truncated item")
+	}
+	var v uint64
+	var n int
+	switch b[0] {
+	case 0xfd:
+		n = 3
+		if len(b) >= n {
+			v = uint64(binary.LittleEndian.Uint16(b[1:]))
+		}
+	case 0xfe:
+		n = 5
+		if len(b) >= n {
+			v = uint64(binary.LittleEndian.Uint32(b[1:]))
+		}
+	case 0xff:
+		n = 9
+		if len(b) >= n {
+			v = binary.LittleEndian.Uint64(b[1:])
+		}
+	default:
+		return uint64(b[0]), 1, nil
+	}
+	if len(b) < n {
+		return 0, 0, errors.New("truncated item")
+	}
+	if len(appendCompactSize(nil, v)) != n {
+		return 0, 0, errors.New("non-canonical compact size")
+	}
+	return v, n, nil
+}
+
+func appendCompactSize(b []byte, v uint64) []byte {
+	switch {
+	case v < 0xfd:
+		return append(b, byte(v))
+	case v <= 0xffff:
+		return binary.LittleEndian.AppendUint16(append(b, 0xfd), uint16(v))
+	case v <= 0xffffffff:
+		return binary.LittleEndian.AppendUint32(append(b, 0xfe), uint32(v))
+	default:
+		return binary.LittleEndian.AppendUint64(append(b, 0xff), v)
+	}
+}
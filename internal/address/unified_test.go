@@ -0,0 +1,198 @@
+package address
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestF4Jumble_ZIP316Vector(t *testing.T) {
+	in, _ := hex.DecodeString("5d7a8f739a2d9e945b0ce152a8049e294c4d6e66b164939daffa2ef6ee6921481cdd86b3cc4318d9614fc820905d042b")
+	want, _ := hex.DecodeString("0304d029141b995da5387c125970673504d6c764d91ea6c082123770c7139ccd88ee27368cd0c0921a0444c8e5858d22")
+
+	got, err := f4Jumble(in)
+	if err != nil {
+		t.Fatalf("f4Jumble: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("f4Jumble=%x want %x", got, want)
+	}
+	back, err := f4JumbleInv(got)
+	if err != nil {
+		t.Fatalf("f4JumbleInv: %v", err)
+	}
+	if !bytes.Equal(back, in) {
+		t.Fatalf("f4JumbleInv=%x want %x", back, in)
+	}
+}
+
+func TestF4Jumble_LongRoundTrip(t *testing.T) {
+	in := make([]byte, 200)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	want, _ := hex.DecodeString("b23b9554c2ac6e0222c9546061472c0d67a83a782d4cbe7c7564cd5068adf74056036dabf15136f739a0703313c9888c34b51550424912a93fac0b0c87dbba19cbe304296511b8b26e4db1033c24e207e78de0834e17f41bd303cbfe6c70a306ed5a8e5fa88450779776cd0a5c651abfffcb49cf25db47a80ac1c6b80ecf963f2a33038fd0add7240185a86c6ddb422493fe3e3d1a3f7a5e0e10886d638ff606a477aaaa01ea0ab6fa1baac3787d525a596f820c4f46b99e71ae3d7d117e5849aedd9ffcc16bbc55")
+
+	got, err := f4Jumble(in)
+	if err != nil {
+		t.Fatalf("f4Jumble: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("f4Jumble=%x", got)
+	}
+	back, err := f4JumbleInv(got)
+	if err != nil || !bytes.Equal(back, in) {
+		t.Fatalf("round trip failed: %v", err)
+	}
+}
+
+func TestBech32mDecode_BIP350Vectors(t *testing.T) {
+	for _, s := range []string{
+		"A1LQFN3A",
+		"a1lqfn3a",
+		"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx",
+	} {
+		if _, _, err := bech32mDecode(s); err != nil {
+			t.Fatalf("bech32mDecode(%q): %v", s, err)
+		}
+	}
+	for _, s := range []string{
+		"a1lqfn3b",   // checksum
+		"A1lqfn3a",   // mixed case
+		"1lqfn3a",    // empty hrp
+		"a1lqfn3",    // short checksum
+		"abc1bqfn3a", // invalid character
+	} {
+		if _, _, err := bech32mDecode(s); err == nil {
+			t.Fatalf("bech32mDecode(%q): expected error", s)
+		}
+	}
+}
+
+func testOrchardReceiver() []byte {
+	r := make([]byte, OrchardReceiverLen)
+	for i := range r {
+		r[i] = byte(0xa0 + i)
+	}
+	return r
+}
+
+func TestUnified_RoundTrip(t *testing.T) {
+	recv := []Item{
+		{Typecode: TypeP2PKH, Data: bytes.Repeat([]byte{0x11}, 20)},
+		{Typecode: TypeOrchard, Data: testOrchardReceiver()},
+	}
+	s, err := EncodeUnified("jregtest", recv)
+	if err != nil {
+		t.Fatalf("EncodeUnified: %v", err)
+	}
+	if !strings.HasPrefix(s, "jregtest1") {
+		t.Fatalf("unexpected address %q", s)
+	}
+
+	ua, err := DecodeUnified(s)
+	if err != nil {
+		t.Fatalf("DecodeUnified: %v", err)
+	}
+	orchard, ok := ua.Orchard()
+	if ua.HRP != "jregtest" || len(ua.Receivers) != 2 || !ok || !bytes.Equal(orchard, testOrchardReceiver()) {
+		t.Fatalf("unexpected decode: %+v", ua)
+	}
+
+	if _, err := DecodeUnified(strings.ToUpper(s)); err != nil {
+		t.Fatalf("DecodeUnified(upper): %v", err)
+	}
+}
+
+func TestValidateOrchard(t *testing.T) {
+	orchardOnly := []Item{{Typecode: TypeOrchard, Data: testOrchardReceiver()}}
+	regtest, _ := EncodeUnified("jregtest", orchardOnly)
+	mainnet, _ := EncodeUnified("j", orchardOnly)
+	saplingOnly, _ := EncodeUnified("jregtest", []Item{{Typecode: TypeSapling, Data: make([]byte, 43)}})
+
+	if err := ValidateOrchard(regtest, "regtest"); err != nil {
+		t.Fatalf("ValidateOrchard: %v", err)
+	}
+
+	typo := []byte(regtest)
+	if typo[len(typo)-1] == 'q' {
+		typo[len(typo)-1] = 'p'
+	} else {
+		typo[len(typo)-1] = 'q'
+	}
+
+	cases := map[string]string{
+		mainnet:      "chain \"main\"",
+		saplingOnly:  "no Orchard receiver",
+		string(typo): "checksum",
+		"jregtest1":  "invalid unified address",
+	}
+	for addr, want := range cases {
+		err := ValidateOrchard(addr, "regtest")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("ValidateOrchard(%q)=%v want %q", addr, err, want)
+		}
+	}
+}
+
+func TestDecodeUnified_RejectsBadReceivers(t *testing.T) {
+	orchard := Item{Typecode: TypeOrchard, Data: testOrchardReceiver()}
+	for name, recv := range map[string][]Item{
+		"transparent only": {{Typecode: TypeP2PKH, Data: make([]byte, 20)}, {Typecode: TypeP2SH, Data: make([]byte, 20)}},
+		"p2pkh and p2sh":   {{Typecode: TypeP2PKH, Data: make([]byte, 20)}, {Typecode: TypeP2SH, Data: make([]byte, 20)}, orchard},
+		"bad length":       {{Typecode: TypeOrchard, Data: make([]byte, 42)}, {Typecode: 0x10, Data: make([]byte, 8)}},
+		"metadata":         {orchard, {Typecode: 0xE0, Data: make([]byte, 8)}},
+	} {
+		s, err := EncodeUnified("jtest", recv)
+		if err != nil {
+			t.Fatalf("%s: EncodeUnified: %v", name, err)
+		}
+		if _, err := DecodeUnified(s); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestBlake2bPersonal(t *testing.T) {
+	var p [16]byte
+	copy(p[:], "0123456789abcdef")
+	got := blake2bPersonal(32, p, []byte("abc"))
+	if hex.EncodeToString(got) != "139dad3b92ad7c4601ae1ba6c616ca59699c6b1e8e789d095e1bec905871f612" {
+		t.Fatalf("blake2b=%x", got)
+	}
+
+	msg := make([]byte, 256)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	copy(p[:], "UA_F4Jumble_G\x01\x02\x00")
+	got = blake2bPersonal(64, p, msg)
+	if hex.EncodeToString(got) != "ed0346f0833cfb8f84bf607cbac5868e8c9bc91e17c66c343ea5e00948a68ffaf3b95a5b10f47eb8bfb1c21b9ebea5829957faf15a5f1b00c705accbad8b5b4a" {
+		t.Fatalf("blake2b=%x", got)
+	}
+}
+
+func TestDecodeUFVK(t *testing.T) {
+	fvk := bytes.Repeat([]byte{0x42}, OrchardFVKLen)
+	s, err := EncodeUnified("jviewregtest", []Item{{Typecode: TypeOrchard, Data: fvk}})
+	if err != nil {
+		t.Fatalf("EncodeUnified: %v", err)
+	}
+
+	got, err := DecodeUFVK(s, "regtest")
+	if err != nil {
+		t.Fatalf("DecodeUFVK: %v", err)
+	}
+	if !bytes.Equal(got, fvk) {
+		t.Fatalf("fvk=%x", got)
+	}
+
+	if _, err := DecodeUFVK(s, "main"); err == nil {
+		t.Fatalf("expected prefix error")
+	}
+	saplingOnly, _ := EncodeUnified("jviewregtest", []Item{{Typecode: TypeSapling, Data: make([]byte, 128)}})
+	if _, err := DecodeUFVK(saplingOnly, "regtest"); err == nil {
+		t.Fatalf("expected missing orchard error")
+	}
+}
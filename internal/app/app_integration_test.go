@@ -335,6 +335,80 @@ func TestIntegration_PlanSweep(t *testing.T) {
 	}
 }
 
+func TestIntegration_PlanSweep_MaxFeeZat(t *testing.T) {
+	jd, _ := startJunocashd(t)
+
+	orchardAddr := unifiedAddress(t, jd, 0)
+	mineAndShieldOnce(t, jd, orchardAddr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	_, err := txbuild.PlanSweep(ctx, txbuild.SweepConfig{
+		RPCURL:  jd.RPCURL,
+		RPCUser: jd.RPCUser,
+		RPCPass: jd.RPCPassword,
+
+		WalletID: "test-wallet",
+		CoinType: 0,
+		Account:  0,
+
+		ToAddress:     orchardAddr,
+		ChangeAddress: orchardAddr,
+
+		MinConfirmations: 1,
+		ExpiryOffset:     40,
+
+		FeeMultiplier: 100_000,
+
+		PlanOptions: txbuild.PlanOptions{MaxFeeZat: 100_000},
+	})
+	var ce types.CodedError
+	if !errors.As(err, &ce) || ce.Code != txbuild.ErrCodeFeeLimitExceeded {
+		t.Fatalf("expected fee_limit_exceeded, got %v", err)
+	}
+}
+
+func TestIntegration_PlanSweep_UFVKChangeAddress(t *testing.T) {
+	jd, _ := startJunocashd(t)
+
+	orchardAddr := unifiedAddress(t, jd, 0)
+	ufvk := unifiedViewingKey(t, jd, orchardAddr)
+	mineAndShieldOnce(t, jd, orchardAddr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	cfg := txbuild.SweepConfig{
+		RPCURL:  jd.RPCURL,
+		RPCUser: jd.RPCUser,
+		RPCPass: jd.RPCPassword,
+
+		WalletID: "test-wallet",
+		CoinType: 0,
+		Account:  0,
+
+		ToAddress: orchardAddr,
+
+		MinConfirmations: 1,
+		ExpiryOffset:     40,
+
+		PlanOptions: txbuild.PlanOptions{UFVK: ufvk},
+	}
+	plan, err := txbuild.PlanSweep(ctx, cfg)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if plan.ChangeAddress == "" || plan.ChangeAddress == orchardAddr {
+		t.Fatalf("change_address=%q, want derived internal address", plan.ChangeAddress)
+	}
+
+	cfg.ChangeAddress = orchardAddr
+	if _, err := txbuild.PlanSweep(ctx, cfg); err != nil {
+		t.Fatalf("plan with owned change address: %v", err)
+	}
+}
+
 func TestIntegration_PlanSendMany(t *testing.T) {
 	jd, _ := startJunocashd(t)
 
@@ -355,9 +429,9 @@ func TestIntegration_PlanSendMany(t *testing.T) {
 		Account:  0,
 
 		Kind: types.TxPlanKindWithdrawal,
-		Outputs: []types.TxOutput{
-			{ToAddress: toAddr, AmountZat: "1000000"},
-			{ToAddress: toAddr, AmountZat: "2000000"},
+		Outputs: []txbuild.TxOutput{
+			{TxOutput: types.TxOutput{ToAddress: toAddr, AmountZat: "1000000"}},
+			{TxOutput: types.TxOutput{ToAddress: toAddr, AmountZat: "2000000"}},
 		},
 		ChangeAddress: changeAddr,
 
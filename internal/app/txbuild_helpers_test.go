@@ -72,6 +72,19 @@ func unifiedAddress(t *testing.T, jd *containers.Junocashd, account uint32) stri
 	return addr
 }
 
+func unifiedViewingKey(t *testing.T, jd *containers.Junocashd, addr string) string {
+	t.Helper()
+	raw, err := jd.ExecCLI(context.Background(), "z_exportviewingkey", addr)
+	if err != nil {
+		t.Fatalf("z_exportviewingkey: %v", err)
+	}
+	ufvk := strings.Trim(strings.TrimSpace(string(raw)), "\"")
+	if ufvk == "" {
+		t.Fatalf("z_exportviewingkey: missing key")
+	}
+	return ufvk
+}
+
 func mineAndShieldOnce(t *testing.T, jd *containers.Junocashd, orchardAddr string) {
 	t.Helper()
 	ctx := context.Background()
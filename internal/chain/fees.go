@@ -0,0 +1,203 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Abdullah1738/juno-txbuild/internal/logic"
+)
+
+type feeTx struct {
+	TxID string `json:"txid"`
+	Vin  []struct {
+		Coinbase string `json:"coinbase"`
+		TxID     string `json:"txid"`
+		Vout     uint32 `json:"vout"`
+	} `json:"vin"`
+	Vout            []feeTxOut        `json:"vout"`
+	VJoinSplit      []json.RawMessage `json:"vjoinsplit"`
+	VShieldedSpend  []json.RawMessage `json:"vShieldedSpend"`
+	VShieldedOutput []json.RawMessage `json:"vShieldedOutput"`
+	ValueBalanceZat int64             `json:"valueBalanceZat"`
+	Orchard         struct {
+		Actions         []json.RawMessage `json:"actions"`
+		ValueBalanceZat int64             `json:"valueBalanceZat"`
+	} `json:"orchard"`
+}
+
+type feeTxOut struct {
+	ValueZat int64 `json:"valueZat"`
+}
+
+func (t feeTx) isCoinbase() bool {
+	return len(t.Vin) > 0 && strings.TrimSpace(t.Vin[0].Coinbase) != ""
+}
+
+func (t feeTx) logicalActions() int {
+	return logic.TxLogicalActions(len(t.Vin), len(t.Vout), len(t.VJoinSplit), len(t.VShieldedSpend), len(t.VShieldedOutput), len(t.Orchard.Actions))
+}
+
+// Bounds on the RPC calls made for fee estimation.
+const (
+	// MaxPrevoutLookups caps the getrawtransaction calls RecentBlockFees makes
+	// to resolve transparent prevouts.
+	MaxPrevoutLookups = 500
+	// MaxMempoolSamples caps the mempool transactions MempoolFees samples.
+	MaxMempoolSamples = 200
+)
+
+// RecentBlockFees returns the fee samples of the non-coinbase transactions in
+// the count blocks ending at tipHeight, one slice per block.
+//
+// Fees are derived from value balances, so transparent inputs are resolved via
+// getrawtransaction, at most MaxPrevoutLookups times per call. Transactions
+// whose fee cannot be determined (Sprout JoinSplits, unresolvable prevouts,
+// prevouts beyond the lookup limit) are skipped.
+func RecentBlockFees(ctx context.Context, rpc RPC, tipHeight int64, count int) ([][]logic.FeeSample, error) {
+	if rpc == nil {
+		return nil, errors.New("chain: rpc is nil")
+	}
+	if count <= 0 {
+		return nil, errors.New("chain: block count must be > 0")
+	}
+
+	prevouts := &prevoutCache{outs: make(map[string][]feeTxOut), budget: MaxPrevoutLookups}
+	out := make([][]logic.FeeSample, 0, count)
+	for height := tipHeight; height >= 0 && height > tipHeight-int64(count); height-- {
+		var hash string
+		if err := rpc.Call(ctx, "getblockhash", []any{height}, &hash); err != nil {
+			return nil, err
+		}
+		var blk struct {
+			Tx []feeTx `json:"tx"`
+		}
+		if err := rpc.Call(ctx, "getblock", []any{hash, 2}, &blk); err != nil {
+			return nil, err
+		}
+
+		samples := make([]logic.FeeSample, 0, len(blk.Tx))
+		for _, t := range blk.Tx {
+			if t.isCoinbase() {
+				continue
+			}
+			fee, ok, err := txFee(ctx, rpc, prevouts, t)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			samples = append(samples, logic.FeeSample{FeeZat: fee, Actions: t.logicalActions()})
+		}
+		out = append(out, samples)
+	}
+	return out, nil
+}
+
+// MempoolInfo is the subset of getmempoolinfo used for fee estimation.
+type MempoolInfo struct {
+	Size  int64 `json:"size"`
+	Bytes int64 `json:"bytes"`
+}
+
+// MempoolFees returns the fee samples of the transactions currently in the
+// mempool, using the fees reported by getrawmempool. Each sample takes a
+// getrawtransaction call, so only the MaxMempoolSamples most recent entries
+// are sampled; info still describes the whole mempool.
+func MempoolFees(ctx context.Context, rpc RPC) ([]logic.FeeSample, MempoolInfo, error) {
+	if rpc == nil {
+		return nil, MempoolInfo{}, errors.New("chain: rpc is nil")
+	}
+
+	var info MempoolInfo
+	if err := rpc.Call(ctx, "getmempoolinfo", nil, &info); err != nil {
+		return nil, MempoolInfo{}, err
+	}
+
+	var entries map[string]struct {
+		Fee  json.Number `json:"fee"`
+		Time int64       `json:"time"`
+	}
+	if err := rpc.Call(ctx, "getrawmempool", []any{true}, &entries); err != nil {
+		return nil, MempoolInfo{}, err
+	}
+
+	txids := make([]string, 0, len(entries))
+	for txid := range entries {
+		txids = append(txids, txid)
+	}
+	sort.Slice(txids, func(i, j int) bool {
+		if ti, tj := entries[txids[i]].Time, entries[txids[j]].Time; ti != tj {
+			return ti > tj
+		}
+		return txids[i] < txids[j]
+	})
+	if len(txids) > MaxMempoolSamples {
+		txids = txids[:MaxMempoolSamples]
+	}
+
+	out := make([]logic.FeeSample, 0, len(txids))
+	for _, txid := range txids {
+		fee, err := logic.ParseZECToZat(entries[txid].Fee.String())
+		if err != nil {
+			return nil, MempoolInfo{}, fmt.Errorf("chain: invalid mempool fee for %s", txid)
+		}
+		var t feeTx
+		if err := rpc.Call(ctx, "getrawtransaction", []any{txid, 1}, &t); err != nil {
+			// Mined or evicted since getrawmempool.
+			continue
+		}
+		out = append(out, logic.FeeSample{FeeZat: fee, Actions: t.logicalActions()})
+	}
+	return out, info, nil
+}
+
+// prevoutCache holds the outputs of resolved prevout transactions and the
+// number of getrawtransaction lookups left.
+type prevoutCache struct {
+	outs   map[string][]feeTxOut
+	budget int
+}
+
+func txFee(ctx context.Context, rpc RPC, prevouts *prevoutCache, t feeTx) (uint64, bool, error) {
+	if len(t.VJoinSplit) > 0 {
+		return 0, false, nil
+	}
+
+	// fee = transparent in - transparent out + Sapling/Orchard value balances.
+	fee := t.ValueBalanceZat + t.Orchard.ValueBalanceZat
+	for _, in := range t.Vin {
+		txid := strings.ToLower(strings.TrimSpace(in.TxID))
+		outs, ok := prevouts.outs[txid]
+		if !ok {
+			if prevouts.budget <= 0 {
+				return 0, false, nil
+			}
+			prevouts.budget--
+			var prev struct {
+				Vout []feeTxOut `json:"vout"`
+			}
+			if err := rpc.Call(ctx, "getrawtransaction", []any{txid, 1}, &prev); err != nil {
+				// Without -txindex, spent prevouts may be unavailable.
+				return 0, false, nil
+			}
+			outs = prev.Vout
+			prevouts.outs[txid] = outs
+		}
+		if int(in.Vout) >= len(outs) {
+			return 0, false, nil
+		}
+		fee += outs[in.Vout].ValueZat
+	}
+	for _, o := range t.Vout {
+		fee -= o.ValueZat
+	}
+	if fee < 0 {
+		return 0, false, nil
+	}
+	return uint64(fee), true, nil
+}
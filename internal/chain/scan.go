@@ -16,9 +16,11 @@ type RPC interface {
 }
 
 type ChainInfo struct {
-	Chain    string
-	Height   int64
-	BranchID uint32
+	Chain  string
+	Height int64
+	// BestBlockHash is the hash of the block at Height.
+	BestBlockHash string
+	BranchID      uint32
 }
 
 func GetChainInfo(ctx context.Context, rpc RPC) (ChainInfo, error) {
@@ -27,9 +29,10 @@ func GetChainInfo(ctx context.Context, rpc RPC) (ChainInfo, error) {
 	}
 
 	var resp struct {
-		Chain     string `json:"chain"`
-		Blocks    int64  `json:"blocks"`
-		Consensus struct {
+		Chain         string `json:"chain"`
+		Blocks        int64  `json:"blocks"`
+		BestBlockHash string `json:"bestblockhash"`
+		Consensus     struct {
 			Chaintip string `json:"chaintip"`
 		} `json:"consensus"`
 	}
@@ -52,9 +55,10 @@ func GetChainInfo(ctx context.Context, rpc RPC) (ChainInfo, error) {
 	}
 
 	return ChainInfo{
-		Chain:    chain,
-		Height:   resp.Blocks,
-		BranchID: uint32(branchU64),
+		Chain:         chain,
+		Height:        resp.Blocks,
+		BestBlockHash: strings.ToLower(strings.TrimSpace(resp.BestBlockHash)),
+		BranchID:      uint32(branchU64),
 	}, nil
 }
 
@@ -66,6 +70,9 @@ type OrchardAction struct {
 	CMX           string
 	EphemeralKey  string
 	EncCiphertext string
+	// Block containing the action.
+	Height    uint32
+	BlockHash string
 }
 
 type OrchardIndex struct {
@@ -127,6 +134,8 @@ func BuildOrchardIndex(ctx context.Context, rpc *junocashd.Client, upToHeight in
 					CMX:           strings.ToLower(strings.TrimSpace(a.CMX)),
 					EphemeralKey:  strings.ToLower(strings.TrimSpace(a.EphemeralKey)),
 					EncCiphertext: strings.ToLower(strings.TrimSpace(a.EncCiphertext)),
+					Height:        uint32(height),
+					BlockHash:     strings.ToLower(strings.TrimSpace(blockHash)),
 				}
 
 				if !is32ByteHex(act.CMX) || !is32ByteHex(act.Nullifier) || !is32ByteHex(act.EphemeralKey) {
@@ -0,0 +1,63 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Abdullah1738/juno-sdk-go/junocashd"
+)
+
+// TxStatus is what the node knows about a transaction.
+type TxStatus int
+
+const (
+	// The node does not know the transaction. Without -txindex it only knows
+	// mined transactions of its own wallet.
+	TxUnknown TxStatus = iota
+	// The transaction is in the node wallet or the mempool, but not in a block
+	// of the active chain.
+	TxNotMined
+	// The transaction is in a block of the active chain.
+	TxMined
+)
+
+// GetTxStatus looks txid up in the node wallet (gettransaction), then in the
+// mempool and transaction index (getrawtransaction).
+func GetTxStatus(ctx context.Context, rpc RPC, txid string) (TxStatus, error) {
+	if rpc == nil {
+		return TxUnknown, errors.New("chain: rpc is nil")
+	}
+	txid = strings.ToLower(strings.TrimSpace(txid))
+	if txid == "" {
+		return TxUnknown, nil
+	}
+
+	var tx struct {
+		Confirmations int64  `json:"confirmations"`
+		BlockHash     string `json:"blockhash"`
+	}
+	for _, call := range []struct {
+		method string
+		params []any
+	}{
+		{"gettransaction", []any{txid}},
+		{"getrawtransaction", []any{txid, 1}},
+	} {
+		err := rpc.Call(ctx, call.method, call.params, &tx)
+		var rpcErr *junocashd.RPCError
+		if errors.As(err, &rpcErr) {
+			// Not in the wallet, the mempool or the index.
+			continue
+		}
+		if err != nil {
+			return TxUnknown, err
+		}
+		// Conflicted wallet transactions report negative confirmations.
+		if tx.Confirmations > 0 {
+			return TxMined, nil
+		}
+		return TxNotMined, nil
+	}
+	return TxUnknown, nil
+}
@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/config"
+	"github.com/Abdullah1738/juno-txbuild/internal/keys"
+	"github.com/Abdullah1738/juno-txbuild/internal/pkcs11key"
+	"github.com/Abdullah1738/juno-txbuild/pkg/plansig"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// readApprovals decodes an approvals file strictly.
+func readApprovals(data []byte) (plansig.Approvals, error) {
+	var a plansig.Approvals
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&a); err != nil {
+		return plansig.Approvals{}, types.CodedError{Code: plansig.ErrCodeInvalidSignature, Message: "invalid approvals json"}
+	}
+	return a, nil
+}
+
+func runApprove(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("approve", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var keyPath string
+	var p11 pkcs11key.Config
+	var approvalsPath string
+	var jsonOut bool
+
+	fs.StringVar(&keyPath, "key", "", "Ed25519 private key of the approver (PEM PKCS #8)")
+	fs.StringVar(&p11.Module, "pkcs11-module", "", "PKCS#11 module path, to approve with a token key instead of --key")
+	fs.StringVar(&p11.Token, "pkcs11-token", "", "PKCS#11 token label")
+	fs.StringVar(&p11.KeyLabel, "pkcs11-key", "", "PKCS#11 key label (CKA_LABEL of the Ed25519 key pair)")
+	fs.StringVar(&p11.PIN, "pkcs11-pin", "", "PKCS#11 user PIN (or JUNO_PKCS11_PIN)")
+	fs.StringVar(&approvalsPath, "approvals", "", "approvals file to add the approval to (created if missing)")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if strings.TrimSpace(approvalsPath) == "" {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "approvals is required")
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "approve takes exactly one plan file (or -)")
+	}
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	if err := checkSignablePlan(data); err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, txbuild.ErrCodeInvalidPlan, err.Error())
+	}
+	planID, err := txbuild.PlanIDJSON(data)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "invalid txplan json: "+err.Error())
+	}
+
+	approvals := plansig.NewApprovals(planID)
+	existing, err := os.ReadFile(approvalsPath)
+	switch {
+	case err == nil:
+		if approvals, err = readApprovals(existing); err != nil {
+			var ce types.CodedError
+			errors.As(err, &ce)
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		if approvals.PlanID != planID {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("%s holds approvals of plan %s, not %s", filepath.Base(approvalsPath), approvals.PlanID, planID))
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("read %s: %v", filepath.Base(approvalsPath), err))
+	}
+
+	signer, closeSigner, err := loadSigner(keyPath, p11)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	defer closeSigner()
+
+	approvals, err = plansig.Approve(approvals, signer, time.Now())
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	b, err := json.MarshalIndent(approvals, "", "  ")
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "marshal approvals")
+	}
+	if err := os.WriteFile(approvalsPath, append(b, '\n'), 0o600); err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("write %s: %v", filepath.Base(approvalsPath), err))
+	}
+
+	publicKey := approvals.Approvals[len(approvals.Approvals)-1].PublicKey
+	if jsonOut {
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data": map[string]any{
+				"plan_id":    planID,
+				"public_key": publicKey,
+				"approvals":  len(approvals.Approvals),
+			},
+		})
+		return 0
+	}
+	fmt.Fprintf(stdout, "approved plan %s with %s (%d approvals)\n", planID, publicKey, len(approvals.Approvals))
+	return 0
+}
+
+func runCheckApprovals(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check-approvals", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var approvalsPath string
+	var configPath string
+	var jsonOut bool
+
+	fs.StringVar(&approvalsPath, "approvals", "", "approvals file written by approve")
+	fs.StringVar(&configPath, "config", "", "config file with the approval policy (or JUNO_TXBUILD_CONFIG)")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if strings.TrimSpace(approvalsPath) == "" {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "approvals is required")
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "check-approvals takes exactly one plan file (or -)")
+	}
+	cfg, ok, err := loadConfig(configPath)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	if !ok {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "check-approvals requires --config (or JUNO_TXBUILD_CONFIG)")
+	}
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	if err := checkSignablePlan(data); err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, txbuild.ErrCodeInvalidPlan, err.Error())
+	}
+	var plan txbuild.TxPlan
+	_ = json.Unmarshal(data, &plan)
+	planID, err := txbuild.PlanIDJSON(data)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "invalid txplan json: "+err.Error())
+	}
+	approvalsData, err := readInput(approvalsPath)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	approvals, err := readApprovals(approvalsData)
+	if err != nil {
+		var ce types.CodedError
+		errors.As(err, &ce)
+		return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+	}
+
+	if err := checkWalletChange(cfg.Wallet(plan.WalletID), plan); err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	amountZat, err := approvalAmountZat(plan)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, txbuild.ErrCodeInvalidPlan, err.Error())
+	}
+	tier, ok := cfg.ApprovalTier(plan.WalletID, amountZat)
+	if !ok {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("no approval policy for wallet %q", plan.WalletID))
+	}
+	q := plansig.Quorum{Threshold: tier.Threshold}
+	for _, a := range tier.Approvers {
+		pub, _ := hex.DecodeString(a.PublicKey)
+		q.Approvers = append(q.Approvers, plansig.TrustedKey{PublicKey: pub, Name: a.Name})
+	}
+
+	approved, err := plansig.CheckApprovals(planID, approvals, q)
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, plansig.ErrCodeInvalidSignature, err.Error())
+	}
+	names := make([]string, 0, len(approved))
+	for _, k := range approved {
+		names = append(names, k.Name)
+	}
+
+	if jsonOut {
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data": map[string]any{
+				"plan_id":     planID,
+				"wallet_id":   plan.WalletID,
+				"amount_zat":  strconv.FormatUint(amountZat, 10),
+				"threshold":   tier.Threshold,
+				"approvers":   len(tier.Approvers),
+				"approved_by": names,
+			},
+		})
+		return 0
+	}
+	if tier.Threshold == 0 {
+		fmt.Fprintf(stdout, "plan %s: no approvals required for %d zat\n", planID, amountZat)
+		return 0
+	}
+	fmt.Fprintf(stdout, "plan %s: approved %d of %d (need %d): %s\n", planID, len(approved), len(tier.Approvers), tier.Threshold, strings.Join(names, ", "))
+	return 0
+}
+
+// addressScope is keys.AddressScope, replaced in tests.
+var addressScope = keys.AddressScope
+
+// checkWalletChange checks that a plan's change goes back to its wallet: an
+// internal address of the wallet's UFVK. Only then do the outputs and fee
+// account for everything the plan takes from the wallet.
+func checkWalletChange(w config.Wallet, plan txbuild.TxPlan) error {
+	if strings.TrimSpace(w.UFVK) == "" {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("no ufvk for wallet %q (wallets.<wallet-id>.ufvk in the config file)", plan.WalletID)}
+	}
+	scope, err := addressScope(w.UFVK, plan.ChangeAddress, plan.Chain)
+	if err != nil {
+		return types.CodedError{Code: txbuild.ErrCodeInvalidPlan, Message: "change_address: " + err.Error()}
+	}
+	if scope != keys.ScopeInternal {
+		return types.CodedError{Code: txbuild.ErrCodeInvalidPlan, Message: fmt.Sprintf("change_address is not an internal address of wallet %q", plan.WalletID)}
+	}
+	return nil
+}
+
+// approvalAmountZat returns the amount that selects a plan's approval tier:
+// what it takes from the wallet, the outputs plus the fee.
+func approvalAmountZat(plan txbuild.TxPlan) (uint64, error) {
+	fee, err := strconv.ParseUint(plan.FeeZat, 10, 64)
+	if err != nil {
+		return 0, errors.New("txplan: fee_zat invalid")
+	}
+	total, err := outputsZat(plan)
+	if err != nil {
+		return 0, err
+	}
+	if total+fee < total {
+		return 0, errors.New("txplan: outputs and fee overflow")
+	}
+	return total + fee, nil
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/keys"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// writeTestKey writes a PEM Ed25519 key derived from seed and returns its
+// path and hex public key.
+func writeTestKey(t *testing.T, dir string, seed byte) (string, string) {
+	t.Helper()
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	path := filepath.Join(dir, "key-"+hex.EncodeToString([]byte{seed})+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	return path, hex.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+func TestRunApproveCheckApprovals(t *testing.T) {
+	dir := t.TempDir()
+	aliceKey, alicePub := writeTestKey(t, dir, 1)
+	bobKey, bobPub := writeTestKey(t, dir, 2)
+	_, carolPub := writeTestKey(t, dir, 3)
+	malloryKey, _ := writeTestKey(t, dir, 4)
+
+	scopes := map[string]string{"j1change": keys.ScopeInternal}
+	addressScope = func(ufvk, addr, chain string) (string, error) {
+		return scopes[addr], nil
+	}
+	t.Cleanup(func() { addressScope = keys.AddressScope })
+
+	configPath := filepath.Join(dir, "config.json")
+	config := `{"wallets": {"cold": {"ufvk": "jviewregtest1cold"}, "hot": {"ufvk": "jviewregtest1hot", "approvals": [
+  {"min_amount_zat": 50000, "threshold": 2, "approvers": [
+    {"name": "alice", "public_key": "` + alicePub + `"},
+    {"name": "bob", "public_key": "` + bobPub + `"},
+    {"name": "carol", "public_key": "` + carolPub + `"}
+  ]}
+]}}}`
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	plan, err := testPlan().WithID()
+	if err != nil {
+		t.Fatalf("WithID: %v", err)
+	}
+	b, _ := json.MarshalIndent(plan, "", "  ")
+	planPath := filepath.Join(dir, "plan.json")
+	if err := os.WriteFile(planPath, b, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	approvalsPath := filepath.Join(dir, "plan.approvals.json")
+
+	var out, errBuf bytes.Buffer
+	check := func() int {
+		out.Reset()
+		return RunWithIO([]string{"check-approvals", "--json", "--config", configPath, "--approvals", approvalsPath, planPath}, &out, &errBuf)
+	}
+
+	for _, key := range []string{aliceKey, malloryKey} {
+		if code := RunWithIO([]string{"approve", "--key", key, "--approvals", approvalsPath, planPath}, &out, &errBuf); code != 0 {
+			t.Fatalf("approve: exit %d (%s)", code, errBuf.String())
+		}
+	}
+	if code := check(); code != 1 || !strings.Contains(out.String(), `"insufficient_approvals"`) {
+		t.Fatalf("check-approvals with 1 of 2: exit %d (%s)", code, out.String())
+	}
+
+	if code := RunWithIO([]string{"approve", "--key", bobKey, "--approvals", approvalsPath, planPath}, &out, &errBuf); code != 0 {
+		t.Fatalf("approve: exit %d (%s)", code, errBuf.String())
+	}
+	if code := check(); code != 0 {
+		t.Fatalf("check-approvals: exit %d (%s)", code, out.String())
+	}
+	var resp struct {
+		Data struct {
+			Threshold  int      `json:"threshold"`
+			ApprovedBy []string `json:"approved_by"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if resp.Data.Threshold != 2 || strings.Join(resp.Data.ApprovedBy, ",") != "alice,bob" {
+		t.Fatalf("unexpected result: %s", out.String())
+	}
+
+	// Approvals do not carry over to a different plan.
+	other := testPlan()
+	other.FeeZat = "20000"
+	other, _ = other.WithID()
+	b, _ = json.MarshalIndent(other, "", "  ")
+	if err := os.WriteFile(planPath, b, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if code := check(); code != 1 || !strings.Contains(out.String(), `"invalid_signature"`) {
+		t.Fatalf("check-approvals of another plan: exit %d (%s)", code, out.String())
+	}
+	if code := RunWithIO([]string{"approve", "--key", aliceKey, "--approvals", approvalsPath, planPath}, &out, &errBuf); code != 1 {
+		t.Fatalf("approve into another plan's file: exit %d", code)
+	}
+
+	// Change must go back to the wallet, and the wallet must have tiers.
+	for name, tc := range map[string]struct {
+		mutate func(*txbuild.TxPlan)
+		want   string
+	}{
+		"external change": {func(p *txbuild.TxPlan) { scopes["j1change"] = keys.ScopeExternal }, `"invalid_plan"`},
+		"foreign change":  {func(p *txbuild.TxPlan) { p.ChangeAddress = "j1evil" }, `"invalid_plan"`},
+		"other wallet":    {func(p *txbuild.TxPlan) { p.WalletID = "cold" }, "no approval policy"},
+		"unknown wallet":  {func(p *txbuild.TxPlan) { p.WalletID = "unknown" }, "no ufvk"},
+	} {
+		scopes["j1change"] = keys.ScopeInternal
+		p := testPlan()
+		tc.mutate(&p)
+		p, _ = p.WithID()
+		b, _ = json.MarshalIndent(p, "", "  ")
+		if err := os.WriteFile(planPath, b, 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if code := check(); code != 1 || !strings.Contains(out.String(), tc.want) {
+			t.Fatalf("%s: exit %d (%s)", name, code, out.String())
+		}
+	}
+}
+
+func TestApprovalAmountZat(t *testing.T) {
+	plan := testPlan()
+	if got, err := approvalAmountZat(plan); err != nil || got != 110000 {
+		t.Fatalf("approvalAmountZat=%d err=%v", got, err)
+	}
+	plan.Outputs = append(plan.Outputs, txbuild.TxOutput{TxOutput: types.TxOutput{ToAddress: "j1b", AmountZat: "18446744073709551615"}})
+	if _, err := approvalAmountZat(plan); err == nil {
+		t.Fatalf("expected overflow error")
+	}
+	plan = testPlan()
+	plan.FeeZat = "x"
+	if _, err := approvalAmountZat(plan); err == nil {
+		t.Fatalf("expected fee error")
+	}
+}
@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -9,10 +12,18 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/api"
+	"github.com/Abdullah1738/juno-txbuild/internal/config"
+	"github.com/Abdullah1738/juno-txbuild/internal/memo"
+	"github.com/Abdullah1738/juno-txbuild/internal/pczt"
+	"github.com/Abdullah1738/juno-txbuild/internal/schema"
+	"github.com/Abdullah1738/juno-txbuild/internal/zip321"
 	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
 )
 
@@ -42,6 +53,36 @@ func RunWithIO(args []string, stdout, stderr io.Writer) int {
 		return runConsolidate(args[1:], stdout, stderr)
 	case "rebalance":
 		return runPlanOutputs(args[1:], types.TxPlanKindRebalance, stdout, stderr)
+	case "estimate-fee":
+		return runEstimateFee(args[1:], stdout, stderr)
+	case "validate":
+		return runValidate(args[1:], stdout, stderr)
+	case "mark-broadcast":
+		return runMarkBroadcast(args[1:], stdout, stderr)
+	case "convert":
+		return runConvert(args[1:], stdout, stderr)
+	case "hash":
+		return runHash(args[1:], stdout, stderr)
+	case "sign":
+		return runSign(args[1:], stdout, stderr)
+	case "verify-signature":
+		return runVerifySignature(args[1:], stdout, stderr)
+	case "approve":
+		return runApprove(args[1:], stdout, stderr)
+	case "check-approvals":
+		return runCheckApprovals(args[1:], stdout, stderr)
+	case "decrypt":
+		return runDecrypt(args[1:], stdout, stderr)
+	case "export-qr":
+		return runExportQR(args[1:], stdout, stderr)
+	case "import-qr":
+		return runImportQR(args[1:], stdout, stderr)
+	case "export-pczt":
+		return runExportPCZT(args[1:], stdout, stderr)
+	case "import-pczt":
+		return runImportPCZT(args[1:], stdout, stderr)
+	case "tx-skeleton":
+		return runTxSkeleton(args[1:], stdout, stderr)
 	default:
 		fmt.Fprintf(stderr, "unknown command: %s\n\n", args[0])
 		writeUsage(stderr)
@@ -52,120 +93,142 @@ func RunWithIO(args []string, stdout, stderr io.Writer) int {
 func writeUsage(w io.Writer) {
 	fmt.Fprintln(w, "juno-txbuild")
 	fmt.Fprintln(w, "")
-	fmt.Fprintln(w, "Online TxPlan v0 builder for offline signing.")
+	fmt.Fprintln(w, "Online TxPlan (v0/v1) builder for offline signing.")
 	fmt.Fprintln(w, "")
 	fmt.Fprintln(w, "Usage:")
-	fmt.Fprintln(w, "  juno-txbuild send --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> --to <j*1..> --amount-zat <zat> --change-address <j*1..> [--memo-hex <hex>] [--fee-multiplier <n>] [--fee-add-zat <zat>] [--min-change-zat <zat>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--out <path>] [--json]")
-	fmt.Fprintln(w, "  juno-txbuild send-many --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> --outputs-file <path|-> --change-address <j*1..> [--fee-multiplier <n>] [--fee-add-zat <zat>] [--min-change-zat <zat>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--out <path>] [--json]")
-	fmt.Fprintln(w, "  juno-txbuild sweep --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> --to <j*1..> [--change-address <j*1..>] [--memo-hex <hex>] [--fee-multiplier <n>] [--fee-add-zat <zat>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--out <path>] [--json]")
-	fmt.Fprintln(w, "  juno-txbuild consolidate --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> --to <j*1..> [--change-address <j*1..>] [--memo-hex <hex>] [--max-spends <n>] [--fee-multiplier <n>] [--fee-add-zat <zat>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--out <path>] [--json]")
-	fmt.Fprintln(w, "  juno-txbuild rebalance --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> --outputs-file <path|-> --change-address <j*1..> [--fee-multiplier <n>] [--fee-add-zat <zat>] [--min-change-zat <zat>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--out <path>] [--json]")
+	fmt.Fprintln(w, "  juno-txbuild send --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> (--to <j*1..> --amount-zat <zat|max> | --uri <juno:...>) [--change-address <j*1..>] [--ufvk <jview*1..>] [--fresh-change-address] [--ovk-policy <sender|internal|none|hex>] [--label <text>] [--request-id <id>] [--metadata-file <path|->] [--plan-version <v0|v1>] [--idempotency-dir <dir> [--idempotency-window <dur>] [--idempotency-key <key>]] [--reserve-zat <zat>] [--memo-hex <hex>|--memo-text <text>|--no-memo] [--subtract-fee-from <0|all>] [--fee-multiplier <n>] [--fee-add-zat <zat>] [--fee-priority <name|auto>] [--config <path>] [--fee-zat <zat>] [--max-fee-zat <zat>] [--max-fee-percent <pct>] [--min-change-zat <zat>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--format <json|cbor|pczt>] [--encrypt-to <age1...>] [--out <path>] [--json]")
+	fmt.Fprintln(w, "  juno-txbuild send-many --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> (--outputs-file <path|-> [--outputs-format <auto|json|csv>] | --uris-file <path|->) [--control-total-zat <zat>] [--memo-template <text>] [--change-address <j*1..>] [--ufvk <jview*1..>] [--fresh-change-address] [--ovk-policy <sender|internal|none|hex>] [--metadata-file <path|->] [--plan-version <v0|v1>] [--idempotency-dir <dir> [--idempotency-window <dur>] [--idempotency-key <key>]] [--subtract-fee-from <index,...|all>] [--fee-multiplier <n>] [--fee-add-zat <zat>] [--fee-priority <name|auto>] [--config <path>] [--fee-zat <zat>] [--max-fee-zat <zat>] [--max-fee-percent <pct>] [--min-change-zat <zat>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--format <json|cbor>] [--encrypt-to <age1...>] [--out <path>] [--json]")
+	fmt.Fprintln(w, "  juno-txbuild sweep --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> --to <j*1..> [--change-address <j*1..>] [--ufvk <jview*1..>] [--fresh-change-address] [--ovk-policy <sender|internal|none|hex>] [--label <text>] [--request-id <id>] [--metadata-file <path|->] [--plan-version <v0|v1>] [--idempotency-dir <dir> [--idempotency-window <dur>] [--idempotency-key <key>]] [--memo-hex <hex>|--memo-text <text>|--no-memo] [--fee-multiplier <n>] [--fee-add-zat <zat>] [--fee-priority <name|auto>] [--config <path>] [--fee-zat <zat>] [--max-fee-zat <zat>] [--max-fee-percent <pct>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--format <json|cbor|pczt>] [--encrypt-to <age1...>] [--out <path>] [--json]")
+	fmt.Fprintln(w, "  juno-txbuild consolidate --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> --to <j*1..> [--change-address <j*1..>] [--ufvk <jview*1..>] [--fresh-change-address] [--ovk-policy <sender|internal|none|hex>] [--label <text>] [--request-id <id>] [--metadata-file <path|->] [--plan-version <v0|v1>] [--idempotency-dir <dir> [--idempotency-window <dur>] [--idempotency-key <key>]] [--memo-hex <hex>|--memo-text <text>|--no-memo] [--max-spends <n>] [--fee-multiplier <n>] [--fee-add-zat <zat>] [--fee-priority <name|auto>] [--config <path>] [--fee-zat <zat>] [--max-fee-zat <zat>] [--max-fee-percent <pct>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--format <json|cbor>] [--encrypt-to <age1...>] [--out <path>] [--json]")
+	fmt.Fprintln(w, "  juno-txbuild rebalance --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--scan-url <url>] [--scan-bearer-token <token>] --wallet-id <id> --coin-type <n> --account <n> (--outputs-file <path|-> [--outputs-format <auto|json|csv>] | --uris-file <path|->) [--control-total-zat <zat>] [--memo-template <text>] [--change-address <j*1..>] [--ufvk <jview*1..>] [--fresh-change-address] [--ovk-policy <sender|internal|none|hex>] [--metadata-file <path|->] [--plan-version <v0|v1>] [--idempotency-dir <dir> [--idempotency-window <dur>] [--idempotency-key <key>]] [--subtract-fee-from <index,...|all>] [--fee-multiplier <n>] [--fee-add-zat <zat>] [--fee-priority <name|auto>] [--config <path>] [--fee-zat <zat>] [--max-fee-zat <zat>] [--max-fee-percent <pct>] [--min-change-zat <zat>] [--min-note-zat <zat>] [--minconf <n>] [--expiry-offset <n>] [--format <json|cbor>] [--encrypt-to <age1...>] [--out <path>] [--json]")
+	fmt.Fprintln(w, "  juno-txbuild mark-broadcast --idempotency-dir <dir> (--plan <path|-> | --idempotency-key <key>) [--txid <hex>] [--json]")
+	fmt.Fprintln(w, "  juno-txbuild convert --to <v0|v1> [--rpc-url <url> --rpc-user <user> --rpc-pass <pass>] [--scan-url <url>] [--scan-bearer-token <token>] [--format <json|cbor>] [--encrypt-to <age1...>] [--out <path>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild hash [--canonical] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild sign (--key <pem> | --pkcs11-module <path> --pkcs11-token <label> --pkcs11-key <label> [--pkcs11-pin <pin>]) [--out <path>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild verify-signature --signature <path> --trusted-keys <path> [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild approve (--key <pem> | --pkcs11-module <path> --pkcs11-token <label> --pkcs11-key <label> [--pkcs11-pin <pin>]) --approvals <path> [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild check-approvals --approvals <path> [--config <path>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild decrypt --identity <path> [--out <path>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild export-qr [--max-fragment-len <n>] [--frames <n>] [--png-dir <dir>] [--out <path>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild import-qr [--format <json|cbor>] [--encrypt-to <age1...>] [--out <path>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild export-pczt [--ufvk <jview*1..>] [--config <path>] [--encrypt-to <age1...>] [--out <path>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild import-pczt [--format <json|cbor>] [--encrypt-to <age1...>] [--out <path>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild tx-skeleton [--ufvk <jview*1..>] [--config <path>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild validate [--schema <auto|txoutputs|txplan.v0|txplan.v1>] [--json] <path|->")
+	fmt.Fprintln(w, "  juno-txbuild estimate-fee --rpc-url <url> --rpc-user <user> --rpc-pass <pass> [--blocks <n>] [--target-blocks <n>] [--json]")
 	fmt.Fprintln(w, "")
 	fmt.Fprintln(w, "Env:")
-	fmt.Fprintln(w, "  JUNO_RPC_URL, JUNO_RPC_USER, JUNO_RPC_PASS, JUNO_SCAN_URL, JUNO_SCAN_BEARER_TOKEN")
+	fmt.Fprintln(w, "  JUNO_RPC_URL, JUNO_RPC_USER, JUNO_RPC_PASS, JUNO_SCAN_URL, JUNO_SCAN_BEARER_TOKEN, JUNO_TXBUILD_CONFIG, JUNO_PKCS11_PIN")
 }
 
 func runSend(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("send", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	var rpcURL string
-	var rpcUser string
-	var rpcPass string
-	var scanURL string
-	var scanBearerToken string
-
-	var walletID string
-	var coinType uint
-	var account uint
+	var pf planFlags
 	var to string
 	var amountZat string
+	var uri string
 	var memoHex string
+	var memoText string
+	var noMemo bool
 	var changeAddr string
-	var minconf int64
-	var expiryOffset uint
-	var feeMultiplier uint64
-	var feeAddZat uint64
+	var label string
+	var requestID string
 	var minChangeZat uint64
-	var minNoteZat uint64
-
-	var outPath string
-	var jsonOut bool
+	var subtractFeeFrom string
+	var reserveZat uint64
 
-	fs.StringVar(&rpcURL, "rpc-url", "", "junocashd RPC URL")
-	fs.StringVar(&rpcUser, "rpc-user", "", "junocashd RPC username")
-	fs.StringVar(&rpcPass, "rpc-pass", "", "junocashd RPC password")
-	fs.StringVar(&scanURL, "scan-url", "", "optional juno-scan base URL (http://host:port)")
-	fs.StringVar(&scanBearerToken, "scan-bearer-token", "", "optional bearer token for juno-scan HTTP API (Authorization: Bearer ...)")
-
-	fs.StringVar(&walletID, "wallet-id", "", "wallet id")
-	fs.UintVar(&coinType, "coin-type", 0, "ZIP-32 coin type (0 = auto)")
-	fs.UintVar(&account, "account", 0, "unified account id")
+	registerPlanFlags(fs, &pf)
 	fs.StringVar(&to, "to", "", "destination unified address (j*1...)")
-	fs.StringVar(&amountZat, "amount-zat", "", "amount to send in zatoshis")
-	fs.StringVar(&memoHex, "memo-hex", "", "optional memo bytes (hex, <=512 bytes)")
-	fs.StringVar(&changeAddr, "change-address", "", "change unified address (j*1...)")
-	fs.Uint64Var(&feeMultiplier, "fee-multiplier", 1, "multiplies the ZIP-317 conventional fee (>=1)")
-	fs.Uint64Var(&feeAddZat, "fee-add-zat", 0, "adds zatoshis on top of the conventional fee")
+	fs.StringVar(&amountZat, "amount-zat", "", "amount to send in zatoshis (or max: all spendable notes after fees and --reserve-zat)")
+	fs.StringVar(&uri, "uri", "", "ZIP-321 payment request URI (juno: or zcash:) with a single payment, instead of --to, --amount-zat and memo flags")
+	fs.StringVar(&memoHex, "memo-hex", "", "optional memo bytes (hex, <=512 bytes, ZIP-302)")
+	fs.StringVar(&memoText, "memo-text", "", "optional UTF-8 text memo (<=512 bytes, ZIP-302)")
+	fs.BoolVar(&noMemo, "no-memo", false, "set the ZIP-302 no-memo marker (0xf6)")
+	fs.StringVar(&changeAddr, "change-address", "", "change unified address (j*1...) (required unless --ufvk)")
+	fs.StringVar(&subtractFeeFrom, "subtract-fee-from", "", "deduct the fee from the output instead of adding it on top (0 or all)")
+	fs.Uint64Var(&reserveZat, "reserve-zat", 0, "with --amount-zat max, keep this many zatoshis as change")
+	fs.StringVar(&label, "label", "", "optional output label, echoed into the plan")
+	fs.StringVar(&requestID, "request-id", "", "optional output request ID, echoed into the plan")
 	fs.Uint64Var(&minChangeZat, "min-change-zat", 0, "if change is in (0, min-change-zat), add it to fee and omit change output")
-	fs.Uint64Var(&minNoteZat, "min-note-zat", 0, "skip spendable notes with value < min-note-zat")
-	fs.Int64Var(&minconf, "minconf", 1, "minimum confirmations for spendable notes")
-	fs.UintVar(&expiryOffset, "expiry-offset", 40, "expiry height offset from next block height (chain tip + 1, min: 4)")
-
-	fs.StringVar(&outPath, "out", "", "optional path to write TxPlan JSON")
-	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+	fs.Var(&pf.Output.Format, "format", "plan encoding: json (default), cbor (compact binary) or pczt (Partially Created Zcash Transaction; needs the wallet ufvk)")
 
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintln(stderr, err.Error())
 		return 2
 	}
+	jsonOut := pf.JSON
 
-	rpcURL, rpcUser, rpcPass, err := rpcConfigFromFlags(rpcURL, rpcUser, rpcPass)
+	fee, opts, err := pf.resolve(fs)
 	if err != nil {
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
-	if strings.TrimSpace(scanURL) == "" {
-		scanURL = os.Getenv("JUNO_SCAN_URL")
+	if pf.Output.Format == formatPCZT {
+		if opts.UFVK == "" {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "format pczt requires --ufvk (or wallets.<wallet-id>.ufvk in the config file)")
+		}
+		pf.Output.UFVK = opts.UFVK
 	}
-	scanURL = strings.TrimSpace(scanURL)
-	if strings.TrimSpace(scanBearerToken) == "" {
-		scanBearerToken = os.Getenv("JUNO_SCAN_BEARER_TOKEN")
+	if strings.TrimSpace(uri) != "" {
+		out, err := sendURIOutput(fs, uri)
+		if err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+		}
+		to, amountZat, memoHex = out.ToAddress, out.AmountZat, out.MemoHex
+		if label == "" {
+			label = out.Label
+		}
 	}
-	if strings.TrimSpace(scanBearerToken) == "" {
-		scanBearerToken = os.Getenv("JUNO_SCAN_API_BEARER_TOKEN")
+	memoHex, err = resolveMemo(memoHex, memoText, noMemo)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	subtractIdx, err := parseSubtractFeeFrom(subtractFeeFrom, 1)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
-	scanBearerToken = strings.TrimSpace(scanBearerToken)
 
 	cfg := txbuild.SendConfig{
-		RPCURL:  rpcURL,
-		RPCUser: rpcUser,
-		RPCPass: rpcPass,
+		RPCURL:  pf.RPCURL,
+		RPCUser: pf.RPCUser,
+		RPCPass: pf.RPCPass,
 
-		ScanURL:         scanURL,
-		ScanBearerToken: scanBearerToken,
+		ScanURL:         pf.ScanURL,
+		ScanBearerToken: pf.ScanBearerToken,
 
-		WalletID: walletID,
-		CoinType: uint32(coinType),
-		Account:  uint32(account),
+		WalletID: pf.WalletID,
+		CoinType: uint32(pf.CoinType),
+		Account:  uint32(pf.Account),
 
 		ToAddress:     to,
 		AmountZat:     amountZat,
 		MemoHex:       memoHex,
+		Label:         label,
+		RequestID:     requestID,
 		ChangeAddress: changeAddr,
 
-		MinConfirmations: minconf,
-		ExpiryOffset:     uint32(expiryOffset),
-		MinNoteZat:       minNoteZat,
+		MinConfirmations: pf.MinConf,
+		ExpiryOffset:     uint32(pf.ExpiryOffset),
+		MinNoteZat:       pf.MinNoteZat,
 
-		FeeMultiplier: feeMultiplier,
-		FeeAddZat:     feeAddZat,
+		FeeMultiplier: fee.Multiplier,
+		FeeAddZat:     fee.AddZat,
 		MinChangeZat:  minChangeZat,
+		SubtractFee:   len(subtractIdx) > 0,
+		ReserveZat:    reserveZat,
+
+		PlanOptions: opts,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	plan, err := txbuild.PlanSend(ctx, cfg)
+	req := newIdempotencyRequest(pf.Idem.Key, pf.WalletID, types.TxPlanKindWithdrawal, []txbuild.TxOutput{{TxOutput: types.TxOutput{ToAddress: to, AmountZat: amountZat, MemoHex: memoHex}, RequestID: requestID}})
+	plan, err := planIdempotent(ctx, pf.Idem, req, nodeChainState(pf.RPCURL, pf.RPCUser, pf.RPCPass), stderr, withTxCommitment(opts.UFVK, func() (txbuild.TxPlan, error) {
+		return txbuild.BuildSend(ctx, cfg)
+	}))
 	if err != nil {
 		var ce types.CodedError
 		if errors.As(err, &ce) {
@@ -174,104 +237,88 @@ func runSend(args []string, stdout, stderr io.Writer) int {
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
 
-	return writePlan(stdout, stderr, jsonOut, outPath, plan)
+	return writePlan(stdout, stderr, jsonOut, pf.Output, plan)
 }
 
 func runSweep(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("sweep", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	var rpcURL string
-	var rpcUser string
-	var rpcPass string
-	var scanURL string
-	var scanBearerToken string
-
-	var walletID string
-	var coinType uint
-	var account uint
+	var pf planFlags
 	var to string
 	var memoHex string
+	var memoText string
+	var noMemo bool
 	var changeAddr string
-	var minconf int64
-	var expiryOffset uint
-	var feeMultiplier uint64
-	var feeAddZat uint64
-	var minNoteZat uint64
+	var label string
+	var requestID string
 
-	var outPath string
-	var jsonOut bool
-
-	fs.StringVar(&rpcURL, "rpc-url", "", "junocashd RPC URL")
-	fs.StringVar(&rpcUser, "rpc-user", "", "junocashd RPC username")
-	fs.StringVar(&rpcPass, "rpc-pass", "", "junocashd RPC password")
-	fs.StringVar(&scanURL, "scan-url", "", "optional juno-scan base URL (http://host:port)")
-	fs.StringVar(&scanBearerToken, "scan-bearer-token", "", "optional bearer token for juno-scan HTTP API (Authorization: Bearer ...)")
-
-	fs.StringVar(&walletID, "wallet-id", "", "wallet id")
-	fs.UintVar(&coinType, "coin-type", 0, "ZIP-32 coin type (0 = auto)")
-	fs.UintVar(&account, "account", 0, "unified account id")
+	registerPlanFlags(fs, &pf)
 	fs.StringVar(&to, "to", "", "destination unified address (j*1...)")
-	fs.StringVar(&memoHex, "memo-hex", "", "optional memo bytes (hex, <=512 bytes)")
-	fs.StringVar(&changeAddr, "change-address", "", "change unified address (j*1...) (defaults to --to)")
-	fs.Uint64Var(&feeMultiplier, "fee-multiplier", 1, "multiplies the ZIP-317 conventional fee (>=1)")
-	fs.Uint64Var(&feeAddZat, "fee-add-zat", 0, "adds zatoshis on top of the conventional fee")
-	fs.Uint64Var(&minNoteZat, "min-note-zat", 0, "skip spendable notes with value < min-note-zat")
-	fs.Int64Var(&minconf, "minconf", 1, "minimum confirmations for spendable notes")
-	fs.UintVar(&expiryOffset, "expiry-offset", 40, "expiry height offset from next block height (chain tip + 1, min: 4)")
-
-	fs.StringVar(&outPath, "out", "", "optional path to write TxPlan JSON")
-	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+	fs.StringVar(&memoHex, "memo-hex", "", "optional memo bytes (hex, <=512 bytes, ZIP-302)")
+	fs.StringVar(&memoText, "memo-text", "", "optional UTF-8 text memo (<=512 bytes, ZIP-302)")
+	fs.BoolVar(&noMemo, "no-memo", false, "set the ZIP-302 no-memo marker (0xf6)")
+	fs.StringVar(&changeAddr, "change-address", "", "change unified address (j*1...) (defaults to --to, or derived from --ufvk)")
+	fs.StringVar(&label, "label", "", "optional output label, echoed into the plan")
+	fs.StringVar(&requestID, "request-id", "", "optional output request ID, echoed into the plan")
+	fs.Var(&pf.Output.Format, "format", "plan encoding: json (default), cbor (compact binary) or pczt (Partially Created Zcash Transaction; needs the wallet ufvk)")
 
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintln(stderr, err.Error())
 		return 2
 	}
+	jsonOut := pf.JSON
 
-	rpcURL, rpcUser, rpcPass, err := rpcConfigFromFlags(rpcURL, rpcUser, rpcPass)
+	fee, opts, err := pf.resolve(fs)
 	if err != nil {
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
-	if strings.TrimSpace(scanURL) == "" {
-		scanURL = os.Getenv("JUNO_SCAN_URL")
-	}
-	scanURL = strings.TrimSpace(scanURL)
-	if strings.TrimSpace(scanBearerToken) == "" {
-		scanBearerToken = os.Getenv("JUNO_SCAN_BEARER_TOKEN")
+	if pf.Output.Format == formatPCZT {
+		if opts.UFVK == "" {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "format pczt requires --ufvk (or wallets.<wallet-id>.ufvk in the config file)")
+		}
+		pf.Output.UFVK = opts.UFVK
 	}
-	if strings.TrimSpace(scanBearerToken) == "" {
-		scanBearerToken = os.Getenv("JUNO_SCAN_API_BEARER_TOKEN")
+	memoHex, err = resolveMemo(memoHex, memoText, noMemo)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
-	scanBearerToken = strings.TrimSpace(scanBearerToken)
 
 	cfg := txbuild.SweepConfig{
-		RPCURL:  rpcURL,
-		RPCUser: rpcUser,
-		RPCPass: rpcPass,
+		RPCURL:  pf.RPCURL,
+		RPCUser: pf.RPCUser,
+		RPCPass: pf.RPCPass,
 
-		ScanURL:         scanURL,
-		ScanBearerToken: scanBearerToken,
+		ScanURL:         pf.ScanURL,
+		ScanBearerToken: pf.ScanBearerToken,
 
-		WalletID: walletID,
-		CoinType: uint32(coinType),
-		Account:  uint32(account),
+		WalletID: pf.WalletID,
+		CoinType: uint32(pf.CoinType),
+		Account:  uint32(pf.Account),
 
 		ToAddress:     to,
 		MemoHex:       memoHex,
+		Label:         label,
+		RequestID:     requestID,
 		ChangeAddress: changeAddr,
 
-		MinConfirmations: minconf,
-		ExpiryOffset:     uint32(expiryOffset),
-		MinNoteZat:       minNoteZat,
+		MinConfirmations: pf.MinConf,
+		ExpiryOffset:     uint32(pf.ExpiryOffset),
+		MinNoteZat:       pf.MinNoteZat,
+
+		FeeMultiplier: fee.Multiplier,
+		FeeAddZat:     fee.AddZat,
 
-		FeeMultiplier: feeMultiplier,
-		FeeAddZat:     feeAddZat,
+		PlanOptions: opts,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	plan, err := txbuild.PlanSweep(ctx, cfg)
+	req := newIdempotencyRequest(pf.Idem.Key, pf.WalletID, types.TxPlanKindSweep, []txbuild.TxOutput{{TxOutput: types.TxOutput{ToAddress: to, AmountZat: txbuild.AmountMax, MemoHex: memoHex}, RequestID: requestID}})
+	plan, err := planIdempotent(ctx, pf.Idem, req, nodeChainState(pf.RPCURL, pf.RPCUser, pf.RPCPass), stderr, withTxCommitment(opts.UFVK, func() (txbuild.TxPlan, error) {
+		return txbuild.BuildSweep(ctx, cfg)
+	}))
 	if err != nil {
 		var ce types.CodedError
 		if errors.As(err, &ce) {
@@ -280,108 +327,86 @@ func runSweep(args []string, stdout, stderr io.Writer) int {
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
 
-	return writePlan(stdout, stderr, jsonOut, outPath, plan)
+	return writePlan(stdout, stderr, jsonOut, pf.Output, plan)
 }
 
 func runConsolidate(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("consolidate", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	var rpcURL string
-	var rpcUser string
-	var rpcPass string
-	var scanURL string
-	var scanBearerToken string
-
-	var walletID string
-	var coinType uint
-	var account uint
+	var pf planFlags
 	var to string
 	var memoHex string
+	var memoText string
+	var noMemo bool
 	var changeAddr string
 	var maxSpends int
-	var minconf int64
-	var expiryOffset uint
-	var feeMultiplier uint64
-	var feeAddZat uint64
-	var minNoteZat uint64
-
-	var outPath string
-	var jsonOut bool
+	var label string
+	var requestID string
 
-	fs.StringVar(&rpcURL, "rpc-url", "", "junocashd RPC URL")
-	fs.StringVar(&rpcUser, "rpc-user", "", "junocashd RPC username")
-	fs.StringVar(&rpcPass, "rpc-pass", "", "junocashd RPC password")
-	fs.StringVar(&scanURL, "scan-url", "", "optional juno-scan base URL (http://host:port)")
-	fs.StringVar(&scanBearerToken, "scan-bearer-token", "", "optional bearer token for juno-scan HTTP API (Authorization: Bearer ...)")
-
-	fs.StringVar(&walletID, "wallet-id", "", "wallet id")
-	fs.UintVar(&coinType, "coin-type", 0, "ZIP-32 coin type (0 = auto)")
-	fs.UintVar(&account, "account", 0, "unified account id")
+	registerPlanFlags(fs, &pf)
 	fs.StringVar(&to, "to", "", "destination unified address (j*1...)")
-	fs.StringVar(&memoHex, "memo-hex", "", "optional memo bytes (hex, <=512 bytes)")
-	fs.StringVar(&changeAddr, "change-address", "", "change unified address (j*1...) (defaults to --to)")
+	fs.StringVar(&memoHex, "memo-hex", "", "optional memo bytes (hex, <=512 bytes, ZIP-302)")
+	fs.StringVar(&memoText, "memo-text", "", "optional UTF-8 text memo (<=512 bytes, ZIP-302)")
+	fs.BoolVar(&noMemo, "no-memo", false, "set the ZIP-302 no-memo marker (0xf6)")
+	fs.StringVar(&changeAddr, "change-address", "", "change unified address (j*1...) (defaults to --to, or derived from --ufvk)")
 	fs.IntVar(&maxSpends, "max-spends", 50, "max notes to consolidate into 1 output")
-	fs.Uint64Var(&feeMultiplier, "fee-multiplier", 1, "multiplies the ZIP-317 conventional fee (>=1)")
-	fs.Uint64Var(&feeAddZat, "fee-add-zat", 0, "adds zatoshis on top of the conventional fee")
-	fs.Uint64Var(&minNoteZat, "min-note-zat", 0, "skip spendable notes with value < min-note-zat")
-	fs.Int64Var(&minconf, "minconf", 1, "minimum confirmations for spendable notes")
-	fs.UintVar(&expiryOffset, "expiry-offset", 40, "expiry height offset from next block height (chain tip + 1, min: 4)")
-
-	fs.StringVar(&outPath, "out", "", "optional path to write TxPlan JSON")
-	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+	fs.StringVar(&label, "label", "", "optional output label, echoed into the plan")
+	fs.StringVar(&requestID, "request-id", "", "optional output request ID, echoed into the plan")
+	fs.Var(&pf.Output.Format, "format", "plan encoding: json (default) or cbor (compact binary)")
 
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintln(stderr, err.Error())
 		return 2
 	}
+	jsonOut := pf.JSON
 
-	rpcURL, rpcUser, rpcPass, err := rpcConfigFromFlags(rpcURL, rpcUser, rpcPass)
+	fee, opts, err := pf.resolve(fs)
 	if err != nil {
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
-	if strings.TrimSpace(scanURL) == "" {
-		scanURL = os.Getenv("JUNO_SCAN_URL")
-	}
-	scanURL = strings.TrimSpace(scanURL)
-	if strings.TrimSpace(scanBearerToken) == "" {
-		scanBearerToken = os.Getenv("JUNO_SCAN_BEARER_TOKEN")
-	}
-	if strings.TrimSpace(scanBearerToken) == "" {
-		scanBearerToken = os.Getenv("JUNO_SCAN_API_BEARER_TOKEN")
+	memoHex, err = resolveMemo(memoHex, memoText, noMemo)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
-	scanBearerToken = strings.TrimSpace(scanBearerToken)
 
 	cfg := txbuild.ConsolidateConfig{
-		RPCURL:  rpcURL,
-		RPCUser: rpcUser,
-		RPCPass: rpcPass,
+		RPCURL:  pf.RPCURL,
+		RPCUser: pf.RPCUser,
+		RPCPass: pf.RPCPass,
 
-		ScanURL:         scanURL,
-		ScanBearerToken: scanBearerToken,
+		ScanURL:         pf.ScanURL,
+		ScanBearerToken: pf.ScanBearerToken,
 
-		WalletID: walletID,
-		CoinType: uint32(coinType),
-		Account:  uint32(account),
+		WalletID: pf.WalletID,
+		CoinType: uint32(pf.CoinType),
+		Account:  uint32(pf.Account),
 
 		ToAddress:     to,
 		MemoHex:       memoHex,
+		Label:         label,
+		RequestID:     requestID,
 		ChangeAddress: changeAddr,
 
 		MaxSpends: maxSpends,
 
-		MinConfirmations: minconf,
-		ExpiryOffset:     uint32(expiryOffset),
-		MinNoteZat:       minNoteZat,
+		MinConfirmations: pf.MinConf,
+		ExpiryOffset:     uint32(pf.ExpiryOffset),
+		MinNoteZat:       pf.MinNoteZat,
+
+		FeeMultiplier: fee.Multiplier,
+		FeeAddZat:     fee.AddZat,
 
-		FeeMultiplier: feeMultiplier,
-		FeeAddZat:     feeAddZat,
+		PlanOptions: opts,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	plan, err := txbuild.PlanConsolidate(ctx, cfg)
+	req := newIdempotencyRequest(pf.Idem.Key, pf.WalletID, types.TxPlanKindRebalance, []txbuild.TxOutput{{TxOutput: types.TxOutput{ToAddress: to, AmountZat: txbuild.AmountMax, MemoHex: memoHex}, RequestID: requestID}})
+	plan, err := planIdempotent(ctx, pf.Idem, req, nodeChainState(pf.RPCURL, pf.RPCUser, pf.RPCPass), stderr, withTxCommitment(opts.UFVK, func() (txbuild.TxPlan, error) {
+		return txbuild.BuildConsolidate(ctx, cfg)
+	}))
 	if err != nil {
 		var ce types.CodedError
 		if errors.As(err, &ce) {
@@ -390,112 +415,266 @@ func runConsolidate(args []string, stdout, stderr io.Writer) int {
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
 
-	return writePlan(stdout, stderr, jsonOut, outPath, plan)
+	return writePlan(stdout, stderr, jsonOut, pf.Output, plan)
 }
 
 func runPlanOutputs(args []string, kind types.TxPlanKind, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet(string(kind), flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
-	var rpcURL string
-	var rpcUser string
-	var rpcPass string
-	var scanURL string
-	var scanBearerToken string
-
-	var walletID string
-	var coinType uint
-	var account uint
+	var pf planFlags
 	var outputsFile string
 	var changeAddr string
-	var minconf int64
-	var expiryOffset uint
-	var feeMultiplier uint64
-	var feeAddZat uint64
 	var minChangeZat uint64
-	var minNoteZat uint64
-
-	var outPath string
-	var jsonOut bool
-
-	fs.StringVar(&rpcURL, "rpc-url", "", "junocashd RPC URL")
-	fs.StringVar(&rpcUser, "rpc-user", "", "junocashd RPC username")
-	fs.StringVar(&rpcPass, "rpc-pass", "", "junocashd RPC password")
-	fs.StringVar(&scanURL, "scan-url", "", "optional juno-scan base URL (http://host:port)")
-	fs.StringVar(&scanBearerToken, "scan-bearer-token", "", "optional bearer token for juno-scan HTTP API (Authorization: Bearer ...)")
+	var subtractFeeFrom string
+	var memoTemplate string
+	var urisFile string
+	var outputsFormat string
+	var controlTotal string
 
-	fs.StringVar(&walletID, "wallet-id", "", "wallet id")
-	fs.UintVar(&coinType, "coin-type", 0, "ZIP-32 coin type (0 = auto)")
-	fs.UintVar(&account, "account", 0, "unified account id")
+	registerPlanFlags(fs, &pf)
 	fs.StringVar(&outputsFile, "outputs-file", "", "path to JSON array of TxOutputs (or - for stdin)")
-	fs.StringVar(&changeAddr, "change-address", "", "change unified address (j*1...)")
-	fs.Uint64Var(&feeMultiplier, "fee-multiplier", 1, "multiplies the ZIP-317 conventional fee (>=1)")
-	fs.Uint64Var(&feeAddZat, "fee-add-zat", 0, "adds zatoshis on top of the conventional fee")
+	fs.StringVar(&outputsFormat, "outputs-format", outputsFormatAuto, "outputs-file format: auto (by extension or content), json or csv")
+	fs.StringVar(&controlTotal, "control-total-zat", "", "optional expected sum of output amounts in zatoshis; planning fails on mismatch")
+	fs.StringVar(&urisFile, "uris-file", "", "path to ZIP-321 payment request URIs, one per line (or - for stdin), instead of --outputs-file")
+	fs.StringVar(&changeAddr, "change-address", "", "change unified address (j*1...) (required unless --ufvk)")
+	fs.StringVar(&subtractFeeFrom, "subtract-fee-from", "", "deduct the fee from these outputs, split proportionally (comma-separated indices or all)")
+	fs.StringVar(&memoTemplate, "memo-template", "", "text memo for outputs without a memo; {request_id} is replaced by the output's request_id")
 	fs.Uint64Var(&minChangeZat, "min-change-zat", 0, "if change is in (0, min-change-zat), add it to fee and omit change output")
-	fs.Uint64Var(&minNoteZat, "min-note-zat", 0, "skip spendable notes with value < min-note-zat")
-	fs.Int64Var(&minconf, "minconf", 1, "minimum confirmations for spendable notes")
-	fs.UintVar(&expiryOffset, "expiry-offset", 40, "expiry height offset from next block height (chain tip + 1, min: 4)")
-
-	fs.StringVar(&outPath, "out", "", "optional path to write TxPlan JSON")
-	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+	fs.Var(&pf.Output.Format, "format", "plan encoding: json (default) or cbor (compact binary)")
 
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintln(stderr, err.Error())
 		return 2
 	}
+	jsonOut := pf.JSON
 
 	outputsFile = strings.TrimSpace(outputsFile)
-	if outputsFile == "" {
+	urisFile = strings.TrimSpace(urisFile)
+	if outputsFile != "" && urisFile != "" {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "outputs-file and uris-file are mutually exclusive")
+	}
+	if outputsFile == "" && urisFile == "" {
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "outputs-file is required")
 	}
 
-	outs, err := loadOutputs(outputsFile)
+	var specs []outputSpec
+	var err error
+	if urisFile != "" {
+		specs, err = loadURIs(urisFile)
+	} else {
+		specs, err = loadOutputs(outputsFile, outputsFormat)
+	}
 	if err != nil {
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
-
-	rpcURL, rpcUser, rpcPass, err = rpcConfigFromFlags(rpcURL, rpcUser, rpcPass)
+	if controlTotal = strings.TrimSpace(controlTotal); controlTotal != "" {
+		want, err := strconv.ParseUint(controlTotal, 10, 64)
+		if err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "control-total-zat invalid")
+		}
+		if err := checkControlTotal(specs, want); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+		}
+	}
+	subtractIdx, err := parseSubtractFeeFrom(subtractFeeFrom, len(specs))
 	if err != nil {
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
-	if strings.TrimSpace(scanURL) == "" {
-		scanURL = os.Getenv("JUNO_SCAN_URL")
-	}
-	scanURL = strings.TrimSpace(scanURL)
-	if strings.TrimSpace(scanBearerToken) == "" {
-		scanBearerToken = os.Getenv("JUNO_SCAN_BEARER_TOKEN")
+	outs := make([]txbuild.TxOutput, 0, len(specs))
+	for i, o := range specs {
+		m, err := resolveOutputMemo(o, memoTemplate)
+		if err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("outputs[%d]: %v", i, err))
+		}
+		o.MemoHex = m
+		outs = append(outs, o.planOutput())
+		if o.SubtractFee && !slices.Contains(subtractIdx, i) {
+			subtractIdx = append(subtractIdx, i)
+		}
 	}
-	if strings.TrimSpace(scanBearerToken) == "" {
-		scanBearerToken = os.Getenv("JUNO_SCAN_API_BEARER_TOKEN")
+	slices.Sort(subtractIdx)
+
+	fee, opts, err := pf.resolve(fs)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
-	scanBearerToken = strings.TrimSpace(scanBearerToken)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	plan, err := txbuild.Plan(ctx, txbuild.PlanConfig{
-		RPCURL:  rpcURL,
-		RPCUser: rpcUser,
-		RPCPass: rpcPass,
+	req := newIdempotencyRequest(pf.Idem.Key, pf.WalletID, kind, outs)
+	plan, err := planIdempotent(ctx, pf.Idem, req, nodeChainState(pf.RPCURL, pf.RPCUser, pf.RPCPass), stderr, withTxCommitment(opts.UFVK, func() (txbuild.TxPlan, error) {
+		return txbuild.Build(ctx, txbuild.PlanConfig{
+			RPCURL:  pf.RPCURL,
+			RPCUser: pf.RPCUser,
+			RPCPass: pf.RPCPass,
 
-		ScanURL:         scanURL,
-		ScanBearerToken: scanBearerToken,
+			ScanURL:         pf.ScanURL,
+			ScanBearerToken: pf.ScanBearerToken,
 
-		WalletID: walletID,
-		CoinType: uint32(coinType),
-		Account:  uint32(account),
+			WalletID: pf.WalletID,
+			CoinType: uint32(pf.CoinType),
+			Account:  uint32(pf.Account),
 
-		Kind:          kind,
-		Outputs:       outs,
-		ChangeAddress: changeAddr,
+			Kind:          kind,
+			Outputs:       outs,
+			ChangeAddress: changeAddr,
 
-		MinConfirmations: minconf,
-		ExpiryOffset:     uint32(expiryOffset),
-		MinNoteZat:       minNoteZat,
+			MinConfirmations: pf.MinConf,
+			ExpiryOffset:     uint32(pf.ExpiryOffset),
+			MinNoteZat:       pf.MinNoteZat,
 
-		FeeMultiplier: feeMultiplier,
-		FeeAddZat:     feeAddZat,
-		MinChangeZat:  minChangeZat,
+			FeeMultiplier:   fee.Multiplier,
+			FeeAddZat:       fee.AddZat,
+			MinChangeZat:    minChangeZat,
+			SubtractFeeFrom: subtractIdx,
+
+			PlanOptions: opts,
+		})
+	}))
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	return writePlan(stdout, stderr, jsonOut, pf.Output, plan)
+}
+
+// outputSpec is a TxOutput as accepted in --outputs-file.
+type outputSpec struct {
+	types.TxOutput
+	SubtractFee bool `json:"subtract_fee,omitempty"`
+	// ZIP-302 text memo, an alternative to memo_hex. It may use
+	// memo.RequestIDPlaceholder.
+	MemoText string `json:"memo_text,omitempty"`
+	// Sets the ZIP-302 no-memo marker.
+	NoMemo    bool   `json:"no_memo,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Label     string `json:"label,omitempty"`
+	// Any JSON value, echoed into the plan output.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// planOutput returns the spec as a plan output with its annotations.
+func (o outputSpec) planOutput() txbuild.TxOutput {
+	return txbuild.TxOutput{
+		TxOutput:  o.TxOutput,
+		Label:     o.Label,
+		RequestID: o.RequestID,
+		Metadata:  o.Metadata,
+	}
+}
+
+// loadMetadata reads --metadata-file: a single JSON value (or - for stdin).
+// "" returns nil.
+func loadMetadata(path string) (json.RawMessage, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, nil
+	}
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open metadata file: %w", err)
+	}
+	if _, err := schema.Decode(data); err != nil {
+		return nil, fmt.Errorf("invalid metadata json: %v", err)
+	}
+	return json.RawMessage(bytes.TrimSpace(data)), nil
+}
+
+// resolveMemo returns the memo_hex for at most one of --memo-hex, --memo-text
+// and --no-memo.
+func resolveMemo(memoHex, memoText string, noMemo bool) (string, error) {
+	memoHex = strings.TrimSpace(memoHex)
+	set := 0
+	for _, ok := range []bool{memoHex != "", memoText != "", noMemo} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", errors.New("memo-hex, memo-text and no-memo are mutually exclusive")
+	}
+	switch {
+	case memoText != "":
+		return memo.TextHex(memoText)
+	case noMemo:
+		return memo.NoMemoHex, nil
+	case memoHex != "":
+		return memo.ParseHex(memoHex)
+	}
+	return "", nil
+}
+
+// resolveOutputMemo returns the memo_hex of an --outputs-file entry, falling
+// back to tmpl (--memo-template) when the entry has no memo.
+func resolveOutputMemo(o outputSpec, tmpl string) (string, error) {
+	text := o.MemoText
+	if o.MemoHex == "" && text == "" && !o.NoMemo {
+		text = tmpl
+	}
+	if text != "" {
+		var err error
+		if text, err = memo.Expand(text, o.RequestID); err != nil {
+			return "", err
+		}
+	}
+	return resolveMemo(o.MemoHex, text, o.NoMemo)
+}
+
+func runEstimateFee(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("estimate-fee", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var rpcURL string
+	var rpcUser string
+	var rpcPass string
+	var blocks int
+	var targetBlocks int
+	var jsonOut bool
+
+	fs.StringVar(&rpcURL, "rpc-url", "", "junocashd RPC URL")
+	fs.StringVar(&rpcUser, "rpc-user", "", "junocashd RPC username")
+	fs.StringVar(&rpcPass, "rpc-pass", "", "junocashd RPC password")
+	fs.IntVar(&blocks, "blocks", 20, "recent blocks to sample")
+	fs.IntVar(&targetBlocks, "target-blocks", 3, "blocks within which inclusion should be likely")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if blocks <= 0 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "blocks must be > 0")
+	}
+	if targetBlocks <= 0 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "target-blocks must be > 0")
+	}
+
+	rpcURL, rpcUser, rpcPass, err := rpcConfigFromFlags(rpcURL, rpcUser, rpcPass)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	est, err := txbuild.EstimateFee(ctx, txbuild.EstimateFeeConfig{
+		RPCURL:       rpcURL,
+		RPCUser:      rpcUser,
+		RPCPass:      rpcPass,
+		Blocks:       blocks,
+		TargetBlocks: targetBlocks,
 	})
 	if err != nil {
 		var ce types.CodedError
@@ -505,56 +684,540 @@ func runPlanOutputs(args []string, kind types.TxPlanKind, stdout, stderr io.Writ
 		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
 	}
 
-	return writePlan(stdout, stderr, jsonOut, outPath, plan)
+	if jsonOut {
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data":    est,
+		})
+		return 0
+	}
+
+	b, err := json.MarshalIndent(est, "", "  ")
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "marshal estimate")
+	}
+	_, _ = stdout.Write(append(b, '\n'))
+	return 0
+}
+
+// Schemas selectable with validate --schema.
+var validateSchemas = map[string]string{
+	"txoutputs": api.TxOutputsSchema,
+	"txplan.v0": api.TxPlanV0Schema,
+	"txplan.v1": api.TxPlanV1Schema,
+}
+
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var schemaName string
+	var jsonOut bool
+
+	fs.StringVar(&schemaName, "schema", "auto", "schema to validate against: auto (TxPlan object or outputs array), txoutputs, txplan.v0 or txplan.v1")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "validate takes exactly one file (or -)")
+	}
+	path := fs.Arg(0)
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("read %s: %v", filepath.Base(path), err))
+	}
+
+	schemaName = strings.ToLower(strings.TrimSpace(schemaName))
+	doc, err := schema.Decode(data)
+	if err == nil {
+		if schemaName == "auto" {
+			schemaName, err = detectSchema(doc)
+		} else if _, ok := validateSchemas[schemaName]; !ok {
+			err = fmt.Errorf("unknown schema %q (want auto, txoutputs, txplan.v0 or txplan.v1)", schemaName)
+		}
+	}
+	if err == nil {
+		err = schema.Validate(validateSchemas[schemaName], doc)
+	}
+	if err == nil && schemaName == "txplan.v1" {
+		err = checkPlanAccounting(data)
+	}
+	if err == nil && strings.HasPrefix(schemaName, "txplan.") {
+		err = checkPlanID(data)
+	}
+
+	var errs schema.Errors
+	if err != nil && !errors.As(err, &errs) {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	if jsonOut {
+		env := map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data": map[string]any{
+				"schema": schemaName,
+				"valid":  len(errs) == 0,
+				"errors": append(schema.Errors{}, errs...),
+			},
+		}
+		if len(errs) > 0 {
+			env["status"] = "err"
+			env["error"] = map[string]any{"code": types.ErrCodeInvalidRequest, "message": errs.Error()}
+		}
+		_ = json.NewEncoder(stdout).Encode(env)
+	} else if len(errs) == 0 {
+		fmt.Fprintf(stdout, "%s: valid %s\n", path, schemaName)
+	} else {
+		fmt.Fprintf(stderr, "%s: invalid\n", path)
+		for _, e := range errs {
+			fmt.Fprintf(stderr, "  %s\n", e.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// detectSchema picks the validate schema of a decoded document.
+func detectSchema(doc any) (string, error) {
+	switch v := doc.(type) {
+	case []any:
+		return "txoutputs", nil
+	case map[string]any:
+		switch v["version"] {
+		case string(types.V0):
+			return "txplan.v0", nil
+		case string(txbuild.PlanVersionV1):
+			return "txplan.v1", nil
+		}
+		return "", errors.New("cannot detect schema: object without a known txplan version (use --schema)")
+	default:
+		return "", errors.New("cannot detect schema: want a TxPlan object or an outputs array (use --schema)")
+	}
 }
 
-func loadOutputs(path string) ([]types.TxOutput, error) {
+// checkPlanAccounting applies the TxPlan v1 accounting rule to a plan that
+// passed its schema.
+func checkPlanAccounting(data []byte) error {
+	var plan txbuild.TxPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return schema.Errors{{Pointer: "", Message: err.Error()}}
+	}
+	if err := txbuild.CheckAccounting(plan); err != nil {
+		return schema.Errors{{Pointer: "/total_input_zat", Message: err.Error()}}
+	}
+	return nil
+}
+
+// checkPlanID checks the plan_id of a plan, if it has one.
+func checkPlanID(data []byte) error {
+	var plan struct {
+		PlanID string `json:"plan_id"`
+	}
+	if err := json.Unmarshal(data, &plan); err != nil || plan.PlanID == "" {
+		return nil
+	}
+	id, err := txbuild.PlanIDJSON(data)
+	if err != nil {
+		return err
+	}
+	if id != plan.PlanID {
+		return schema.Errors{{Pointer: "/plan_id", Message: fmt.Sprintf("does not match the plan (%s)", id)}}
+	}
+	return nil
+}
+
+func loadOutputs(path, format string) ([]outputSpec, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open outputs file: %w", err)
+	}
+
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "", outputsFormatAuto:
+		format = detectOutputsFormat(path, data)
+	case outputsFormatJSON, outputsFormatCSV:
+	default:
+		return nil, fmt.Errorf("unknown outputs-format %q (want auto, json or csv)", format)
+	}
+	if format == outputsFormatCSV {
+		return parseOutputsCSV(bytes.NewReader(data))
+	}
+
+	if err := schema.ValidateJSON(api.TxOutputsSchema, data); err != nil {
+		return nil, fmt.Errorf("invalid outputs json: %v", err)
+	}
+	var outs []outputSpec
+	if err := json.Unmarshal(data, &outs); err != nil {
+		return nil, errors.New("invalid outputs json")
+	}
+	return outs, nil
+}
+
+// loadURIs reads ZIP-321 payment request URIs, one per line. Blank lines and
+// lines starting with # are skipped.
+func loadURIs(path string) ([]outputSpec, error) {
 	var r io.Reader
 	if path == "-" {
 		r = os.Stdin
 	} else {
 		f, err := os.Open(path)
 		if err != nil {
-			return nil, fmt.Errorf("open outputs file: %w", err)
+			return nil, fmt.Errorf("open uris file: %w", err)
 		}
 		defer f.Close()
 		r = f
 	}
 
-	var outs []types.TxOutput
-	dec := json.NewDecoder(r)
-	if err := dec.Decode(&outs); err != nil {
-		return nil, errors.New("invalid outputs json")
+	var outs []outputSpec
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		payments, err := zip321.Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("uris file line %d: %v", line, err)
+		}
+		for _, p := range payments {
+			outs = append(outs, outputSpec{TxOutput: p.TxOutput(), Label: p.Label})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read uris file: %w", err)
+	}
+	if len(outs) == 0 {
+		return nil, errors.New("uris file has no payment requests")
 	}
 	return outs, nil
 }
 
-func writePlan(stdout, stderr io.Writer, jsonOut bool, outPath string, plan types.TxPlan) int {
-	b, err := json.MarshalIndent(plan, "", "  ")
+// sendURIOutput parses --uri, which replaces --to, --amount-zat and the memo
+// flags.
+func sendURIOutput(fs *flag.FlagSet, uri string) (outputSpec, error) {
+	for _, name := range []string{"to", "amount-zat", "memo-hex", "memo-text", "no-memo"} {
+		if flagSet(fs, name) {
+			return outputSpec{}, fmt.Errorf("uri cannot be combined with --%s", name)
+		}
+	}
+	payments, err := zip321.Parse(uri)
 	if err != nil {
-		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "marshal txplan")
+		return outputSpec{}, err
+	}
+	if len(payments) != 1 {
+		return outputSpec{}, fmt.Errorf("uri requests %d payments; use send-many --uris-file", len(payments))
+	}
+	return outputSpec{TxOutput: payments[0].TxOutput(), Label: payments[0].Label}, nil
+}
+
+// feeFlags are the fee policy settings shared by all plan commands.
+type feeFlags struct {
+	Multiplier   uint64
+	AddZat       uint64
+	PerActionZat uint64
+	CapZat       uint64
+	Priority     string
+}
+
+// resolveFeeFlags applies --fee-priority from the config file, or from
+// estimate for "auto". A preset replaces --fee-multiplier and --fee-add-zat, so
+// combining them is an error.
+func resolveFeeFlags(fs *flag.FlagSet, configPath, priority string, multiplier, addZat uint64, estimate func() (uint64, error)) (feeFlags, error) {
+	priority = strings.ToLower(strings.TrimSpace(priority))
+	if priority == "" {
+		return feeFlags{Multiplier: multiplier, AddZat: addZat}, nil
+	}
+	if flagSet(fs, "fee-multiplier") || flagSet(fs, "fee-add-zat") {
+		return feeFlags{}, errors.New("fee-priority cannot be combined with --fee-multiplier or --fee-add-zat")
 	}
-	b = append(b, '\n')
 
-	if outPath != "" {
-		if err := os.WriteFile(outPath, b, 0o600); err != nil {
-			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("write %s: %v", filepath.Base(outPath), err))
+	if priority == txbuild.FeePriorityAuto {
+		m, err := estimate()
+		if err != nil {
+			return feeFlags{}, fmt.Errorf("fee-priority auto: %w", err)
+		}
+		return feeFlags{Multiplier: m, Priority: priority}, nil
+	}
+
+	cfg, ok, err := loadConfig(configPath)
+	if err != nil {
+		return feeFlags{}, err
+	}
+	if !ok {
+		return feeFlags{}, errors.New("fee-priority requires --config (or JUNO_TXBUILD_CONFIG)")
+	}
+	preset, err := cfg.FeePriority(priority)
+	if err != nil {
+		return feeFlags{}, err
+	}
+	return feeFlags{
+		Multiplier:   preset.Multiplier,
+		AddZat:       preset.AddZat,
+		PerActionZat: preset.PerActionZat,
+		CapZat:       preset.CapZat,
+		Priority:     priority,
+	}, nil
+}
+
+// resolveUFVK returns --ufvk, or the wallet's ufvk from the config file.
+func resolveUFVK(configPath, walletID, ufvk string) (string, error) {
+	if ufvk = strings.TrimSpace(ufvk); ufvk != "" {
+		return ufvk, nil
+	}
+	cfg, ok, err := loadConfig(configPath)
+	if err != nil || !ok {
+		return "", err
+	}
+	return cfg.Wallet(walletID).UFVK, nil
+}
+
+// checkExactFeeFlags rejects --fee-zat combined with flags it overrides.
+func checkExactFeeFlags(fs *flag.FlagSet) error {
+	if !flagSet(fs, "fee-zat") {
+		return nil
+	}
+	for _, name := range []string{"fee-multiplier", "fee-add-zat", "fee-priority"} {
+		if flagSet(fs, name) {
+			return fmt.Errorf("fee-zat cannot be combined with --%s", name)
+		}
+	}
+	return nil
+}
+
+// autoFeeMultiplier returns the estimator used by --fee-priority auto.
+func autoFeeMultiplier(rpcURL, rpcUser, rpcPass string) func() (uint64, error) {
+	return func() (uint64, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		est, err := txbuild.EstimateFee(ctx, txbuild.EstimateFeeConfig{
+			RPCURL:  rpcURL,
+			RPCUser: rpcUser,
+			RPCPass: rpcPass,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return est.Multiplier, nil
+	}
+}
+
+// loadConfig loads --config (or JUNO_TXBUILD_CONFIG). It reports false when
+// neither is set.
+func loadConfig(path string) (config.Config, bool, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv("JUNO_TXBUILD_CONFIG"))
+	}
+	if path == "" {
+		return config.Config{}, false, nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return config.Config{}, false, err
+	}
+	return cfg, true, nil
+}
+
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseSubtractFeeFrom parses --subtract-fee-from: "all" or a comma-separated
+// list of output indices.
+func parseSubtractFeeFrom(s string, outputCount int) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(s, "all") {
+		out := make([]int, outputCount)
+		for i := range out {
+			out[i] = i
+		}
+		return out, nil
+	}
+
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		idx, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || idx < 0 {
+			return nil, fmt.Errorf("subtract-fee-from: invalid index %q", strings.TrimSpace(part))
+		}
+		if idx >= outputCount {
+			return nil, fmt.Errorf("subtract-fee-from: index %d out of range", idx)
+		}
+		if slices.Contains(out, idx) {
+			return nil, fmt.Errorf("subtract-fee-from: index %d duplicated", idx)
+		}
+		out = append(out, idx)
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// writePlan validates plan and writes it to output.Path and stdout, in
+// output.Format (a PCZT of the plan for formatPCZT) and encrypted to
+// output.EncryptTo if set.
+func writePlan(stdout, stderr io.Writer, jsonOut bool, output outputFlags, plan txbuild.TxPlan) int {
+	if plan.PlanID == "" {
+		var err error
+		if plan, err = plan.WithID(); err != nil {
+			return writeErr(stdout, stderr, jsonOut, txbuild.ErrCodeInvalidPlan, err.Error())
+		}
+	}
+	if err := txbuild.ValidatePlan(plan); err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, txbuild.ErrCodeInvalidPlan, err.Error())
+	}
+	var b []byte
+	var err error
+	switch output.Format {
+	case formatCBOR:
+		if b, err = txbuild.MarshalPlanCBOR(plan); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+		}
+	case formatPCZT:
+		if output.UFVK == "" {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "format pczt is only supported by send, sweep and export-pczt")
+		}
+		if b, err = pczt.FromPlan(plan, output.UFVK); err != nil {
+			var ce types.CodedError
+			if errors.As(err, &ce) {
+				return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+			}
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+		}
+	default:
+		if b, err = json.MarshalIndent(plan, "", "  "); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "marshal txplan")
+		}
+		b = append(b, '\n')
+	}
+	if len(output.EncryptTo) > 0 {
+		if b, err = encryptPlan(b, output.EncryptTo); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+		}
+	}
+
+	if a := plan.FeeAnalysis; a != nil && a.UnpaidActions > 0 {
+		fmt.Fprintf(stderr, "warning: fee %s leaves %d of %d ZIP-317 actions unpaid (conventional fee %s); inclusion risk: %s\n", plan.FeeZat, a.UnpaidActions, a.LogicalActions, a.ConventionalFeeZat, a.InclusionRisk)
+	}
+
+	var summary planSummary
+	if jsonOut {
+		if summary, err = summarizePlan(plan); err != nil {
+			return writeErr(stdout, stderr, jsonOut, txbuild.ErrCodeInvalidPlan, err.Error())
+		}
+	}
+
+	if output.Path != "" {
+		if err := os.WriteFile(output.Path, b, 0o600); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("write %s: %v", filepath.Base(output.Path), err))
 		}
 	}
 
 	if jsonOut {
+		var data any = plan
+		switch {
+		case len(output.EncryptTo) > 0:
+			data = map[string]any{"age": string(b)}
+		case output.Format == formatCBOR:
+			data = map[string]any{"cbor": base64.StdEncoding.EncodeToString(b)}
+		case output.Format == formatPCZT:
+			data = map[string]any{"pczt": base64.StdEncoding.EncodeToString(b)}
+		}
 		_ = json.NewEncoder(stdout).Encode(map[string]any{
 			"version": jsonVersionV1,
 			"status":  "ok",
-			"data":    plan,
+			"data":    data,
+			"summary": summary,
 		})
 		return 0
 	}
 
+	fmt.Fprintf(stderr, "plan_id: %s\n", plan.PlanID)
 	_, _ = stdout.Write(b)
 	return 0
 }
 
+// planSummary is the "summary" object of the --json envelope.
+type planSummary struct {
+	PlanID    string `json:"plan_id"`
+	AmountZat string `json:"amount_zat"`
+	FeeZat    string `json:"fee_zat"`
+	Outputs   int    `json:"outputs"`
+	Spends    int    `json:"spends"`
+	// From fee_analysis, if present.
+	UnpaidActions int    `json:"unpaid_actions"`
+	InclusionRisk string `json:"inclusion_risk,omitempty"`
+}
+
+// outputsZat returns the sum of a plan's output amounts.
+func outputsZat(plan txbuild.TxPlan) (uint64, error) {
+	var total uint64
+	for i, o := range plan.Outputs {
+		v, err := strconv.ParseUint(o.AmountZat, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("txplan: outputs[%d].amount_zat invalid", i)
+		}
+		if total+v < total {
+			return 0, errors.New("txplan: outputs sum overflow")
+		}
+		total += v
+	}
+	return total, nil
+}
+
+func summarizePlan(plan txbuild.TxPlan) (planSummary, error) {
+	total, err := outputsZat(plan)
+	if err != nil {
+		return planSummary{}, err
+	}
+	summary := planSummary{
+		PlanID:    plan.PlanID,
+		AmountZat: strconv.FormatUint(total, 10),
+		FeeZat:    plan.FeeZat,
+		Outputs:   len(plan.Outputs),
+		Spends:    len(plan.Notes),
+	}
+	if a := plan.FeeAnalysis; a != nil {
+		summary.UnpaidActions = a.UnpaidActions
+		summary.InclusionRisk = a.InclusionRisk
+	}
+	return summary, nil
+}
+
 func rpcConfigFromFlags(url, user, pass string) (string, string, string, error) {
 	if strings.TrimSpace(url) == "" {
 		url = os.Getenv("JUNO_RPC_URL")
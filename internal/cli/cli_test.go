@@ -3,9 +3,15 @@ package cli
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
 )
 
 func TestWriteErr_JSON_IncludesVersion(t *testing.T) {
@@ -25,15 +31,32 @@ func TestWriteErr_JSON_IncludesVersion(t *testing.T) {
 	}
 }
 
+// testPlan returns a minimal TxPlan that validates against the v0 schema.
+func testPlan() txbuild.TxPlan {
+	return txbuild.TxPlan{
+		Version:       types.V0,
+		Kind:          types.TxPlanKindWithdrawal,
+		WalletID:      "hot",
+		Chain:         "regtest",
+		Outputs:       []txbuild.TxOutput{{TxOutput: types.TxOutput{ToAddress: "j1a", AmountZat: "100000"}}},
+		ChangeAddress: "j1change",
+		FeeZat:        "10000",
+		Notes: []txbuild.SpendNote{{OrchardSpendNote: types.OrchardSpendNote{
+			ActionNullifier: "00",
+			CMX:             "00",
+			Path:            []string{},
+			EphemeralKey:    "00",
+			EncCiphertext:   "00",
+		}}},
+	}
+}
+
 func TestWritePlan_JSON_IncludesVersion(t *testing.T) {
 	var out, errBuf bytes.Buffer
 
-	plan := types.TxPlan{
-		Version: types.V0,
-		Kind:    types.TxPlanKindWithdrawal,
-	}
+	plan := testPlan()
 
-	code := writePlan(&out, &errBuf, true, "", plan)
+	code := writePlan(&out, &errBuf, true, outputFlags{}, plan)
 	if code != 0 {
 		t.Fatalf("unexpected exit code: %d (stderr=%q)", code, errBuf.String())
 	}
@@ -45,4 +68,303 @@ func TestWritePlan_JSON_IncludesVersion(t *testing.T) {
 	if v["version"] != "v1" || v["status"] != "ok" {
 		t.Fatalf("unexpected json: %v", v)
 	}
+	if _, ok := v["summary"].(map[string]any); !ok {
+		t.Fatalf("missing summary: %v", v)
+	}
+}
+
+func TestWritePlan_RejectsInvalidPlan(t *testing.T) {
+	plan := testPlan()
+	plan.FeeZat = "1.5"
+
+	var out, errBuf bytes.Buffer
+	if code := writePlan(&out, &errBuf, true, outputFlags{}, plan); code != 1 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"invalid_plan"`)) || !bytes.Contains(out.Bytes(), []byte("/fee_zat")) {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "outputs.json")
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(good, []byte(`[{"to_address":"j1a","amount_zat":"1"}]`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(bad, []byte(`[{"to_address":"j1a","amount_zat":"1","to_address":"j1b"}]`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if code := RunWithIO([]string{"validate", good}, &out, &errBuf); code != 0 {
+		t.Fatalf("validate good: exit %d (%s)", code, errBuf.String())
+	}
+
+	out.Reset()
+	if code := RunWithIO([]string{"validate", "--json", bad}, &out, &errBuf); code != 1 {
+		t.Fatalf("validate bad: exit %d", code)
+	}
+	var env struct {
+		Data struct {
+			Valid  bool `json:"valid"`
+			Errors []struct {
+				Pointer string `json:"pointer"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if env.Data.Valid || len(env.Data.Errors) != 1 || env.Data.Errors[0].Pointer != "/0" {
+		t.Fatalf("unexpected result: %s", out.String())
+	}
+}
+
+func TestDetectSchema_PlanVersions(t *testing.T) {
+	for version, want := range map[string]string{"v0": "txplan.v0", "v1": "txplan.v1"} {
+		got, err := detectSchema(map[string]any{"version": version})
+		if err != nil || got != want {
+			t.Fatalf("detectSchema(%s) = %q, %v; want %q", version, got, err, want)
+		}
+	}
+	if _, err := detectSchema(map[string]any{"version": "v9"}); err == nil {
+		t.Fatalf("expected error for unknown version")
+	}
+	if err := checkPlanAccounting([]byte(`{"version":"v1","fee_zat":"1","total_input_zat":"5","change_zat":"0","outputs":[{"to_address":"j1a","amount_zat":"3"}],"notes":[{"value_zat":"5"}]}`)); err == nil {
+		t.Fatalf("expected accounting error")
+	}
+}
+
+func TestSummarizePlan(t *testing.T) {
+	plan := txbuild.TxPlan{
+		Outputs: []txbuild.TxOutput{
+			{TxOutput: types.TxOutput{ToAddress: "j1a", AmountZat: "100000"}},
+			{TxOutput: types.TxOutput{ToAddress: "j1b", AmountZat: "250000"}},
+		},
+		FeeZat: "15000",
+		Notes:  make([]txbuild.SpendNote, 2),
+	}
+
+	got, err := summarizePlan(plan)
+	if err != nil || got.AmountZat != "350000" || got.FeeZat != "15000" || got.Outputs != 2 || got.Spends != 2 {
+		t.Fatalf("unexpected summary: %+v (%v)", got, err)
+	}
+
+	for _, bad := range []string{"x", "18446744073709551615"} {
+		plan.Outputs[1].AmountZat = bad
+		if _, err := summarizePlan(plan); err == nil {
+			t.Fatalf("expected error for amount_zat %q", bad)
+		}
+	}
+}
+
+func TestParseSubtractFeeFrom(t *testing.T) {
+	got, err := parseSubtractFeeFrom("all", 3)
+	if err != nil {
+		t.Fatalf("parseSubtractFeeFrom: %v", err)
+	}
+	if len(got) != 3 || got[0] != 0 || got[2] != 2 {
+		t.Fatalf("got %v", got)
+	}
+
+	got, err = parseSubtractFeeFrom("2, 0", 3)
+	if err != nil {
+		t.Fatalf("parseSubtractFeeFrom: %v", err)
+	}
+	if len(got) != 2 || got[0] != 0 || got[1] != 2 {
+		t.Fatalf("got %v", got)
+	}
+
+	for _, bad := range []string{"3", "-1", "x", "1,1"} {
+		if _, err := parseSubtractFeeFrom(bad, 3); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestResolveOutputMemo(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		spec outputSpec
+		tmpl string
+		want string
+	}{
+		{"hex", outputSpec{TxOutput: types.TxOutput{MemoHex: "ff"}}, "t", "ff"},
+		{"text", outputSpec{MemoText: "hi"}, "", "6869"},
+		{"no memo", outputSpec{NoMemo: true}, "t", "f6"},
+		{"template", outputSpec{RequestID: "r1"}, "id {request_id}", "6964207231"},
+		{"none", outputSpec{}, "", ""},
+	} {
+		got, err := resolveOutputMemo(tc.spec, tc.tmpl)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: memo=%q want %q", tc.name, got, tc.want)
+		}
+	}
+
+	for name, spec := range map[string]outputSpec{
+		"hex and text":       {TxOutput: types.TxOutput{MemoHex: "ff"}, MemoText: "hi"},
+		"text and no memo":   {MemoText: "hi", NoMemo: true},
+		"missing request id": {MemoText: "id {request_id}"},
+	} {
+		if _, err := resolveOutputMemo(spec, ""); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadURIs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uris.txt")
+	body := "# payouts\njuno:j1a?amount=1\n\njuno:?address=j1b&amount=0.5&address.1=j1c&amount.1=2&memo.1=aGk\n"
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write uris: %v", err)
+	}
+	got, err := loadURIs(path)
+	if err != nil {
+		t.Fatalf("loadURIs: %v", err)
+	}
+	if len(got) != 3 || got[0].AmountZat != "100000000" || got[1].ToAddress != "j1b" || got[2].MemoHex != "6869" {
+		t.Fatalf("unexpected outputs: %+v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("juno:j1a?amount=1\njuno:j1b?amount=x\n"), 0o600); err != nil {
+		t.Fatalf("write uris: %v", err)
+	}
+	if _, err := loadURIs(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected line 2 error, got %v", err)
+	}
+}
+
+func TestLoadMetadata(t *testing.T) {
+	if m, err := loadMetadata(""); err != nil || m != nil {
+		t.Fatalf("loadMetadata(\"\")=%s, %v", m, err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "meta.json")
+	if err := os.WriteFile(path, []byte("{\"batch\": 12345678901234567890}\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	m, err := loadMetadata(path)
+	if err != nil {
+		t.Fatalf("loadMetadata: %v", err)
+	}
+	if string(m) != `{"batch": 12345678901234567890}` {
+		t.Fatalf("metadata=%s", m)
+	}
+
+	bad := filepath.Join(dir, "dup.json")
+	if err := os.WriteFile(bad, []byte(`{"a":1,"a":2}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := loadMetadata(bad); err == nil || !strings.Contains(err.Error(), "duplicate key") {
+		t.Fatalf("expected duplicate key error, got %v", err)
+	}
+}
+
+func TestResolveFeeFlags_Priority(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"fee_priorities":{"urgent":{"multiplier":4,"cap_zat":100000}}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	fs.Uint64("fee-multiplier", 1, "")
+	if err := fs.Parse(nil); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	got, err := resolveFeeFlags(fs, path, "urgent", 1, 0, nil)
+	if err != nil {
+		t.Fatalf("resolveFeeFlags: %v", err)
+	}
+	if got.Priority != "urgent" || got.Multiplier != 4 || got.CapZat != 100_000 {
+		t.Fatalf("unexpected fee flags: %+v", got)
+	}
+
+	if err := fs.Parse([]string{"--fee-multiplier", "2"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if _, err := resolveFeeFlags(fs, path, "urgent", 2, 0, nil); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestResolveUFVK(t *testing.T) {
+	t.Setenv("JUNO_TXBUILD_CONFIG", "")
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"wallets":{"hot":{"ufvk":"jviewregtest1hot"}}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	for _, tc := range []struct {
+		configPath, walletID, ufvk, want string
+	}{
+		{path, "hot", "", "jviewregtest1hot"},
+		{path, "hot", " jviewregtest1flag ", "jviewregtest1flag"},
+		{path, "cold", "", ""},
+		{"", "hot", "", ""},
+	} {
+		got, err := resolveUFVK(tc.configPath, tc.walletID, tc.ufvk)
+		if err != nil {
+			t.Fatalf("resolveUFVK: %v", err)
+		}
+		if got != tc.want {
+			t.Fatalf("resolveUFVK(%q, %q, %q)=%q want %q", tc.configPath, tc.walletID, tc.ufvk, got, tc.want)
+		}
+	}
+}
+
+func TestResolveFeeFlags_Auto(t *testing.T) {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	fs.Uint64("fee-multiplier", 1, "")
+	if err := fs.Parse(nil); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	t.Setenv("JUNO_TXBUILD_CONFIG", "")
+	got, err := resolveFeeFlags(fs, "", "auto", 1, 0, func() (uint64, error) { return 3, nil })
+	if err != nil {
+		t.Fatalf("resolveFeeFlags: %v", err)
+	}
+	if got.Priority != "auto" || got.Multiplier != 3 {
+		t.Fatalf("unexpected fee flags: %+v", got)
+	}
+
+	if _, err := resolveFeeFlags(fs, "", "auto", 1, 0, func() (uint64, error) { return 0, errors.New("rpc down") }); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestWritePlan_WarnsOnUnpaidActions(t *testing.T) {
+	plan := testPlan()
+	plan.FeeZat = "5000"
+	plan.FeeAnalysis = &txbuild.FeeAnalysis{
+		LogicalActions:     2,
+		ConventionalFeeZat: "10000",
+		UnpaidActions:      1,
+		InclusionRisk:      "elevated",
+	}
+
+	var out, errBuf bytes.Buffer
+	if code := writePlan(&out, &errBuf, true, outputFlags{}, plan); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	if !bytes.Contains(errBuf.Bytes(), []byte("inclusion risk: elevated")) {
+		t.Fatalf("missing warning: %q", errBuf.String())
+	}
+
+	var env struct {
+		Summary planSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if env.Summary.UnpaidActions != 1 || env.Summary.InclusionRisk != "elevated" {
+		t.Fatalf("unexpected summary: %+v", env.Summary)
+	}
 }
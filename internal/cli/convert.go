@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+func runConvert(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var to string
+	var rpcURL string
+	var rpcUser string
+	var rpcPass string
+	var scanURL string
+	var scanBearerToken string
+	var output outputFlags
+	var jsonOut bool
+
+	fs.StringVar(&to, "to", "", "target TxPlan version: v0 or v1")
+	fs.StringVar(&rpcURL, "rpc-url", "", "junocashd RPC URL, to upgrade v0 plans (or JUNO_RPC_URL)")
+	fs.StringVar(&rpcUser, "rpc-user", "", "junocashd RPC username (or JUNO_RPC_USER)")
+	fs.StringVar(&rpcPass, "rpc-pass", "", "junocashd RPC password (or JUNO_RPC_PASS)")
+	fs.StringVar(&scanURL, "scan-url", "", "optional juno-scan URL to read note values and heights from (or JUNO_SCAN_URL)")
+	fs.StringVar(&scanBearerToken, "scan-bearer-token", "", "optional juno-scan bearer token (or JUNO_SCAN_BEARER_TOKEN)")
+	fs.StringVar(&output.Path, "out", "", "optional path to write the TxPlan")
+	fs.Var(&output.Format, "format", "plan encoding: json (default) or cbor (compact binary)")
+	fs.Var(&output.EncryptTo, "encrypt-to", "age X25519 recipient (age1...) to encrypt the plan to; repeatable")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if strings.TrimSpace(to) == "" {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "to is required (v0 or v1)")
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "convert takes exactly one plan file (or -)")
+	}
+	path := fs.Arg(0)
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("read %s: %v", filepath.Base(path), err))
+	}
+
+	if txbuild.IsPlanCBOR(data) {
+		plan, err := txbuild.UnmarshalPlanCBOR(data)
+		if err != nil {
+			var ce types.CodedError
+			if errors.As(err, &ce) {
+				return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+			}
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+		}
+		if data, err = json.Marshal(plan); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "marshal txplan")
+		}
+	}
+
+	// The node is only needed to upgrade, so a missing rpc-url is left to
+	// txbuild.ConvertPlan.
+	if strings.TrimSpace(rpcURL) == "" {
+		rpcURL = os.Getenv("JUNO_RPC_URL")
+	}
+	if strings.TrimSpace(rpcUser) == "" {
+		rpcUser = os.Getenv("JUNO_RPC_USER")
+	}
+	if strings.TrimSpace(rpcPass) == "" {
+		rpcPass = os.Getenv("JUNO_RPC_PASS")
+	}
+	if strings.TrimSpace(scanURL) == "" {
+		scanURL = os.Getenv("JUNO_SCAN_URL")
+	}
+	if strings.TrimSpace(scanBearerToken) == "" {
+		scanBearerToken = os.Getenv("JUNO_SCAN_BEARER_TOKEN")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	plan, err := txbuild.ConvertPlan(ctx, txbuild.ConvertConfig{
+		RPCURL:  rpcURL,
+		RPCUser: rpcUser,
+		RPCPass: rpcPass,
+
+		ScanURL:         scanURL,
+		ScanBearerToken: scanBearerToken,
+
+		Version: to,
+	}, data)
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	return writePlan(stdout, stderr, jsonOut, output, plan)
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunConvert(t *testing.T) {
+	t.Setenv("JUNO_RPC_URL", "")
+	b, err := json.Marshal(testPlan())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plan.json")
+	extra := filepath.Join(dir, "extra.json")
+	if err := os.WriteFile(plain, b, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(extra, []byte(strings.Replace(string(b), "{", `{"spend_limit_zat":"5",`, 1)), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if code := RunWithIO([]string{"convert", "--to", "v0", plain}, &out, &errBuf); code != 0 {
+		t.Fatalf("convert: exit %d (%s)", code, errBuf.String())
+	}
+
+	for name, args := range map[string][]string{
+		"lossy":   {"convert", "--json", "--to", "v0", extra},
+		"upgrade": {"convert", "--json", "--to", "v1", plain},
+	} {
+		out.Reset()
+		if code := RunWithIO(args, &out, &errBuf); code != 1 {
+			t.Fatalf("%s: exit %d", name, code)
+		}
+		var env struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+			t.Fatalf("%s: invalid json: %v", name, err)
+		}
+		want := map[string]string{"lossy": "lossy_conversion", "upgrade": "invalid_request"}[name]
+		if env.Error.Code != want {
+			t.Fatalf("%s: code %q, want %q", name, env.Error.Code, want)
+		}
+	}
+}
+
+func TestRunConvert_CBORRoundTrip(t *testing.T) {
+	plan, err := testPlan().WithID()
+	if err != nil {
+		t.Fatalf("WithID: %v", err)
+	}
+	dir := t.TempDir()
+	cborPath := filepath.Join(dir, "plan.cbor")
+
+	var out, errBuf bytes.Buffer
+	if code := writePlan(&out, &errBuf, true, outputFlags{Path: cborPath, Format: formatCBOR}, plan); code != 0 {
+		t.Fatalf("writePlan: exit %d (%s)", code, out.String())
+	}
+	var env struct {
+		Data struct {
+			CBOR []byte `json:"cbor"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	onDisk, err := os.ReadFile(cborPath)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(env.Data.CBOR, onDisk) {
+		t.Fatalf("--json data.cbor differs from --out")
+	}
+
+	out.Reset()
+	if code := RunWithIO([]string{"convert", "--to", "v0", "--format", "json", cborPath}, &out, &errBuf); code != 0 {
+		t.Fatalf("convert: exit %d (%s)", code, errBuf.String())
+	}
+	want, _ := json.MarshalIndent(plan, "", "  ")
+	if strings.TrimSpace(out.String()) != string(want) {
+		t.Fatalf("round trip changed the plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	if code := RunWithIO([]string{"convert", "--to", "v0", "--format", "protobuf", cborPath}, &out, &errBuf); code != 2 {
+		t.Fatalf("unsupported format: exit %d", code)
+	}
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Abdullah1738/juno-txbuild/internal/logic"
+	"github.com/Abdullah1738/juno-txbuild/internal/schema"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// Output file formats (--outputs-format).
+const (
+	outputsFormatAuto = "auto"
+	outputsFormatJSON = "json"
+	outputsFormatCSV  = "csv"
+)
+
+// csvColumns maps accepted CSV header names to canonical column names.
+var csvColumns = map[string]string{
+	"address":      "address",
+	"to_address":   "address",
+	"amount_zat":   "amount_zat",
+	"amount":       "amount",
+	"memo_text":    "memo_text",
+	"memo_hex":     "memo_hex",
+	"label":        "label",
+	"request_id":   "request_id",
+	"metadata":     "metadata",
+	"subtract_fee": "subtract_fee",
+}
+
+var decimalAmountRE = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,8})?$`)
+
+// csvError locates an error in the CSV file (1-based row and column; the
+// header is row 1).
+func csvError(row, col int, name string, err error) error {
+	return fmt.Errorf("outputs csv row %d column %d (%s): %v", row, col, name, err)
+}
+
+// parseOutputsCSV reads outputs from CSV with a header row. Columns are
+// address (or to_address), amount_zat or amount (decimal JUNO), and the
+// optional memo_text, memo_hex, label, request_id, metadata (a JSON value)
+// and subtract_fee.
+func parseOutputsCSV(r io.Reader) ([]outputSpec, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("outputs csv: empty file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("outputs csv: %v", err)
+	}
+
+	cols := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		c, ok := csvColumns[name]
+		if !ok {
+			return nil, csvError(1, i+1, h, errors.New("unknown column"))
+		}
+		if seen[c] {
+			return nil, csvError(1, i+1, h, errors.New("duplicate column"))
+		}
+		seen[c] = true
+		cols[i] = c
+	}
+	if !seen["address"] {
+		return nil, errors.New("outputs csv: missing address column")
+	}
+	if !seen["amount_zat"] && !seen["amount"] {
+		return nil, errors.New("outputs csv: missing amount_zat or amount column")
+	}
+
+	var outs []outputSpec
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				return nil, fmt.Errorf("outputs csv row %d column %d: %v", pe.Line, pe.Column, pe.Err)
+			}
+			return nil, fmt.Errorf("outputs csv: %v", err)
+		}
+		row, _ := cr.FieldPos(0)
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+
+		var o outputSpec
+		for i, v := range rec {
+			col := i + 1
+			switch cols[i] {
+			case "address":
+				o.ToAddress = strings.TrimSpace(v)
+				if o.ToAddress == "" {
+					return nil, csvError(row, col, header[i], errors.New("address required"))
+				}
+			case "amount_zat":
+				v = strings.TrimSpace(v)
+				if v == "" {
+					continue
+				}
+				if o.AmountZat != "" {
+					return nil, csvError(row, col, header[i], errors.New("both amount_zat and amount set"))
+				}
+				if !strings.EqualFold(v, txbuild.AmountMax) {
+					if _, err := strconv.ParseUint(v, 10, 64); err != nil {
+						return nil, csvError(row, col, header[i], fmt.Errorf("invalid zatoshi amount %q", v))
+					}
+				}
+				o.AmountZat = v
+			case "amount":
+				v = strings.TrimSpace(v)
+				if v == "" {
+					continue
+				}
+				if o.AmountZat != "" {
+					return nil, csvError(row, col, header[i], errors.New("both amount_zat and amount set"))
+				}
+				if !decimalAmountRE.MatchString(v) {
+					return nil, csvError(row, col, header[i], fmt.Errorf("invalid JUNO amount %q (at most 8 decimals)", v))
+				}
+				zat, err := logic.ParseZECToZat(v)
+				if err != nil {
+					return nil, csvError(row, col, header[i], fmt.Errorf("invalid JUNO amount %q: %v", v, err))
+				}
+				o.AmountZat = strconv.FormatUint(zat, 10)
+			case "memo_text":
+				o.MemoText = v
+			case "memo_hex":
+				o.MemoHex = strings.TrimSpace(v)
+			case "label":
+				o.Label = v
+			case "request_id":
+				o.RequestID = strings.TrimSpace(v)
+			case "metadata":
+				v = strings.TrimSpace(v)
+				if v == "" {
+					continue
+				}
+				if _, err := schema.Decode([]byte(v)); err != nil {
+					return nil, csvError(row, col, header[i], fmt.Errorf("invalid metadata json: %v", err))
+				}
+				o.Metadata = json.RawMessage(v)
+			case "subtract_fee":
+				v = strings.TrimSpace(v)
+				if v == "" {
+					continue
+				}
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					return nil, csvError(row, col, header[i], fmt.Errorf("invalid boolean %q", v))
+				}
+				o.SubtractFee = b
+			}
+		}
+		if o.AmountZat == "" {
+			return nil, fmt.Errorf("outputs csv row %d: amount_zat or amount required", row)
+		}
+		if o.MemoText != "" || o.MemoHex != "" {
+			if _, err := resolveOutputMemo(o, ""); err != nil {
+				return nil, fmt.Errorf("outputs csv row %d: %v", row, err)
+			}
+		}
+		outs = append(outs, o)
+	}
+	if len(outs) == 0 {
+		return nil, errors.New("outputs csv: no outputs")
+	}
+	return outs, nil
+}
+
+// detectOutputsFormat picks csv or json for --outputs-format auto from the
+// file extension, or the first non-space byte of data.
+func detectOutputsFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return outputsFormatCSV
+	case ".json":
+		return outputsFormatJSON
+	}
+	trimmed := strings.TrimLeft(string(data), " \t\r\n\ufeff")
+	if strings.HasPrefix(trimmed, "[") {
+		return outputsFormatJSON
+	}
+	return outputsFormatCSV
+}
+
+// checkControlTotal compares the sum of the output amounts with expected.
+func checkControlTotal(outs []outputSpec, expected uint64) error {
+	var sum uint64
+	for i, o := range outs {
+		if strings.EqualFold(o.AmountZat, txbuild.AmountMax) {
+			return fmt.Errorf("control total: outputs[%d] uses amount max", i)
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(o.AmountZat), 10, 64)
+		if err != nil {
+			return fmt.Errorf("control total: outputs[%d].amount_zat invalid", i)
+		}
+		if sum+v < sum {
+			return errors.New("control total: outputs sum overflow")
+		}
+		sum += v
+	}
+	if sum != expected {
+		return fmt.Errorf("control total mismatch: outputs sum to %d zat, expected %d", sum, expected)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOutputsCSV(t *testing.T) {
+	in := "address,amount,amount_zat,memo_text,label,request_id,metadata\n" +
+		"j1a,1.5,,\"Payout, {request_id}\",Alice,w-1,\"{\"\"ledger\"\":42}\"\n" +
+		"j1b,,2500,,,,\n"
+	got, err := parseOutputsCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parseOutputsCSV: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("outputs=%d", len(got))
+	}
+	if got[0].ToAddress != "j1a" || got[0].AmountZat != "150000000" || got[0].MemoText != "Payout, {request_id}" || got[0].Label != "Alice" || got[0].RequestID != "w-1" || string(got[0].Metadata) != `{"ledger":42}` {
+		t.Fatalf("unexpected first output: %+v", got[0])
+	}
+	if got[1].AmountZat != "2500" {
+		t.Fatalf("unexpected second output: %+v", got[1])
+	}
+	if err := checkControlTotal(got, 150_002_500); err != nil {
+		t.Fatalf("checkControlTotal: %v", err)
+	}
+	if err := checkControlTotal(got, 150_002_501); err == nil {
+		t.Fatalf("expected control total mismatch")
+	}
+}
+
+func TestParseOutputsCSV_Errors(t *testing.T) {
+	for in, want := range map[string]string{
+		"address,amount\nj1a,1.123456789\n":      "row 2 column 2 (amount)",
+		"address,amount_zat\nj1a,1\nj1b,x\n":     "row 3 column 2 (amount_zat)",
+		"address,amount_zat,amount\nj1a,1,1\n":   "row 2 column 3 (amount): both",
+		"address,amount_zat\n,1\n":               "row 2 column 1 (address)",
+		"address,amount,colour\n":                "row 1 column 3 (colour): unknown column",
+		"address,to_address,amount\n":            "duplicate column",
+		"amount_zat\n1\n":                        "missing address column",
+		"address,amount_zat\nj1a,\n":             "row 2: amount_zat or amount required",
+		"address,amount_zat,memo_hex\nj1a,1,zz":  "row 2: memo hex",
+		"address,amount_zat\nj1a,1,extra\n":      "row 2",
+		"address,amount_zat,metadata\nj1a,1,{\n": "row 2 column 3 (metadata): invalid metadata json",
+		"address,amount_zat\n":                   "no outputs",
+	} {
+		_, err := parseOutputsCSV(strings.NewReader(in))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("parseOutputsCSV(%q)=%v want %q", in, err, want)
+		}
+	}
+}
+
+func TestDetectOutputsFormat(t *testing.T) {
+	for _, tc := range []struct {
+		path, data, want string
+	}{
+		{"payouts.CSV", "[", outputsFormatCSV},
+		{"payouts.json", "address", outputsFormatJSON},
+		{"-", " \n[{}]", outputsFormatJSON},
+		{"-", "address,amount\n", outputsFormatCSV},
+	} {
+		if got := detectOutputsFormat(tc.path, []byte(tc.data)); got != tc.want {
+			t.Fatalf("detectOutputsFormat(%q, %q)=%q want %q", tc.path, tc.data, got, tc.want)
+		}
+	}
+}
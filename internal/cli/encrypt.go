@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/Abdullah1738/juno-sdk-go/types"
+)
+
+// ErrCodeDecryptFailed reports an encrypted plan that none of the
+// identities can decrypt, or that is corrupted.
+const ErrCodeDecryptFailed types.ErrorCode = "decrypt_failed"
+
+// recipientsFlag collects --encrypt-to age X25519 recipients.
+type recipientsFlag []age.Recipient
+
+func (r *recipientsFlag) String() string { return "" }
+
+func (r *recipientsFlag) Set(s string) error {
+	rcpt, err := age.ParseX25519Recipient(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("encrypt-to: %v", err)
+	}
+	*r = append(*r, rcpt)
+	return nil
+}
+
+// encryptPlan encrypts b to recipients as an ASCII-armored age file.
+func encryptPlan(b []byte, recipients []age.Recipient) ([]byte, error) {
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: %v", err)
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, fmt.Errorf("encrypt: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("encrypt: %v", err)
+	}
+	if err := aw.Close(); err != nil {
+		return nil, fmt.Errorf("encrypt: %v", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// decryptFile decrypts an age file, armored or binary.
+func decryptFile(data []byte, identities []age.Identity) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(data)
+	br := bufio.NewReader(src)
+	if start, _ := br.Peek(len(armor.Header)); string(start) == armor.Header {
+		src = armor.NewReader(br)
+	} else {
+		src = br
+	}
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func runDecrypt(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var identityPath string
+	var outPath string
+	var jsonOut bool
+
+	fs.StringVar(&identityPath, "identity", "", "age identity file (AGE-SECRET-KEY-1... lines, as written by age-keygen)")
+	fs.StringVar(&outPath, "out", "", "optional path to write the decrypted plan")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if strings.TrimSpace(identityPath) == "" {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "identity is required")
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "decrypt takes exactly one file (or -)")
+	}
+	idData, err := os.ReadFile(identityPath)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("read %s: %v", filepath.Base(identityPath), err))
+	}
+	identities, err := age.ParseIdentities(bytes.NewReader(idData))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("%s: %v", filepath.Base(identityPath), err))
+	}
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	plain, err := decryptFile(data, identities)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return writeErr(stdout, stderr, jsonOut, ErrCodeDecryptFailed, "no identity matches a recipient of the file")
+		}
+		return writeErr(stdout, stderr, jsonOut, ErrCodeDecryptFailed, err.Error())
+	}
+	if outPath != "" {
+		if err := os.WriteFile(outPath, plain, 0o600); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("write %s: %v", filepath.Base(outPath), err))
+		}
+	}
+
+	if jsonOut {
+		if !json.Valid(plain) {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "decrypted file is not JSON")
+		}
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data":    json.RawMessage(plain),
+		})
+		return 0
+	}
+	if outPath == "" {
+		_, _ = stdout.Write(plain)
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+func TestWritePlan_EncryptDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+	other, _ := age.GenerateX25519Identity()
+	idPath := filepath.Join(dir, "signer.key")
+	otherPath := filepath.Join(dir, "other.key")
+	if err := os.WriteFile(idPath, []byte("# signer\n"+id.String()+"\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(otherPath, []byte(other.String()+"\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var recipients recipientsFlag
+	if err := recipients.Set(id.Recipient().String()); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := recipients.Set("age1notarecipient"); err == nil {
+		t.Fatalf("expected error for an invalid recipient")
+	}
+
+	plan, _ := testPlan().WithID()
+	planPath := filepath.Join(dir, "plan.json.age")
+	var out bytes.Buffer
+	if code := writePlan(&out, io.Discard, true, outputFlags{Path: planPath, EncryptTo: recipients}, plan); code != 0 {
+		t.Fatalf("writePlan: exit %d", code)
+	}
+	var resp struct {
+		Data struct {
+			Age string `json:"age"`
+		} `json:"data"`
+		Summary planSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if !strings.HasPrefix(resp.Data.Age, armor.Header) || resp.Summary.PlanID != plan.PlanID {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+	enc, err := os.ReadFile(planPath)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.HasPrefix(string(enc), armor.Header) || strings.Contains(string(enc), "j1change") {
+		t.Fatalf("plan file is not encrypted:\n%s", enc)
+	}
+
+	var errBuf bytes.Buffer
+	out.Reset()
+	if code := RunWithIO([]string{"decrypt", "--identity", idPath, planPath}, &out, &errBuf); code != 0 {
+		t.Fatalf("decrypt: exit %d (%s)", code, errBuf.String())
+	}
+	var got txbuild.TxPlan
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("decrypted plan: %v", err)
+	}
+	if got.PlanID != plan.PlanID || got.ChangeAddress != "j1change" {
+		t.Fatalf("unexpected plan: %+v", got)
+	}
+
+	out.Reset()
+	if code := RunWithIO([]string{"decrypt", "--json", "--identity", otherPath, planPath}, &out, &errBuf); code != 1 {
+		t.Fatalf("decrypt with the wrong identity: exit %d", code)
+	}
+	if !strings.Contains(out.String(), `"decrypt_failed"`) {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+func runHash(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("hash", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var canonical bool
+	var jsonOut bool
+
+	fs.BoolVar(&canonical, "canonical", false, "print the canonical (RFC 8785) plan that is hashed instead of the plan ID")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "hash takes exactly one plan file (or -)")
+	}
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	canon, err := txbuild.CanonicalPlanJSON(data)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "invalid txplan json: "+err.Error())
+	}
+	id, err := txbuild.PlanIDJSON(data)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "invalid txplan json: "+err.Error())
+	}
+	var plan struct {
+		PlanID string `json:"plan_id"`
+	}
+	_ = json.Unmarshal(data, &plan)
+	if plan.PlanID != "" && plan.PlanID != id {
+		return writeErr(stdout, stderr, jsonOut, txbuild.ErrCodeInvalidPlan, fmt.Sprintf("plan_id %s does not match the plan (%s)", plan.PlanID, id))
+	}
+
+	if jsonOut {
+		out := map[string]any{"plan_id": id}
+		if canonical {
+			out["canonical"] = string(canon)
+		}
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data":    out,
+		})
+		return 0
+	}
+	if canonical {
+		_, _ = stdout.Write(canon)
+		return 0
+	}
+	fmt.Fprintln(stdout, id)
+	return 0
+}
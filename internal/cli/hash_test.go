@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunHash(t *testing.T) {
+	plan, err := testPlan().WithID()
+	if err != nil {
+		t.Fatalf("WithID: %v", err)
+	}
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	dir := t.TempDir()
+	good := filepath.Join(dir, "plan.json")
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(good, b, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(bad, []byte(strings.Replace(string(b), `"fee_zat": "10000"`, `"fee_zat": "10001"`, 1)), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if code := RunWithIO([]string{"hash", good}, &out, &errBuf); code != 0 {
+		t.Fatalf("hash: exit %d (%s)", code, errBuf.String())
+	}
+	if got := strings.TrimSpace(out.String()); got != plan.PlanID {
+		t.Fatalf("hash printed %q, want %q", got, plan.PlanID)
+	}
+
+	out.Reset()
+	if code := RunWithIO([]string{"hash", "--canonical", good}, &out, &errBuf); code != 0 {
+		t.Fatalf("hash --canonical: exit %d", code)
+	}
+	if strings.Contains(out.String(), "plan_id") || !strings.HasPrefix(out.String(), `{"account":0,`) {
+		t.Fatalf("unexpected canonical form: %s", out.String())
+	}
+
+	if code := RunWithIO([]string{"hash", bad}, &out, &errBuf); code != 1 {
+		t.Fatalf("hash of a modified plan: exit %d", code)
+	}
+	if code := RunWithIO([]string{"validate", bad}, &out, &errBuf); code != 1 {
+		t.Fatalf("validate of a modified plan: exit %d", code)
+	}
+}
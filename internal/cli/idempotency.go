@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/junocashd"
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/chain"
+	"github.com/Abdullah1738/juno-txbuild/internal/idempotency"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// idempotencyFlags are the idempotency store settings shared by all plan
+// commands. The store is off unless Dir is set.
+type idempotencyFlags struct {
+	Dir    string
+	Window time.Duration
+	// Overrides the key derived from the request.
+	Key string
+}
+
+// idempotencyRequest identifies a request in the idempotency store.
+type idempotencyRequest struct {
+	Key string
+	// idempotency.RequestHash of the request; a stored plan is only returned
+	// for a request with the same hash.
+	Hash string
+}
+
+// newIdempotencyRequest returns the request with its store key:
+// --idempotency-key, the request IDs if every output has one, or else a hash
+// of the outputs.
+func newIdempotencyRequest(key, walletID string, kind types.TxPlanKind, outs []txbuild.TxOutput) idempotencyRequest {
+	walletID = strings.TrimSpace(walletID)
+	ids := make([]string, 0, len(outs))
+	plain := make([]types.TxOutput, 0, len(outs))
+	for _, o := range outs {
+		if o.RequestID != "" {
+			ids = append(ids, o.RequestID)
+		}
+		plain = append(plain, o.TxOutput)
+	}
+	req := idempotencyRequest{Key: strings.TrimSpace(key), Hash: idempotency.RequestHash(walletID, kind, plain)}
+	switch {
+	case req.Key != "":
+	case len(ids) > 0 && len(ids) == len(outs):
+		req.Key = idempotency.RequestKey(walletID, ids)
+	default:
+		req.Key = idempotency.OutputsKey(walletID, kind, plain)
+	}
+	return req
+}
+
+// chainState is the chain lookups of planIdempotent.
+type chainState struct {
+	Tip      func(context.Context) (int64, error)
+	TxStatus func(context.Context, string) (chain.TxStatus, error)
+}
+
+// nodeChainState returns the chainState of a junocashd node.
+func nodeChainState(rpcURL, rpcUser, rpcPass string) chainState {
+	rpc := junocashd.New(rpcURL, rpcUser, rpcPass)
+	return chainState{
+		Tip: func(ctx context.Context) (int64, error) {
+			info, err := chain.GetChainInfo(ctx, rpc)
+			if err != nil {
+				return 0, err
+			}
+			return info.Height, nil
+		},
+		TxStatus: func(ctx context.Context, txid string) (chain.TxStatus, error) {
+			return chain.GetTxStatus(ctx, rpc, txid)
+		},
+	}
+}
+
+// planIdempotent runs build unless the idempotency store holds a plan for the
+// request that is still usable (see idempotency.Entry.Decide). A stored plan
+// of a request with other outputs fails with idempotency.ErrCodeConflict. A
+// new plan is stored under the request key before it is returned.
+func planIdempotent(ctx context.Context, idem idempotencyFlags, req idempotencyRequest, node chainState, stderr io.Writer, build func() (txbuild.TxPlan, error)) (txbuild.TxPlan, error) {
+	if strings.TrimSpace(idem.Dir) == "" {
+		if strings.TrimSpace(idem.Key) != "" {
+			return txbuild.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "idempotency-key requires idempotency-dir"}
+		}
+		return build()
+	}
+	if idem.Window < 0 {
+		return txbuild.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "idempotency-window must be >= 0"}
+	}
+
+	key := req.Key
+	store, err := idempotency.Open(idem.Dir)
+	if err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	unlock, err := store.Lock(key)
+	if err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	defer unlock()
+
+	e, ok, err := store.Get(key)
+	if err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	if ok && e.RequestHash != "" && e.RequestHash != req.Hash {
+		return txbuild.TxPlan{}, types.CodedError{Code: idempotency.ErrCodeConflict, Message: fmt.Sprintf("request %q was planned with different outputs; use a new request ID or idempotency key", key)}
+	}
+	if ok {
+		height, err := node.Tip(ctx)
+		if err != nil {
+			return txbuild.TxPlan{}, err
+		}
+		switch e.Decide(time.Now(), idem.Window, height) {
+		case idempotency.ActionRefuse:
+			if e.BroadcastAt == nil {
+				return txbuild.TxPlan{}, refuseOutstanding(key, e, height)
+			}
+			msg := fmt.Sprintf("request %q was broadcast at %s and its plan does not expire until after height %d", key, e.BroadcastAt.Format(time.RFC3339), e.ExpiryHeight)
+			if e.TxID != "" {
+				msg += " (txid " + e.TxID + ")"
+			}
+			return txbuild.TxPlan{}, types.CodedError{Code: idempotency.ErrCodeAlreadyBroadcast, Message: msg}
+		case idempotency.ActionVerify:
+			if err := checkNotMined(ctx, node, key, e); err != nil {
+				return txbuild.TxPlan{}, err
+			}
+		case idempotency.ActionReplay:
+			var plan txbuild.TxPlan
+			if err := json.Unmarshal(e.Plan, &plan); err != nil {
+				return txbuild.TxPlan{}, fmt.Errorf("idempotency: corrupt plan for %q", key)
+			}
+			fmt.Fprintf(stderr, "idempotency: returning the plan built at %s for %s\n", e.CreatedAt.Format(time.RFC3339), key)
+			return plan, nil
+		}
+	}
+
+	plan, err := build()
+	if err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	plan.IdempotencyKey = key
+	if plan, err = plan.WithID(); err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	if err := txbuild.ValidatePlan(plan); err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	b, err := json.Marshal(plan)
+	if err != nil {
+		return txbuild.TxPlan{}, errors.New("marshal txplan")
+	}
+	if err := store.Put(idempotency.Entry{
+		Key:          key,
+		CreatedAt:    time.Now().UTC(),
+		ExpiryHeight: plan.ExpiryHeight,
+		RequestHash:  req.Hash,
+		Plan:         b,
+	}); err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	return plan, nil
+}
+
+// refuseOutstanding refuses a request whose stored plan was not marked
+// broadcast and is past the window or expired: it may have been signed, and
+// only a recorded txid lets the request be planned again.
+func refuseOutstanding(key string, e idempotency.Entry, tipHeight int64) error {
+	state := fmt.Sprintf("can be mined until after height %d", e.ExpiryHeight)
+	if tipHeight >= int64(e.ExpiryHeight) {
+		state = fmt.Sprintf("expired at height %d but cannot be checked to be unmined without a txid", e.ExpiryHeight)
+	}
+	msg := fmt.Sprintf("request %q was planned at %s and not marked broadcast; its plan %s; record it with mark-broadcast --txid if it was broadcast, or use a new idempotency key", key, e.CreatedAt.Format(time.RFC3339), state)
+	return types.CodedError{Code: idempotency.ErrCodePlanOutstanding, Message: msg}
+}
+
+// checkNotMined refuses to plan the request of a broadcast, expired entry
+// again unless the node knows that its transaction was not mined.
+func checkNotMined(ctx context.Context, node chainState, key string, e idempotency.Entry) error {
+	prefix := fmt.Sprintf("request %q was broadcast at %s", key, e.BroadcastAt.Format(time.RFC3339))
+	if e.TxID == "" {
+		return types.CodedError{Code: idempotency.ErrCodeAlreadyBroadcast, Message: prefix + " without a txid (mark-broadcast --txid), so its plan cannot be checked to be unmined after expiry"}
+	}
+	status, err := node.TxStatus(ctx, e.TxID)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case chain.TxNotMined:
+		return nil
+	case chain.TxMined:
+		return types.CodedError{Code: idempotency.ErrCodeAlreadyBroadcast, Message: fmt.Sprintf("%s and its transaction %s was mined", prefix, e.TxID)}
+	default:
+		return types.CodedError{Code: idempotency.ErrCodeAlreadyBroadcast, Message: fmt.Sprintf("%s and the node does not know its transaction %s (not in its wallet or mempool, and no -txindex), so it cannot be checked to be unmined", prefix, e.TxID)}
+	}
+}
+
+func runMarkBroadcast(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("mark-broadcast", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var dir string
+	var planPath string
+	var key string
+	var txid string
+	var jsonOut bool
+
+	fs.StringVar(&dir, "idempotency-dir", "", "idempotency store directory")
+	fs.StringVar(&planPath, "plan", "", "TxPlan JSON file (or - for stdin) built with --idempotency-dir")
+	fs.StringVar(&key, "idempotency-key", "", "request key, instead of --plan")
+	fs.StringVar(&txid, "txid", "", "txid of the broadcast transaction; without it the request stays refused after the plan expires")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+
+	planPath = strings.TrimSpace(planPath)
+	key = strings.TrimSpace(key)
+	if (planPath == "") == (key == "") {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "exactly one of plan and idempotency-key is required")
+	}
+	if planPath != "" {
+		var data []byte
+		var err error
+		if planPath == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(planPath)
+		}
+		if err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("read %s: %v", filepath.Base(planPath), err))
+		}
+		var plan txbuild.TxPlan
+		if err := json.Unmarshal(data, &plan); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "invalid txplan json")
+		}
+		if plan.IdempotencyKey == "" {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "plan has no idempotency_key (built without --idempotency-dir)")
+		}
+		key = plan.IdempotencyKey
+	}
+
+	store, err := idempotency.Open(dir)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	e, err := store.MarkBroadcast(key, txid, time.Now())
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	if jsonOut {
+		e.Plan = nil
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data":    e,
+		})
+		return 0
+	}
+	fmt.Fprintf(stdout, "%s: broadcast at %s (expiry_height %d)\n", key, e.BroadcastAt.Format(time.RFC3339), e.ExpiryHeight)
+	return 0
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/chain"
+	"github.com/Abdullah1738/juno-txbuild/internal/idempotency"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+func TestNewIdempotencyRequest(t *testing.T) {
+	out := func(addr, requestID string) txbuild.TxOutput {
+		return txbuild.TxOutput{TxOutput: types.TxOutput{ToAddress: addr, AmountZat: "100"}, RequestID: requestID}
+	}
+	kind := types.TxPlanKindWithdrawal
+
+	if got := newIdempotencyRequest(" k ", "hot", kind, nil); got.Key != "k" {
+		t.Fatalf("explicit key=%q", got.Key)
+	}
+	byID := newIdempotencyRequest("", " hot ", kind, []txbuild.TxOutput{out("j1a", "w-2"), out("j1b", "w-1")})
+	if byID.Key != idempotency.RequestKey("hot", []string{"w-1", "w-2"}) {
+		t.Fatalf("request key=%q", byID.Key)
+	}
+	// Unless every output has a request ID, the request is keyed by content.
+	partial := newIdempotencyRequest("", "hot", kind, []txbuild.TxOutput{out("j1a", "w-1"), out("j1b", "")})
+	if !strings.HasPrefix(partial.Key, "outputs:") || partial.Key != newIdempotencyRequest("", "hot", kind, []txbuild.TxOutput{out("j1a", ""), out("j1b", "")}).Key {
+		t.Fatalf("outputs key=%q", partial.Key)
+	}
+	// The hash covers the outputs, not the request IDs.
+	changed := newIdempotencyRequest("", "hot", kind, []txbuild.TxOutput{out("j1a", "w-2"), out("j1c", "w-1")})
+	if changed.Key != byID.Key || changed.Hash == byID.Hash || partial.Hash != byID.Hash {
+		t.Fatalf("hashes: %q %q %q", byID.Hash, changed.Hash, partial.Hash)
+	}
+}
+
+func TestPlanIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	idem := idempotencyFlags{Dir: dir, Window: time.Hour}
+	ctx := context.Background()
+
+	var tipHeight int64 = 100
+	txStatus := chain.TxUnknown
+	node := chainState{
+		Tip: func(context.Context) (int64, error) { return tipHeight, nil },
+		TxStatus: func(_ context.Context, txid string) (chain.TxStatus, error) {
+			if txid != "ab" {
+				t.Fatalf("txid=%q", txid)
+			}
+			return txStatus, nil
+		},
+	}
+	req := idempotencyRequest{Key: "request:hot:w-1", Hash: "h1"}
+	builds := 0
+	build := func() (txbuild.TxPlan, error) {
+		builds++
+		p := testPlan()
+		p.ExpiryHeight = 141
+		p.FeeZat = strings.Repeat("1", builds) + "0000"
+		return p, nil
+	}
+
+	first, err := planIdempotent(ctx, idem, req, node, io.Discard, build)
+	if err != nil {
+		t.Fatalf("first plan: %v", err)
+	}
+	if first.IdempotencyKey != "request:hot:w-1" {
+		t.Fatalf("idempotency_key=%q", first.IdempotencyKey)
+	}
+
+	var stderr bytes.Buffer
+	again, err := planIdempotent(ctx, idem, req, node, &stderr, build)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if builds != 1 || again.FeeZat != first.FeeZat || !strings.Contains(stderr.String(), "returning the plan built at") {
+		t.Fatalf("retry rebuilt the plan: builds=%d fee=%s stderr=%q", builds, again.FeeZat, stderr.String())
+	}
+
+	// A retry with other outputs under the same key is a conflict.
+	_, err = planIdempotent(ctx, idem, idempotencyRequest{Key: req.Key, Hash: "h2"}, node, io.Discard, build)
+	var ce types.CodedError
+	if !errors.As(err, &ce) || ce.Code != idempotency.ErrCodeConflict || builds != 1 {
+		t.Fatalf("expected %s, got %v", idempotency.ErrCodeConflict, err)
+	}
+
+	// Past the window, or once expired, an unbroadcast plan may still have been
+	// signed: it is refused rather than replayed or planned again.
+	_, err = planIdempotent(ctx, idempotencyFlags{Dir: dir}, req, node, io.Discard, build)
+	if !errors.As(err, &ce) || ce.Code != idempotency.ErrCodePlanOutstanding || !strings.Contains(ce.Message, "until after height 141") || builds != 1 {
+		t.Fatalf("window elapsed: expected %s, got %v", idempotency.ErrCodePlanOutstanding, err)
+	}
+	tipHeight = 141
+	_, err = planIdempotent(ctx, idem, req, node, io.Discard, build)
+	if !errors.As(err, &ce) || ce.Code != idempotency.ErrCodePlanOutstanding || !strings.Contains(ce.Message, "expired") || builds != 1 {
+		t.Fatalf("expired: expected %s, got %v", idempotency.ErrCodePlanOutstanding, err)
+	}
+	tipHeight = 100
+
+	planPath := filepath.Join(dir, "plan.json")
+	var out bytes.Buffer
+	if code := writePlan(&out, io.Discard, false, outputFlags{Path: planPath}, again); code != 0 {
+		t.Fatalf("writePlan exit=%d", code)
+	}
+	if code := RunWithIO([]string{"mark-broadcast", "--idempotency-dir", dir, "--plan", planPath, "--txid", "AB"}, &out, io.Discard); code != 0 {
+		t.Fatalf("mark-broadcast exit=%d", code)
+	}
+
+	_, err = planIdempotent(ctx, idem, req, node, io.Discard, build)
+	if !errors.As(err, &ce) || ce.Code != idempotency.ErrCodeAlreadyBroadcast || !strings.Contains(ce.Message, "txid ab") {
+		t.Fatalf("expected %s, got %v", idempotency.ErrCodeAlreadyBroadcast, err)
+	}
+
+	// Once the broadcast plan has expired, the request is planned again only
+	// if its transaction is known not to be mined.
+	tipHeight = 141
+	for _, status := range []chain.TxStatus{chain.TxMined, chain.TxUnknown} {
+		txStatus = status
+		_, err = planIdempotent(ctx, idem, req, node, io.Discard, build)
+		if !errors.As(err, &ce) || ce.Code != idempotency.ErrCodeAlreadyBroadcast {
+			t.Fatalf("status %d: expected %s, got %v", status, idempotency.ErrCodeAlreadyBroadcast, err)
+		}
+	}
+	txStatus = chain.TxNotMined
+	replanned, err := planIdempotent(ctx, idem, req, node, io.Discard, build)
+	if err != nil {
+		t.Fatalf("replan: %v", err)
+	}
+	if builds != 2 || replanned.FeeZat == first.FeeZat {
+		t.Fatalf("expected a new plan: builds=%d", builds)
+	}
+}
+
+func TestPlanIdempotent_Disabled(t *testing.T) {
+	builds := 0
+	build := func() (txbuild.TxPlan, error) { builds++; return testPlan(), nil }
+	for i := 0; i < 2; i++ {
+		if _, err := planIdempotent(context.Background(), idempotencyFlags{}, idempotencyRequest{Key: "k"}, chainState{}, io.Discard, build); err != nil {
+			t.Fatalf("plan: %v", err)
+		}
+	}
+	if builds != 2 {
+		t.Fatalf("builds=%d", builds)
+	}
+	if _, err := planIdempotent(context.Background(), idempotencyFlags{Key: "k"}, idempotencyRequest{Key: "k"}, chainState{}, io.Discard, build); err == nil {
+		t.Fatalf("expected error for idempotency-key without idempotency-dir")
+	}
+}
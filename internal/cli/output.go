@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plan encodings (--format).
+const (
+	formatJSON = "json"
+	// Compact binary encoding; see txbuild.MarshalPlanCBOR.
+	formatCBOR = "cbor"
+	// Partially Created Zcash Transaction (send and sweep); see pczt.FromPlan.
+	formatPCZT = "pczt"
+)
+
+// outputFlags say where and how a plan is written.
+type outputFlags struct {
+	Path      string
+	Format    planFormat
+	EncryptTo recipientsFlag
+	// Wallet UFVK the spent notes are decrypted with, for formatPCZT. Only
+	// commands that set it can write PCZTs.
+	UFVK string
+}
+
+// planFormat is --format; the zero value is formatJSON.
+type planFormat string
+
+func (f *planFormat) String() string {
+	if *f == "" {
+		return formatJSON
+	}
+	return string(*f)
+}
+
+func (f *planFormat) Set(s string) error {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case formatJSON, formatCBOR, formatPCZT:
+		*f = planFormat(s)
+		return nil
+	}
+	return fmt.Errorf("format: unsupported format %q (want json, cbor or pczt)", s)
+}
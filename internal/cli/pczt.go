@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/pczt"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// readWalletPlan reads a plan (JSON or CBOR) and the UFVK of its wallet:
+// ufvk, or wallets.<wallet-id>.ufvk in the config file.
+func readWalletPlan(path, configPath, ufvk string) (txbuild.TxPlan, string, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return txbuild.TxPlan{}, "", err
+	}
+	plan, err := readPlan(data)
+	if err != nil {
+		return txbuild.TxPlan{}, "", err
+	}
+	if ufvk, err = resolveUFVK(configPath, plan.WalletID, ufvk); err != nil {
+		return txbuild.TxPlan{}, "", err
+	}
+	if ufvk == "" {
+		return txbuild.TxPlan{}, "", errors.New("ufvk is required (--ufvk or wallets.<wallet-id>.ufvk in the config file)")
+	}
+	return plan, ufvk, nil
+}
+
+func runExportPCZT(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export-pczt", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var ufvk string
+	var configPath string
+	var output outputFlags
+	var jsonOut bool
+
+	fs.StringVar(&ufvk, "ufvk", "", "wallet unified full viewing key (jview*1...) to decrypt the spent notes with (default: wallets.<wallet-id>.ufvk in the config file)")
+	fs.StringVar(&configPath, "config", "", "optional juno-txbuild config file (JSON)")
+	fs.StringVar(&output.Path, "out", "", "optional path to write the PCZT")
+	fs.Var(&output.EncryptTo, "encrypt-to", "age X25519 recipient (age1...) to encrypt the PCZT to; repeatable")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "export-pczt takes exactly one plan file (or -)")
+	}
+	plan, ufvk, err := readWalletPlan(fs.Arg(0), configPath, ufvk)
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	output.Format = formatPCZT
+	output.UFVK = ufvk
+	return writePlan(stdout, stderr, jsonOut, output, plan)
+}
+
+func runImportPCZT(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import-pczt", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var output outputFlags
+	var jsonOut bool
+
+	fs.StringVar(&output.Path, "out", "", "optional path to write the TxPlan")
+	fs.Var(&output.Format, "format", "plan encoding: json (default) or cbor (compact binary)")
+	fs.Var(&output.EncryptTo, "encrypt-to", "age X25519 recipient (age1...) to encrypt the plan to; repeatable")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "import-pczt takes exactly one PCZT file (or -)")
+	}
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	plan, err := pczt.Import(data)
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, pczt.ErrCodeInvalidPCZT, err.Error())
+	}
+	return writePlan(stdout, stderr, jsonOut, output, plan)
+}
+
+func runTxSkeleton(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tx-skeleton", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var ufvk string
+	var configPath string
+	var jsonOut bool
+
+	fs.StringVar(&ufvk, "ufvk", "", "wallet unified full viewing key (jview*1...) to decrypt the spent notes with (default: wallets.<wallet-id>.ufvk in the config file)")
+	fs.StringVar(&configPath, "config", "", "optional juno-txbuild config file (JSON)")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "tx-skeleton takes exactly one plan file (or -)")
+	}
+	plan, ufvk, err := readWalletPlan(fs.Arg(0), configPath, ufvk)
+	if err == nil {
+		err = txbuild.ValidatePlan(plan)
+	}
+	var s pczt.Skeleton
+	if err == nil {
+		s, err = pczt.VerifyCommitment(plan, ufvk)
+	}
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	if jsonOut {
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data":    s,
+		})
+		return 0
+	}
+	b, _ := json.MarshalIndent(s, "", "  ")
+	fmt.Fprintf(stdout, "%s\n", b)
+	return 0
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+func TestRunPCZT_Errors(t *testing.T) {
+	dir := t.TempDir()
+	plan, _ := testPlan().WithID()
+	b, _ := json.Marshal(plan)
+	planPath := filepath.Join(dir, "plan.json")
+	if err := os.WriteFile(planPath, b, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		code string
+		msg  string
+	}{
+		{[]string{"export-pczt", "--json", planPath}, "invalid_request", "ufvk is required"},
+		{[]string{"export-pczt", "--ufvk", "jview1x", "--json", planPath}, "invalid_request", "ufvk"},
+		{[]string{"import-pczt", "--json", planPath}, "invalid_pczt", "not a pczt"},
+		{[]string{"convert", "--to", "v0", "--format", "pczt", "--json", planPath}, "invalid_request", "only supported by send, sweep and export-pczt"},
+		{[]string{"tx-skeleton", "--json", planPath}, "invalid_request", "ufvk is required"},
+		{[]string{"tx-skeleton", "--ufvk", "jviewregtest1abc", "--json", planPath}, "invalid_request", "no tx_commitment"},
+	} {
+		var out bytes.Buffer
+		if code := RunWithIO(tc.args, &out, io.Discard); code == 0 {
+			t.Fatalf("%v: expected failure", tc.args)
+		}
+		var resp struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+			t.Fatalf("%v: invalid json: %v (%q)", tc.args, err, out.String())
+		}
+		if resp.Error.Code != tc.code || !strings.Contains(resp.Error.Message, tc.msg) {
+			t.Fatalf("%v: unexpected error: %s", tc.args, out.String())
+		}
+	}
+}
+
+func TestWithTxCommitment(t *testing.T) {
+	plan, _ := testPlan().WithID()
+	build := func() (txbuild.TxPlan, error) { return plan, nil }
+
+	// Without a UFVK, plans are returned uncommitted.
+	got, err := withTxCommitment("", build)()
+	if err != nil || got.PlanID != plan.PlanID || got.TxCommitment != nil {
+		t.Fatalf("withTxCommitment without ufvk: %+v, %v", got, err)
+	}
+	// With one, the plan is committed, so a bad key fails the build.
+	if _, err := withTxCommitment("jview1x", build)(); err == nil {
+		t.Fatalf("expected ufvk error")
+	}
+	failed := errors.New("build failed")
+	if _, err := withTxCommitment("jview1x", func() (txbuild.TxPlan, error) { return txbuild.TxPlan{}, failed })(); err != failed {
+		t.Fatalf("expected the build error, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/Abdullah1738/juno-txbuild/internal/idempotency"
+	"github.com/Abdullah1738/juno-txbuild/internal/pczt"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// planFlags are the flags shared by the plan commands (send, sweep,
+// consolidate, send-many and rebalance).
+type planFlags struct {
+	RPCURL          string
+	RPCUser         string
+	RPCPass         string
+	ScanURL         string
+	ScanBearerToken string
+
+	WalletID string
+	CoinType uint
+	Account  uint
+
+	MinConf      int64
+	ExpiryOffset uint
+	MinNoteZat   uint64
+
+	FeeMultiplier uint64
+	FeeAddZat     uint64
+	FeeZat        string
+	MaxFeeZat     uint64
+	MaxFeePercent float64
+	FeePriority   string
+
+	ConfigPath   string
+	UFVK         string
+	FreshChange  bool
+	OVKPolicy    string
+	MetadataFile string
+	PlanVersion  string
+
+	Idem   idempotencyFlags
+	Output outputFlags
+	JSON   bool
+}
+
+// registerPlanFlags defines the planFlags on fs. --format and
+// --change-address differ between commands and are defined by them.
+func registerPlanFlags(fs *flag.FlagSet, f *planFlags) {
+	fs.StringVar(&f.RPCURL, "rpc-url", "", "junocashd RPC URL")
+	fs.StringVar(&f.RPCUser, "rpc-user", "", "junocashd RPC username")
+	fs.StringVar(&f.RPCPass, "rpc-pass", "", "junocashd RPC password")
+	fs.StringVar(&f.ScanURL, "scan-url", "", "optional juno-scan base URL (http://host:port)")
+	fs.StringVar(&f.ScanBearerToken, "scan-bearer-token", "", "optional bearer token for juno-scan HTTP API (Authorization: Bearer ...)")
+
+	fs.StringVar(&f.WalletID, "wallet-id", "", "wallet id")
+	fs.UintVar(&f.CoinType, "coin-type", 0, "ZIP-32 coin type (0 = auto)")
+	fs.UintVar(&f.Account, "account", 0, "unified account id")
+
+	fs.Uint64Var(&f.FeeMultiplier, "fee-multiplier", 1, "multiplies the ZIP-317 conventional fee (>=1)")
+	fs.Uint64Var(&f.FeeAddZat, "fee-add-zat", 0, "adds zatoshis on top of the conventional fee")
+	fs.StringVar(&f.FeeZat, "fee-zat", "", "exact fee in zatoshis, replacing the computed ZIP-317 fee (may be below it; refused above the block unpaid-action limit)")
+	fs.Uint64Var(&f.MaxFeeZat, "max-fee-zat", 0, "refuse to plan if the final fee exceeds this many zatoshis (0 = no limit)")
+	fs.Float64Var(&f.MaxFeePercent, "max-fee-percent", 0, "refuse to plan if the final fee exceeds this percentage of the output amount (0 = no limit)")
+	fs.StringVar(&f.FeePriority, "fee-priority", "", "named fee policy preset from the config file (fee_priorities), or auto to estimate from recent blocks and the mempool")
+	fs.StringVar(&f.ConfigPath, "config", "", "optional juno-txbuild config file (JSON)")
+	fs.StringVar(&f.UFVK, "ufvk", "", "wallet unified full viewing key (jview*1...): derives the change address, checks --change-address and commits the plan to its unsigned transaction (default: wallets.<wallet-id>.ufvk in the config file)")
+	fs.BoolVar(&f.FreshChange, "fresh-change-address", false, "with --ufvk, derive change at a random diversifier index instead of index 0")
+	fs.StringVar(&f.OVKPolicy, "ovk-policy", "", "outgoing viewing key the signer encrypts outputs to: sender, internal, none or a 32-byte hex OVK (default: signer default)")
+	fs.StringVar(&f.MetadataFile, "metadata-file", "", "optional JSON file (or - for stdin) echoed verbatim into the plan as metadata")
+	fs.StringVar(&f.PlanVersion, "plan-version", "v0", "TxPlan version to build: v0, or v1 (adds note values, change, the chain tip and provenance)")
+	fs.StringVar(&f.Idem.Dir, "idempotency-dir", "", "optional idempotency store directory: retries of a request return the plan built first (see --idempotency-window)")
+	fs.DurationVar(&f.Idem.Window, "idempotency-window", idempotency.DefaultWindow, "with --idempotency-dir, how long a stored plan is returned for retries")
+	fs.StringVar(&f.Idem.Key, "idempotency-key", "", "with --idempotency-dir, request key (default: the request IDs, or a hash of wallet and outputs)")
+	fs.Uint64Var(&f.MinNoteZat, "min-note-zat", 0, "skip spendable notes with value < min-note-zat")
+	fs.Int64Var(&f.MinConf, "minconf", 1, "minimum confirmations for spendable notes")
+	fs.UintVar(&f.ExpiryOffset, "expiry-offset", 40, "expiry height offset from next block height (chain tip + 1, min: 4)")
+
+	fs.StringVar(&f.Output.Path, "out", "", "optional path to write the TxPlan")
+	fs.Var(&f.Output.EncryptTo, "encrypt-to", "age X25519 recipient (age1...) to encrypt the plan to; repeatable")
+	fs.BoolVar(&f.JSON, "json", false, "JSON output")
+}
+
+// resolve applies the environment and the config file to the parsed flags:
+// the RPC and juno-scan settings, the fee priority and the wallet UFVK. It
+// returns the fee settings and the plan options.
+func (f *planFlags) resolve(fs *flag.FlagSet) (feeFlags, txbuild.PlanOptions, error) {
+	var err error
+	f.RPCURL, f.RPCUser, f.RPCPass, err = rpcConfigFromFlags(f.RPCURL, f.RPCUser, f.RPCPass)
+	if err != nil {
+		return feeFlags{}, txbuild.PlanOptions{}, err
+	}
+	if strings.TrimSpace(f.ScanURL) == "" {
+		f.ScanURL = os.Getenv("JUNO_SCAN_URL")
+	}
+	f.ScanURL = strings.TrimSpace(f.ScanURL)
+	if strings.TrimSpace(f.ScanBearerToken) == "" {
+		f.ScanBearerToken = os.Getenv("JUNO_SCAN_BEARER_TOKEN")
+	}
+	if strings.TrimSpace(f.ScanBearerToken) == "" {
+		f.ScanBearerToken = os.Getenv("JUNO_SCAN_API_BEARER_TOKEN")
+	}
+	f.ScanBearerToken = strings.TrimSpace(f.ScanBearerToken)
+
+	if err := checkExactFeeFlags(fs); err != nil {
+		return feeFlags{}, txbuild.PlanOptions{}, err
+	}
+	fee, err := resolveFeeFlags(fs, f.ConfigPath, f.FeePriority, f.FeeMultiplier, f.FeeAddZat, autoFeeMultiplier(f.RPCURL, f.RPCUser, f.RPCPass))
+	if err != nil {
+		return feeFlags{}, txbuild.PlanOptions{}, err
+	}
+	f.UFVK, err = resolveUFVK(f.ConfigPath, f.WalletID, f.UFVK)
+	if err != nil {
+		return feeFlags{}, txbuild.PlanOptions{}, err
+	}
+	metadata, err := loadMetadata(f.MetadataFile)
+	if err != nil {
+		return feeFlags{}, txbuild.PlanOptions{}, err
+	}
+
+	return fee, txbuild.PlanOptions{
+		UFVK:               f.UFVK,
+		FreshChangeAddress: f.FreshChange,
+		OVKPolicy:          f.OVKPolicy,
+		Metadata:           metadata,
+		PlanVersion:        f.PlanVersion,
+
+		FeePerActionZat: fee.PerActionZat,
+		FeeCapZat:       fee.CapZat,
+		FeeZat:          f.FeeZat,
+		MaxFeeZat:       f.MaxFeeZat,
+		MaxFeePercent:   f.MaxFeePercent,
+		FeePriority:     fee.Priority,
+	}, nil
+}
+
+// withTxCommitment commits the plans build returns to their unsigned
+// transaction (tx_commitment) when the wallet UFVK is known: the spent notes
+// can only be decrypted with it, so plans built without one carry none.
+func withTxCommitment(ufvk string, build func() (txbuild.TxPlan, error)) func() (txbuild.TxPlan, error) {
+	return func() (txbuild.TxPlan, error) {
+		plan, err := build()
+		if err != nil || ufvk == "" {
+			return plan, err
+		}
+		return pczt.Commit(plan, ufvk)
+	}
+}
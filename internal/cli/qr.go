@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/ur"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// urTypeTxPlan is the UR type of a CBOR-encoded TxPlan.
+const urTypeTxPlan = "juno-txplan"
+
+// qrPNGSize is the width and height of --png-dir frames, in pixels.
+const qrPNGSize = 512
+
+// readPlan parses a plan file in either encoding.
+func readPlan(data []byte) (txbuild.TxPlan, error) {
+	if txbuild.IsPlanCBOR(data) {
+		return txbuild.UnmarshalPlanCBOR(data)
+	}
+	var plan txbuild.TxPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return txbuild.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "invalid txplan json"}
+	}
+	return plan, nil
+}
+
+func runExportQR(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export-qr", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var maxFragmentLen int
+	var frames int
+	var pngDir string
+	var outPath string
+	var jsonOut bool
+
+	fs.IntVar(&maxFragmentLen, "max-fragment-len", 200, "maximum bytes of the plan per frame")
+	fs.IntVar(&frames, "frames", 0, "number of frames to emit (default: twice the fragment count, or 1 for a single-part UR)")
+	fs.StringVar(&pngDir, "png-dir", "", "optional directory to write the frames to as PNG QR codes (frame-0001.png, ...)")
+	fs.StringVar(&outPath, "out", "", "optional path to write the text frames, one per line")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "export-qr takes exactly one plan file (or -)")
+	}
+	if maxFragmentLen < 10 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "max-fragment-len must be at least 10")
+	}
+	if frames < 0 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "frames must be positive")
+	}
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	plan, err := readPlan(data)
+	if err == nil && plan.PlanID == "" {
+		plan, err = plan.WithID()
+	}
+	if err == nil {
+		err = txbuild.ValidatePlan(plan)
+	}
+	var message []byte
+	if err == nil {
+		message, err = txbuild.MarshalPlanCBOR(plan)
+	}
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, txbuild.ErrCodeInvalidPlan, err.Error())
+	}
+
+	enc, err := ur.NewEncoder(urTypeTxPlan, message, maxFragmentLen)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	if frames == 0 {
+		frames = 1
+		if enc.SeqLen() > 1 {
+			frames = 2 * enc.SeqLen()
+		}
+	}
+	parts := make([]string, frames)
+	for i := range parts {
+		if parts[i], err = enc.NextPart(); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+		}
+	}
+
+	var pngFiles []string
+	if pngDir != "" {
+		if err := os.MkdirAll(pngDir, 0o700); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("create %s: %v", filepath.Base(pngDir), err))
+		}
+		for i, p := range parts {
+			// Upper case fits the denser alphanumeric QR mode.
+			png, err := qrcode.Encode(strings.ToUpper(p), qrcode.Low, qrPNGSize)
+			if err != nil {
+				return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("frame %d: %v", i+1, err))
+			}
+			name := filepath.Join(pngDir, fmt.Sprintf("frame-%04d.png", i+1))
+			if err := os.WriteFile(name, png, 0o600); err != nil {
+				return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("write %s: %v", filepath.Base(name), err))
+			}
+			pngFiles = append(pngFiles, name)
+		}
+	}
+
+	text := strings.Join(parts, "\n") + "\n"
+	if outPath != "" {
+		if err := os.WriteFile(outPath, []byte(text), 0o600); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("write %s: %v", filepath.Base(outPath), err))
+		}
+	}
+
+	if jsonOut {
+		out := map[string]any{
+			"plan_id": plan.PlanID,
+			"ur_type": urTypeTxPlan,
+			"seq_len": enc.SeqLen(),
+			"frames":  parts,
+		}
+		if pngFiles != nil {
+			out["png_files"] = pngFiles
+		}
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data":    out,
+		})
+		return 0
+	}
+	if outPath == "" && pngDir == "" {
+		_, _ = io.WriteString(stdout, text)
+	}
+	return 0
+}
+
+func runImportQR(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import-qr", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var output outputFlags
+	var jsonOut bool
+
+	fs.StringVar(&output.Path, "out", "", "optional path to write the TxPlan")
+	fs.Var(&output.Format, "format", "plan encoding: json (default) or cbor (compact binary)")
+	fs.Var(&output.EncryptTo, "encrypt-to", "age X25519 recipient (age1...) to encrypt the plan to; repeatable")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "import-qr takes exactly one frames file (or -)")
+	}
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	// Frames are scanned in any order; duplicates and frames past the point
+	// of completion are ignored.
+	var dec ur.Decoder
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan() && !dec.Complete(); line++ {
+		frame := strings.TrimSpace(sc.Text())
+		if frame == "" {
+			continue
+		}
+		if err := dec.Receive(frame); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("line %d: %v", line, err))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	if !dec.Complete() {
+		got, need := dec.Progress()
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("incomplete: recovered %d of %d fragments", got, need))
+	}
+	urType, message := dec.Result()
+	if urType != urTypeTxPlan {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("ur type %s is not %s", urType, urTypeTxPlan))
+	}
+	plan, err := txbuild.UnmarshalPlanCBOR(message)
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	return writePlan(stdout, stderr, jsonOut, output, plan)
+}
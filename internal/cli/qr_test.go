@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+func TestRunExportImportQR(t *testing.T) {
+	dir := t.TempDir()
+	plan, _ := testPlan().WithID()
+	b, _ := json.Marshal(plan)
+	planPath := filepath.Join(dir, "plan.json")
+	if err := os.WriteFile(planPath, b, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var out bytes.Buffer
+	pngDir := filepath.Join(dir, "frames")
+	if code := RunWithIO([]string{"export-qr", "--max-fragment-len", "40", "--png-dir", pngDir, "--json", planPath}, &out, io.Discard); code != 0 {
+		t.Fatalf("export-qr: exit %d: %s", code, out.String())
+	}
+	var resp struct {
+		Data struct {
+			PlanID   string   `json:"plan_id"`
+			SeqLen   int      `json:"seq_len"`
+			Frames   []string `json:"frames"`
+			PNGFiles []string `json:"png_files"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if resp.Data.PlanID != plan.PlanID || resp.Data.SeqLen < 2 || len(resp.Data.Frames) != 2*resp.Data.SeqLen || len(resp.Data.PNGFiles) != len(resp.Data.Frames) {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+	if !strings.HasPrefix(resp.Data.Frames[0], "ur:juno-txplan/1-") {
+		t.Fatalf("unexpected frame: %s", resp.Data.Frames[0])
+	}
+	png, err := os.ReadFile(resp.Data.PNGFiles[0])
+	if err != nil || !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Fatalf("frame png: %v", err)
+	}
+
+	// Only the second half of the frames, most of them mixed, in reverse
+	// order and in upper case as a scanner reads them.
+	var frames []string
+	for i := len(resp.Data.Frames) - 1; i >= resp.Data.SeqLen-1; i-- {
+		frames = append(frames, strings.ToUpper(resp.Data.Frames[i]), "")
+	}
+	framesPath := filepath.Join(dir, "frames.txt")
+	if err := os.WriteFile(framesPath, []byte(strings.Join(frames, "\n")), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out.Reset()
+	cborPath := filepath.Join(dir, "plan.cbor")
+	if code := RunWithIO([]string{"import-qr", "--format", "cbor", "--out", cborPath, framesPath}, &out, io.Discard); code != 0 {
+		t.Fatalf("import-qr: exit %d: %s", code, out.String())
+	}
+	data, err := os.ReadFile(cborPath)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	got, err := txbuild.UnmarshalPlanCBOR(data)
+	if err != nil || got.PlanID != plan.PlanID {
+		t.Fatalf("round trip: %s, %v", got.PlanID, err)
+	}
+
+	// One frame is not enough.
+	if err := os.WriteFile(framesPath, []byte(resp.Data.Frames[0]+"\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out.Reset()
+	if code := RunWithIO([]string{"import-qr", "--json", framesPath}, &out, io.Discard); code == 0 || !strings.Contains(out.String(), "incomplete") {
+		t.Fatalf("expected incomplete error, got exit %d: %s", code, out.String())
+	}
+}
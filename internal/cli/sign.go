@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/pkcs11key"
+	"github.com/Abdullah1738/juno-txbuild/internal/schema"
+	"github.com/Abdullah1738/juno-txbuild/pkg/plansig"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// readInput reads a file argument, or stdin for "-".
+func readInput(path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", filepath.Base(path), err)
+	}
+	return data, nil
+}
+
+// loadSigner opens the signing key: a PEM key file, or a PKCS#11 key.
+func loadSigner(keyPath string, p11 pkcs11key.Config) (crypto.Signer, func(), error) {
+	keyPath = strings.TrimSpace(keyPath)
+	hasP11 := strings.TrimSpace(p11.Module) != ""
+	if (keyPath == "") == !hasP11 {
+		return nil, nil, errors.New("exactly one of key and pkcs11-module is required")
+	}
+	if keyPath != "" {
+		b, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read %s: %v", filepath.Base(keyPath), err)
+		}
+		k, err := plansig.ParsePrivateKey(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		return k, func() {}, nil
+	}
+	if p11.PIN == "" {
+		p11.PIN = os.Getenv("JUNO_PKCS11_PIN")
+	}
+	k, err := pkcs11key.Open(p11)
+	if err != nil {
+		return nil, nil, err
+	}
+	return k, k.Close, nil
+}
+
+// checkSignablePlan refuses plans that do not validate. The schema and the
+// plan_id are checked on data itself, so a signed plan cannot carry fields
+// or duplicate keys that TxPlan would drop on decoding.
+func checkSignablePlan(data []byte) error {
+	var plan txbuild.TxPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "invalid txplan json"}
+	}
+	if plan.PlanID == "" {
+		return types.CodedError{Code: txbuild.ErrCodeInvalidPlan, Message: "txplan: plan_id required"}
+	}
+	name, ok := txbuild.PlanSchema(plan.Version)
+	if !ok {
+		return types.CodedError{Code: txbuild.ErrCodeInvalidPlan, Message: fmt.Sprintf("txplan: unsupported version %q", plan.Version)}
+	}
+	if err := schema.ValidateJSON(name, data); err != nil {
+		return types.CodedError{Code: txbuild.ErrCodeInvalidPlan, Message: "txplan: " + err.Error()}
+	}
+	id, err := txbuild.PlanIDJSON(data)
+	if err != nil {
+		return types.CodedError{Code: txbuild.ErrCodeInvalidPlan, Message: "txplan: " + err.Error()}
+	}
+	if id != plan.PlanID {
+		return types.CodedError{Code: txbuild.ErrCodeInvalidPlan, Message: fmt.Sprintf("txplan: plan_id %s does not match the plan (%s)", plan.PlanID, id)}
+	}
+	// The accounting rule of v1 plans.
+	return txbuild.ValidatePlan(plan)
+}
+
+func runSign(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var keyPath string
+	var p11 pkcs11key.Config
+	var outPath string
+	var jsonOut bool
+
+	fs.StringVar(&keyPath, "key", "", "Ed25519 private key (PEM PKCS #8, e.g. from openssl genpkey -algorithm ed25519)")
+	fs.StringVar(&p11.Module, "pkcs11-module", "", "PKCS#11 module path, to sign with a token key instead of --key")
+	fs.StringVar(&p11.Token, "pkcs11-token", "", "PKCS#11 token label")
+	fs.StringVar(&p11.KeyLabel, "pkcs11-key", "", "PKCS#11 key label (CKA_LABEL of the Ed25519 key pair)")
+	fs.StringVar(&p11.PIN, "pkcs11-pin", "", "PKCS#11 user PIN (or JUNO_PKCS11_PIN)")
+	fs.StringVar(&outPath, "out", "", "optional path to write the signature envelope JSON")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "sign takes exactly one plan file (or -)")
+	}
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	if err := checkSignablePlan(data); err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, txbuild.ErrCodeInvalidPlan, err.Error())
+	}
+
+	signer, closeSigner, err := loadSigner(keyPath, p11)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	defer closeSigner()
+
+	env, err := plansig.Sign(data, signer, time.Now())
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	b, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "marshal signature")
+	}
+	b = append(b, '\n')
+	if outPath != "" {
+		if err := os.WriteFile(outPath, b, 0o600); err != nil {
+			return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, fmt.Sprintf("write %s: %v", filepath.Base(outPath), err))
+		}
+	}
+
+	if jsonOut {
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data":    env,
+		})
+		return 0
+	}
+	if outPath == "" {
+		_, _ = stdout.Write(b)
+	} else {
+		fmt.Fprintf(stdout, "signed plan %s with %s\n", env.PlanID, env.PublicKey)
+	}
+	return 0
+}
+
+func runVerifySignature(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("verify-signature", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var sigPath string
+	var keysPath string
+	var jsonOut bool
+
+	fs.StringVar(&sigPath, "signature", "", "signature envelope JSON written by sign")
+	fs.StringVar(&keysPath, "trusted-keys", "", "pinned public keys: one 64-hex Ed25519 key per line, optionally followed by a name")
+	fs.BoolVar(&jsonOut, "json", false, "JSON output")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+	if strings.TrimSpace(sigPath) == "" || strings.TrimSpace(keysPath) == "" {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "signature and trusted-keys are required")
+	}
+	if fs.NArg() != 1 {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, "verify-signature takes exactly one plan file (or -)")
+	}
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	sigData, err := readInput(sigPath)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	var env plansig.Envelope
+	dec := json.NewDecoder(bytes.NewReader(sigData))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&env); err != nil {
+		return writeErr(stdout, stderr, jsonOut, plansig.ErrCodeInvalidSignature, "invalid signature envelope json")
+	}
+	keysData, err := readInput(keysPath)
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+	trusted, err := plansig.ParseTrustedKeys(bytes.NewReader(keysData))
+	if err != nil {
+		return writeErr(stdout, stderr, jsonOut, types.ErrCodeInvalidRequest, err.Error())
+	}
+
+	key, err := plansig.Verify(data, env, trusted)
+	if err != nil {
+		var ce types.CodedError
+		if errors.As(err, &ce) {
+			return writeErr(stdout, stderr, jsonOut, ce.Code, ce.Message)
+		}
+		return writeErr(stdout, stderr, jsonOut, plansig.ErrCodeInvalidSignature, err.Error())
+	}
+
+	if jsonOut {
+		_ = json.NewEncoder(stdout).Encode(map[string]any{
+			"version": jsonVersionV1,
+			"status":  "ok",
+			"data": map[string]any{
+				"plan_id":    env.PlanID,
+				"public_key": env.PublicKey,
+				"key_name":   key.Name,
+			},
+		})
+		return 0
+	}
+	name := key.Name
+	if name == "" {
+		name = env.PublicKey
+	}
+	fmt.Fprintf(stdout, "plan %s: valid signature by %s\n", env.PlanID, name)
+	return 0
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSignVerify(t *testing.T) {
+	dir := t.TempDir()
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	keyPath := filepath.Join(dir, "builder.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	keysPath := filepath.Join(dir, "trusted-keys")
+	if err := os.WriteFile(keysPath, []byte(hex.EncodeToString(key.Public().(ed25519.PublicKey))+" builder-1\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	plan, err := testPlan().WithID()
+	if err != nil {
+		t.Fatalf("WithID: %v", err)
+	}
+	b, _ := json.MarshalIndent(plan, "", "  ")
+	planPath := filepath.Join(dir, "plan.json")
+	tamperedPath := filepath.Join(dir, "tampered.json")
+	sigPath := filepath.Join(dir, "plan.json.sig")
+	if err := os.WriteFile(planPath, b, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// Changes the content but keeps plan_id: only the signature catches it.
+	if err := os.WriteFile(tamperedPath, []byte(strings.Replace(string(b), `"j1a"`, `"j1evil"`, 1)), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if code := RunWithIO([]string{"sign", "--key", keyPath, "--out", sigPath, planPath}, &out, &errBuf); code != 0 {
+		t.Fatalf("sign: exit %d (%s)", code, errBuf.String())
+	}
+
+	out.Reset()
+	if code := RunWithIO([]string{"verify-signature", "--signature", sigPath, "--trusted-keys", keysPath, planPath}, &out, &errBuf); code != 0 {
+		t.Fatalf("verify-signature: exit %d (%s)", code, errBuf.String())
+	}
+	if !strings.Contains(out.String(), "builder-1") {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+
+	out.Reset()
+	if code := RunWithIO([]string{"verify-signature", "--json", "--signature", sigPath, "--trusted-keys", keysPath, tamperedPath}, &out, &errBuf); code != 1 {
+		t.Fatalf("verify-signature of a tampered plan: exit %d", code)
+	}
+	if !strings.Contains(out.String(), `"invalid_signature"`) {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+
+	if code := RunWithIO([]string{"sign", "--key", keyPath, tamperedPath}, &out, &errBuf); code != 1 {
+		t.Fatalf("sign of a plan with a stale plan_id: exit %d", code)
+	}
+
+	// Fields and duplicate keys that TxPlan drops on decoding are refused,
+	// although the re-encoded plan would validate.
+	for name, doc := range map[string]string{
+		"extra.json":     strings.Replace(string(b), "{", `{"x_extra": 1,`, 1),
+		"duplicate.json": strings.Replace(string(b), "{", `{"fee_zat": "1",`, 1),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		out.Reset()
+		if code := RunWithIO([]string{"sign", "--json", "--key", keyPath, path}, &out, &errBuf); code != 1 || !strings.Contains(out.String(), `"invalid_plan"`) {
+			t.Fatalf("sign %s: exit %d (%s)", name, code, out.String())
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Abdullah1738/juno-txbuild/internal/logic"
+)
+
+// Config is the optional juno-txbuild configuration file (--config or
+// JUNO_TXBUILD_CONFIG).
+type Config struct {
+	// Named fee policies selectable with --fee-priority.
+	FeePriorities map[string]FeePriority `json:"fee_priorities,omitempty"`
+	// Per-wallet settings, keyed by wallet ID.
+	Wallets map[string]Wallet `json:"wallets,omitempty"`
+}
+
+// Wallet holds the settings of one wallet.
+type Wallet struct {
+	// Unified full viewing key used to derive and check change addresses
+	// (--ufvk and check-approvals).
+	UFVK string `json:"ufvk,omitempty"`
+	// Approval tiers of the wallet's plans (check-approvals).
+	Approvals []ApprovalTier `json:"approvals,omitempty"`
+}
+
+// ApprovalTier is an M-of-N approval policy for plans that send at least
+// MinAmountZat (the sum of their outputs and fee).
+type ApprovalTier struct {
+	MinAmountZat uint64 `json:"min_amount_zat,omitempty"`
+	// Number of distinct approvers required (M).
+	Threshold int `json:"threshold"`
+	// The N approvers.
+	Approvers []Approver `json:"approvers"`
+}
+
+// Approver is a named Ed25519 approval key.
+type Approver struct {
+	Name string `json:"name"`
+	// 64 hex characters.
+	PublicKey string `json:"public_key"`
+}
+
+// FeePriority is a named fee policy preset.
+type FeePriority struct {
+	// Marginal fee per logical action (0 = ZIP-317 conventional 5000).
+	PerActionZat uint64 `json:"per_action_zat,omitempty"`
+	// Multiplies the per-action fee (0 = 1).
+	Multiplier uint64 `json:"multiplier,omitempty"`
+	// Added on top of the multiplied fee.
+	AddZat uint64 `json:"add_zat,omitempty"`
+	// Caps the fee, but never below the conventional fee (0 = no cap).
+	CapZat uint64 `json:"cap_zat,omitempty"`
+}
+
+// FeePolicy returns the preset as a logic.FeePolicy.
+func (p FeePriority) FeePolicy() logic.FeePolicy {
+	return logic.FeePolicy{
+		Multiplier:   p.Multiplier,
+		AddZat:       p.AddZat,
+		PerActionZat: p.PerActionZat,
+		CapZat:       p.CapZat,
+	}
+}
+
+func Load(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("config: read: %w", err)
+	}
+
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("config: invalid json: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c Config) Validate() error {
+	for name, p := range c.FeePriorities {
+		if strings.TrimSpace(name) == "" || name != strings.ToLower(strings.TrimSpace(name)) {
+			return fmt.Errorf("config: fee_priorities: invalid name %q (want lowercase)", name)
+		}
+		if name == "auto" {
+			return errors.New("config: fee_priorities: name \"auto\" is reserved for the fee estimator")
+		}
+		if p.PerActionZat != 0 && p.PerActionZat < logic.MarginalFeeZat {
+			return fmt.Errorf("config: fee_priorities.%s.per_action_zat must be >= %d", name, logic.MarginalFeeZat)
+		}
+	}
+	for id, w := range c.Wallets {
+		if strings.TrimSpace(id) == "" || id != strings.TrimSpace(id) {
+			return fmt.Errorf("config: wallets: invalid wallet id %q", id)
+		}
+		// check-approvals needs the UFVK to check change addresses.
+		if strings.TrimSpace(w.UFVK) == "" {
+			return fmt.Errorf("config: wallets.%s.ufvk required", id)
+		}
+		if err := validateApprovalTiers("wallets."+id+".approvals", w.Approvals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateApprovalTiers(field string, tiers []ApprovalTier) error {
+	mins := make(map[uint64]bool, len(tiers))
+	for i, t := range tiers {
+		if mins[t.MinAmountZat] {
+			return fmt.Errorf("config: %s[%d]: duplicate min_amount_zat %d", field, i, t.MinAmountZat)
+		}
+		mins[t.MinAmountZat] = true
+		if t.Threshold < 1 || t.Threshold > len(t.Approvers) {
+			return fmt.Errorf("config: %s[%d].threshold must be between 1 and the number of approvers (%d)", field, i, len(t.Approvers))
+		}
+		names := make(map[string]bool, len(t.Approvers))
+		keys := make(map[string]bool, len(t.Approvers))
+		for j, a := range t.Approvers {
+			if strings.TrimSpace(a.Name) == "" || names[a.Name] {
+				return fmt.Errorf("config: %s[%d].approvers[%d]: missing or duplicate name", field, i, j)
+			}
+			names[a.Name] = true
+			k, err := hex.DecodeString(a.PublicKey)
+			if err != nil || len(k) != 32 {
+				return fmt.Errorf("config: %s[%d].approvers[%d].public_key: want 64 hex characters", field, i, j)
+			}
+			if keys[strings.ToLower(a.PublicKey)] {
+				return fmt.Errorf("config: %s[%d].approvers[%d]: duplicate public_key", field, i, j)
+			}
+			keys[strings.ToLower(a.PublicKey)] = true
+		}
+	}
+	return nil
+}
+
+// ApprovalTier returns the approval tier of a plan of walletID sending
+// amountZat: the wallet's tier with the highest min_amount_zat not above
+// amountZat. A plan below every tier needs no approvals (the zero tier). It
+// reports false when the wallet has no tiers.
+func (c Config) ApprovalTier(walletID string, amountZat uint64) (ApprovalTier, bool) {
+	tiers := c.Wallet(walletID).Approvals
+	if len(tiers) == 0 {
+		return ApprovalTier{}, false
+	}
+	var best ApprovalTier
+	found := false
+	for _, t := range tiers {
+		if t.MinAmountZat <= amountZat && (!found || t.MinAmountZat > best.MinAmountZat) {
+			best, found = t, true
+		}
+	}
+	return best, true
+}
+
+// Wallet returns the settings of walletID (the zero Wallet if it has none).
+func (c Config) Wallet(walletID string) Wallet {
+	return c.Wallets[strings.TrimSpace(walletID)]
+}
+
+// FeePriority looks up a fee priority preset by name (case-insensitive).
+func (c Config) FeePriority(name string) (FeePriority, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return FeePriority{}, errors.New("config: fee priority name required")
+	}
+	p, ok := c.FeePriorities[name]
+	if !ok {
+		known := make([]string, 0, len(c.FeePriorities))
+		for k := range c.FeePriorities {
+			known = append(known, k)
+		}
+		sort.Strings(known)
+		if len(known) == 0 {
+			return FeePriority{}, fmt.Errorf("config: unknown fee priority %q (no fee_priorities configured)", name)
+		}
+		return FeePriority{}, fmt.Errorf("config: unknown fee priority %q (have: %s)", name, strings.Join(known, ", "))
+	}
+	return p, nil
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad_FeePriorities(t *testing.T) {
+	path := writeConfig(t, `{
+  "fee_priorities": {
+    "economy": {},
+    "urgent": {"per_action_zat": 10000, "multiplier": 2, "add_zat": 1000, "cap_zat": 500000}
+  }
+}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	p, err := cfg.FeePriority("URGENT")
+	if err != nil {
+		t.Fatalf("FeePriority: %v", err)
+	}
+	fp := p.FeePolicy()
+	if fp.PerActionZat != 10_000 || fp.Multiplier != 2 || fp.AddZat != 1_000 || fp.CapZat != 500_000 {
+		t.Fatalf("unexpected policy: %+v", fp)
+	}
+
+	if _, err := cfg.FeePriority("normal"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestLoad_RejectsInvalid(t *testing.T) {
+	for _, body := range []string{
+		`{"fee_priorities": {"slow": {"per_action_zat": 100}}}`,
+		`{"fee_priorities": {"Urgent": {}}}`,
+		`{"fee_prioritys": {}}`,
+		`{"wallets": {"hot": {}}}`,
+	} {
+		if _, err := Load(writeConfig(t, body)); err == nil {
+			t.Fatalf("expected error for %s", body)
+		}
+	}
+}
+
+func TestLoad_Wallets(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"wallets": {"hot": {"ufvk": "jviewregtest1abc"}}}`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Wallet(" hot ").UFVK; got != "jviewregtest1abc" {
+		t.Fatalf("ufvk=%q", got)
+	}
+	if got := cfg.Wallet("cold").UFVK; got != "" {
+		t.Fatalf("ufvk=%q", got)
+	}
+}
+
+func TestLoad_ApprovalTiers(t *testing.T) {
+	const (
+		alice = "0101010101010101010101010101010101010101010101010101010101010101"
+		bob   = "0202020202020202020202020202020202020202020202020202020202020202"
+		carol = "0303030303030303030303030303030303030303030303030303030303030303"
+	)
+	cfg, err := Load(writeConfig(t, `{
+  "wallets": {
+    "hot": {"ufvk": "jviewregtest1abc"},
+    "treasury": {"ufvk": "jviewregtest1def", "approvals": [
+      {"min_amount_zat": 100000000, "threshold": 2, "approvers": [
+        {"name": "alice", "public_key": "`+alice+`"},
+        {"name": "bob", "public_key": "`+bob+`"},
+        {"name": "carol", "public_key": "`+carol+`"}
+      ]}
+    ]}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tier, ok := cfg.ApprovalTier("treasury", 250_000_000)
+	if !ok || tier.Threshold != 2 || len(tier.Approvers) != 3 {
+		t.Fatalf("high-value tier: %+v %v", tier, ok)
+	}
+	tier, ok = cfg.ApprovalTier("treasury", 99_999_999)
+	if !ok || tier.Threshold != 0 {
+		t.Fatalf("below every tier: %+v %v", tier, ok)
+	}
+	// A wallet without tiers has no policy.
+	if _, ok := cfg.ApprovalTier("hot", 1); ok {
+		t.Fatalf("expected no policy for a wallet without tiers")
+	}
+	if _, ok := cfg.ApprovalTier("unknown", 1); ok {
+		t.Fatalf("expected no policy for an unknown wallet")
+	}
+
+	for _, body := range []string{
+		`{"wallets": {"w": {"ufvk": "jviewregtest1abc", "approvals": [{"threshold": 2, "approvers": [{"name": "a", "public_key": "` + alice + `"}]}]}}}`,
+		`{"wallets": {"w": {"ufvk": "jviewregtest1abc", "approvals": [{"threshold": 0, "approvers": [{"name": "a", "public_key": "` + alice + `"}]}]}}}`,
+		`{"wallets": {"w": {"ufvk": "jviewregtest1abc", "approvals": [{"threshold": 1, "approvers": [{"name": "a", "public_key": "abcd"}]}]}}}`,
+		`{"wallets": {"w": {"ufvk": "jviewregtest1abc", "approvals": [{"threshold": 1, "approvers": [{"name": "a", "public_key": "` + alice + `"}, {"name": "b", "public_key": "` + alice + `"}]}]}}}`,
+		`{"wallets": {"w": {"ufvk": "jviewregtest1abc", "approvals": [{"threshold": 1, "approvers": [{"name": "a", "public_key": "` + alice + `"}]}, {"threshold": 1, "approvers": [{"name": "a", "public_key": "` + alice + `"}]}]}}}`,
+		`{"approvals": []}`,
+		`{"wallets": {"w": {"approvals": [{"threshold": 1, "approvers": [{"name": "a", "public_key": "` + alice + `"}]}]}}}`,
+	} {
+		if _, err := Load(writeConfig(t, body)); err == nil {
+			t.Fatalf("expected error for %s", body)
+		}
+	}
+}
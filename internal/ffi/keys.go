@@ -0,0 +1,38 @@
+package ffi
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/../../rust/txbuild/include
+#cgo LDFLAGS: -L${SRCDIR}/../../rust/txbuild/target/release -ljuno_txbuild
+
+#include "juno_txbuild.h"
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+func OrchardDeriveAddressJSON(reqJSON string) (string, error) {
+	cReq := C.CString(reqJSON)
+	defer C.free(unsafe.Pointer(cReq))
+
+	out := C.juno_txbuild_orchard_derive_address_json(cReq)
+	if out == nil {
+		return "", errNull
+	}
+	defer C.juno_txbuild_string_free(out)
+
+	return C.GoString(out), nil
+}
+
+func OrchardAddressScopeJSON(reqJSON string) (string, error) {
+	cReq := C.CString(reqJSON)
+	defer C.free(unsafe.Pointer(cReq))
+
+	out := C.juno_txbuild_orchard_address_scope_json(cReq)
+	if out == nil {
+		return "", errNull
+	}
+	defer C.juno_txbuild_string_free(out)
+
+	return C.GoString(out), nil
+}
@@ -0,0 +1,51 @@
+package ffi
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/../../rust/txbuild/include
+#cgo LDFLAGS: -L${SRCDIR}/../../rust/txbuild/target/release -ljuno_txbuild
+
+#include "juno_txbuild.h"
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+func PCZTFromPlanJSON(reqJSON string) (string, error) {
+	cReq := C.CString(reqJSON)
+	defer C.free(unsafe.Pointer(cReq))
+
+	out := C.juno_txbuild_pczt_from_plan_json(cReq)
+	if out == nil {
+		return "", errNull
+	}
+	defer C.juno_txbuild_string_free(out)
+
+	return C.GoString(out), nil
+}
+
+func PCZTParseJSON(reqJSON string) (string, error) {
+	cReq := C.CString(reqJSON)
+	defer C.free(unsafe.Pointer(cReq))
+
+	out := C.juno_txbuild_pczt_parse_json(cReq)
+	if out == nil {
+		return "", errNull
+	}
+	defer C.juno_txbuild_string_free(out)
+
+	return C.GoString(out), nil
+}
+
+func TxSkeletonJSON(reqJSON string) (string, error) {
+	cReq := C.CString(reqJSON)
+	defer C.free(unsafe.Pointer(cReq))
+
+	out := C.juno_txbuild_tx_skeleton_json(cReq)
+	if out == nil {
+		return "", errNull
+	}
+	defer C.juno_txbuild_string_free(out)
+
+	return C.GoString(out), nil
+}
@@ -0,0 +1,220 @@
+// Package idempotency stores built plans by request key, so that a retried
+// request returns the plan built the first time instead of selecting new
+// notes.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+)
+
+// Error codes for requests the store refuses.
+const (
+	// The earlier plan of the request was broadcast and has not expired.
+	ErrCodeAlreadyBroadcast types.ErrorCode = "already_broadcast"
+	// The earlier plan of the request was not marked broadcast, is past the
+	// window and may still be (or have been) signed and mined.
+	ErrCodePlanOutstanding types.ErrorCode = "plan_outstanding"
+	// Another process is planning the same request.
+	ErrCodeInProgress types.ErrorCode = "request_in_progress"
+	// The key was used before for a request with different outputs.
+	ErrCodeConflict types.ErrorCode = "idempotency_conflict"
+)
+
+// DefaultWindow is how long a stored plan is returned for retries.
+const DefaultWindow = 10 * time.Minute
+
+// lockStale is the age after which a lock is assumed to be left over from a
+// crashed process. It exceeds the CLI's planning timeout.
+const lockStale = 10 * time.Minute
+
+// Entry is the stored state of one request.
+type Entry struct {
+	Key          string    `json:"key"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiryHeight uint32    `json:"expiry_height"`
+	// RequestHash of the request the plan was built for.
+	RequestHash string          `json:"request_hash,omitempty"`
+	Plan        json.RawMessage `json:"plan,omitempty"`
+
+	// Set by MarkBroadcast.
+	BroadcastAt *time.Time `json:"broadcast_at,omitempty"`
+	TxID        string     `json:"txid,omitempty"`
+}
+
+// Action is what to do with a request that has an Entry.
+type Action int
+
+const (
+	// Return the stored plan.
+	ActionReplay Action = iota
+	// Refuse the request: ErrCodeAlreadyBroadcast if the plan was broadcast,
+	// else ErrCodePlanOutstanding.
+	ActionRefuse
+	// The broadcast plan expired, but its transaction may have been mined
+	// before that. Build a new plan only once the transaction (TxID) is known
+	// not to be mined, else refuse the request.
+	ActionVerify
+)
+
+// Decide returns the action for a retry of e at now, given the chain tip.
+// An unbroadcast plan is replayed within window while it can still be mined,
+// and refused after that: it may have been signed without being marked
+// broadcast, and without a txid it cannot be checked to be unmined. A
+// broadcast plan is refused until it expires and then verified.
+func (e Entry) Decide(now time.Time, window time.Duration, tipHeight int64) Action {
+	// Mineable while the next block height is <= expiry_height.
+	live := tipHeight < int64(e.ExpiryHeight)
+	switch {
+	case e.BroadcastAt == nil && live && now.Sub(e.CreatedAt) < window:
+		return ActionReplay
+	case e.BroadcastAt == nil || live:
+		return ActionRefuse
+	default:
+		return ActionVerify
+	}
+}
+
+// RequestKey is the key of a request identified by caller request IDs: a
+// hash of the wallet and the sorted IDs, which may contain any character.
+func RequestKey(walletID string, requestIDs []string) string {
+	ids := append([]string(nil), requestIDs...)
+	sort.Strings(ids)
+	b, _ := json.Marshal(append([]string{walletID}, ids...))
+	sum := sha256.Sum256(b)
+	return "request:" + hex.EncodeToString(sum[:])
+}
+
+// OutputsKey is the key of a request without request IDs: its RequestHash.
+func OutputsKey(walletID string, kind types.TxPlanKind, outputs []types.TxOutput) string {
+	return "outputs:" + RequestHash(walletID, kind, outputs)
+}
+
+// RequestHash is a hash of the wallet, plan kind and requested outputs.
+func RequestHash(walletID string, kind types.TxPlanKind, outputs []types.TxOutput) string {
+	b, _ := json.Marshal(struct {
+		WalletID string           `json:"wallet_id"`
+		Kind     types.TxPlanKind `json:"kind"`
+		Outputs  []types.TxOutput `json:"outputs"`
+	}{walletID, kind, outputs})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Store is a directory of entries, one file per key.
+type Store struct {
+	dir string
+}
+
+// Open opens the store in dir, creating it if needed.
+func Open(dir string) (*Store, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, errors.New("idempotency: dir required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("idempotency: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) path(key, ext string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+ext)
+}
+
+// Get returns the entry of key, if any.
+func (s *Store) Get(key string) (Entry, bool, error) {
+	b, err := os.ReadFile(s.path(key, ".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("idempotency: %w", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return Entry{}, false, fmt.Errorf("idempotency: corrupt entry for %q", key)
+	}
+	if e.Key != key {
+		return Entry{}, false, fmt.Errorf("idempotency: entry key mismatch for %q", key)
+	}
+	return e, true, nil
+}
+
+// Put writes e, replacing any entry with the same key.
+func (s *Store) Put(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("idempotency: %w", err)
+	}
+	path := s.path(e.Key, ".json")
+	tmp, err := os.CreateTemp(s.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("idempotency: %w", err)
+	}
+	_, werr := tmp.Write(append(b, '\n'))
+	if err := tmp.Close(); werr == nil {
+		werr = err
+	}
+	if werr == nil {
+		werr = os.Rename(tmp.Name(), path)
+	}
+	if werr != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("idempotency: %w", werr)
+	}
+	return nil
+}
+
+// MarkBroadcast records that the plan stored for key was broadcast.
+func (s *Store) MarkBroadcast(key, txid string, at time.Time) (Entry, error) {
+	e, ok, err := s.Get(key)
+	if err != nil {
+		return Entry{}, err
+	}
+	if !ok {
+		return Entry{}, fmt.Errorf("idempotency: no plan stored for %q", key)
+	}
+	at = at.UTC()
+	e.BroadcastAt = &at
+	e.TxID = strings.ToLower(strings.TrimSpace(txid))
+	if err := s.Put(e); err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+// Lock takes the planning lock of key. It fails with ErrCodeInProgress while
+// another process holds it.
+func (s *Store) Lock(key string) (unlock func(), err error) {
+	path := s.path(key, ".lock")
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("idempotency: %w", err)
+		}
+		fi, err := os.Stat(path)
+		if err == nil && time.Since(fi.ModTime()) < lockStale {
+			break
+		}
+		if err == nil {
+			_ = os.Remove(path)
+		}
+	}
+	return nil, types.CodedError{Code: ErrCodeInProgress, Message: fmt.Sprintf("request %q is being planned by another process", key)}
+}
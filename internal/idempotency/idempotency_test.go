@@ -0,0 +1,124 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+)
+
+func TestEntryDecide(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	sent := now.Add(-time.Minute)
+	for _, tc := range []struct {
+		name string
+		e    Entry
+		tip  int64
+		want Action
+	}{
+		{"fresh", Entry{CreatedAt: now.Add(-time.Minute), ExpiryHeight: 140}, 100, ActionReplay},
+		{"live, unbroadcast, window elapsed", Entry{CreatedAt: now.Add(-time.Hour), ExpiryHeight: 140}, 100, ActionRefuse},
+		{"expired, unbroadcast", Entry{CreatedAt: now.Add(-time.Minute), ExpiryHeight: 140}, 140, ActionRefuse},
+		{"broadcast", Entry{CreatedAt: now.Add(-time.Hour), ExpiryHeight: 140, BroadcastAt: &sent}, 139, ActionRefuse},
+		{"broadcast expired", Entry{CreatedAt: now.Add(-time.Hour), ExpiryHeight: 140, BroadcastAt: &sent}, 140, ActionVerify},
+	} {
+		if got := tc.e.Decide(now, 10*time.Minute, tc.tip); got != tc.want {
+			t.Fatalf("%s: got %d want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	if a, b := RequestKey("hot", []string{"w-2", "w-1"}), RequestKey("hot", []string{"w-1", "w-2"}); a != b || !strings.HasPrefix(a, "request:") {
+		t.Fatalf("RequestKey: %q %q", a, b)
+	}
+	// Separators in IDs or the wallet do not make keys collide.
+	for _, tc := range [][2]string{
+		{RequestKey("hot", []string{"a,b"}), RequestKey("hot", []string{"a", "b"})},
+		{RequestKey("hot:a", []string{"b"}), RequestKey("hot", []string{"a:b"})},
+		{RequestKey("hot", []string{"a"}), RequestKey("cold", []string{"a"})},
+	} {
+		if tc[0] == tc[1] {
+			t.Fatalf("RequestKey collision: %q", tc[0])
+		}
+	}
+	outs := []types.TxOutput{{ToAddress: "j1a", AmountZat: "100"}}
+	a := OutputsKey("hot", types.TxPlanKindWithdrawal, outs)
+	if a != OutputsKey("hot", types.TxPlanKindWithdrawal, outs) {
+		t.Fatalf("OutputsKey not deterministic")
+	}
+	if a == OutputsKey("cold", types.TxPlanKindWithdrawal, outs) || a == OutputsKey("hot", types.TxPlanKindSweep, outs) {
+		t.Fatalf("OutputsKey ignores wallet or kind")
+	}
+	if h := RequestHash("hot", types.TxPlanKindWithdrawal, outs); h == RequestHash("hot", types.TxPlanKindWithdrawal, []types.TxOutput{{ToAddress: "j1a", AmountZat: "101"}}) || a != "outputs:"+h {
+		t.Fatalf("RequestHash ignores amounts or differs from OutputsKey")
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "idem")
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, ok, err := s.Get("k"); ok || err != nil {
+		t.Fatalf("Get empty: ok=%v err=%v", ok, err)
+	}
+	if _, err := s.MarkBroadcast("k", "", time.Now()); err == nil {
+		t.Fatalf("expected error marking a missing entry")
+	}
+
+	created := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	if err := s.Put(Entry{Key: "k", CreatedAt: created, ExpiryHeight: 140, Plan: json.RawMessage(`{"fee_zat":"10000"}`)}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	e, err := s.MarkBroadcast("k", "ABCD", created.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("MarkBroadcast: %v", err)
+	}
+	if e.TxID != "abcd" || e.BroadcastAt == nil {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	got, ok, err := s.Get("k")
+	if err != nil || !ok {
+		t.Fatalf("Get: ok=%v err=%v", ok, err)
+	}
+	if !got.CreatedAt.Equal(created) || got.ExpiryHeight != 140 || string(got.Plan) != `{"fee_zat":"10000"}` || got.BroadcastAt == nil {
+		t.Fatalf("unexpected entry: %+v", got)
+	}
+}
+
+func TestStoreLock(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	unlock, err := s.Lock("k")
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	var ce types.CodedError
+	if _, err := s.Lock("k"); !errors.As(err, &ce) || ce.Code != ErrCodeInProgress {
+		t.Fatalf("expected %s, got %v", ErrCodeInProgress, err)
+	}
+	unlock()
+	unlock, err = s.Lock("k")
+	if err != nil {
+		t.Fatalf("Lock after unlock: %v", err)
+	}
+	defer unlock()
+
+	// A lock older than lockStale is taken over.
+	stale := time.Now().Add(-2 * lockStale)
+	if err := os.Chtimes(s.path("k", ".lock"), stale, stale); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if _, err := s.Lock("k"); err != nil {
+		t.Fatalf("Lock stale: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package jcs
+
+import "testing"
+
+func TestCanonicalize(t *testing.T) {
+	for in, want := range map[string]string{
+		// RFC 8785 section 3.2.2.
+		`{
+		  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		  "string": "€$\u000F\u000aA'B\"\\\\\"\/",
+		  "literals": [null, true, false]
+		}`: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		// RFC 8785 section 3.2.3: sorting by UTF-16 code units.
+		`{"€":"Euro Sign","\r":"Carriage Return","דּ":"Hebrew Letter Dalet With Dagesh","1":"One","😀":"Emoji: Grinning Face","\u0080":"Control","ö":"Latin Small Letter O With Diaeresis"}`: `{"\r":"Carriage Return","1":"One","` + "\u0080" + `":"Control","ö":"Latin Small Letter O With Diaeresis","€":"Euro Sign","😀":"Emoji: Grinning Face","דּ":"Hebrew Letter Dalet With Dagesh"}`,
+		`[-0, 100, 1e21, 123e-9, -5.5]`: `[0,100,1e+21,1.23e-7,-5.5]`,
+	} {
+		got, err := Canonicalize([]byte(in))
+		if err != nil {
+			t.Fatalf("Canonicalize(%s): %v", in, err)
+		}
+		if string(got) != want {
+			t.Fatalf("Canonicalize(%s)\n got %s\nwant %s", in, got, want)
+		}
+	}
+
+	if _, err := Canonicalize([]byte(`{"a":1,"a":2}`)); err == nil {
+		t.Fatalf("expected duplicate key error")
+	}
+	if _, err := Canonicalize([]byte(`[1e400]`)); err == nil {
+		t.Fatalf("expected out of range error")
+	}
+}
@@ -0,0 +1,117 @@
+// Package keys derives and checks Orchard addresses of a unified full viewing
+// key.
+package keys
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/Abdullah1738/juno-txbuild/internal/address"
+	"github.com/Abdullah1738/juno-txbuild/internal/ffi"
+)
+
+// Scopes returned by AddressScope.
+const (
+	ScopeExternal = "external"
+	ScopeInternal = "internal"
+)
+
+// DiversifierIndex is a ZIP-32 diversifier index (11 bytes, little-endian).
+type DiversifierIndex [11]byte
+
+// RandomDiversifierIndex returns a uniformly random diversifier index.
+func RandomDiversifierIndex() (DiversifierIndex, error) {
+	var j DiversifierIndex
+	if _, err := rand.Read(j[:]); err != nil {
+		return DiversifierIndex{}, err
+	}
+	return j, nil
+}
+
+// ChangeAddress derives the internal-scope Orchard address at index j of ufvk
+// as a unified address for chain.
+func ChangeAddress(ufvk, chain string, j DiversifierIndex) (string, error) {
+	fvk, err := address.DecodeUFVK(ufvk, chain)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		AddressHex string `json:"address_hex"`
+	}
+	if err := call(ffi.OrchardDeriveAddressJSON, map[string]string{
+		"fvk_hex":               hex.EncodeToString(fvk),
+		"scope":                 ScopeInternal,
+		"diversifier_index_hex": hex.EncodeToString(j[:]),
+	}, &resp); err != nil {
+		return "", err
+	}
+	raw, err := hex.DecodeString(resp.AddressHex)
+	if err != nil {
+		return "", errors.New("keys: invalid response")
+	}
+	return address.OrchardAddress(raw, chain)
+}
+
+// AddressScope reports whether the Orchard receiver of addr was derived from
+// ufvk, and in which scope. It returns "" if it was not.
+func AddressScope(ufvk, addr, chain string) (string, error) {
+	fvk, err := address.DecodeUFVK(ufvk, chain)
+	if err != nil {
+		return "", err
+	}
+	ua, err := address.DecodeUnified(addr)
+	if err != nil {
+		return "", err
+	}
+	receiver, ok := ua.Orchard()
+	if !ok {
+		return "", errors.New("address has no Orchard receiver")
+	}
+
+	var resp struct {
+		Scope string `json:"scope"`
+	}
+	if err := call(ffi.OrchardAddressScopeJSON, map[string]string{
+		"fvk_hex":     hex.EncodeToString(fvk),
+		"address_hex": hex.EncodeToString(receiver),
+	}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Scope, nil
+}
+
+func call(fn func(string) (string, error), req any, out any) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return errors.New("keys: marshal request")
+	}
+	raw, err := fn(string(b))
+	if err != nil {
+		return err
+	}
+
+	var status struct {
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(raw), &status); err != nil {
+		return errors.New("keys: invalid response")
+	}
+	switch status.Status {
+	case "ok":
+		if err := json.Unmarshal([]byte(raw), out); err != nil {
+			return errors.New("keys: invalid response")
+		}
+		return nil
+	case "err":
+		if status.Error == "" {
+			return errors.New("keys: failed")
+		}
+		return errors.New("keys: " + status.Error)
+	default:
+		return errors.New("keys: invalid response")
+	}
+}
@@ -0,0 +1,136 @@
+package logic
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// MaxWeightRatio is the ZIP-317 cap on a transaction's fee weight ratio:
+// paying more than 4x the conventional fee buys no extra block template
+// priority.
+const MaxWeightRatio = 4
+
+// FeeSample is a transaction's fee and ZIP-317 logical action count.
+type FeeSample struct {
+	FeeZat  uint64
+	Actions int
+}
+
+// WeightRatio returns fee / conventional fee, capped at MaxWeightRatio.
+func (s FeeSample) WeightRatio() float64 {
+	conventional := MarginalFeeZat * uint64(max(s.Actions, 2))
+	r := float64(s.FeeZat) / float64(conventional)
+	return math.Min(r, MaxWeightRatio)
+}
+
+// FeeRatioStats summarizes the weight ratios of a set of transactions.
+type FeeRatioStats struct {
+	Txs     int     `json:"txs"`
+	Actions int     `json:"actions"`
+	Min     float64 `json:"min_ratio"`
+	Median  float64 `json:"median_ratio"`
+	P90     float64 `json:"p90_ratio"`
+	Max     float64 `json:"max_ratio"`
+}
+
+func feeRatioStats(samples []FeeSample) FeeRatioStats {
+	st := FeeRatioStats{Txs: len(samples)}
+	if len(samples) == 0 {
+		return st
+	}
+	ratios := make([]float64, 0, len(samples))
+	for _, s := range samples {
+		st.Actions += s.Actions
+		ratios = append(ratios, s.WeightRatio())
+	}
+	sort.Float64s(ratios)
+	st.Min = ratios[0]
+	st.Median = quantile(ratios, 0.5)
+	st.P90 = quantile(ratios, 0.9)
+	st.Max = ratios[len(ratios)-1]
+	return st
+}
+
+// quantile returns the nearest-rank q-quantile of sorted values.
+func quantile(sorted []float64, q float64) float64 {
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	idx = min(max(idx, 0), len(sorted)-1)
+	return sorted[idx]
+}
+
+// MinBlockCapacityActions is the smallest per-block action capacity assumed by
+// EstimateFeeMultiplier, so that a run of quiet blocks does not make a small
+// mempool look congested.
+const MinBlockCapacityActions = 50
+
+// FeeEstimate is the result of EstimateFeeMultiplier.
+type FeeEstimate struct {
+	Mined   FeeRatioStats `json:"mined"`
+	Mempool FeeRatioStats `json:"mempool"`
+	// Assumed logical actions mined per block (the busiest recent block, at
+	// least MinBlockCapacityActions).
+	BlockCapacityActions int `json:"block_capacity_actions"`
+	// Mempool actions paying at least the recommended multiplier.
+	ActionsAhead int `json:"actions_ahead"`
+	// Recommended --fee-multiplier.
+	Multiplier uint64 `json:"recommended_multiplier"`
+	// False when even MaxWeightRatio leaves more than targetBlocks worth of
+	// mempool actions ahead.
+	Likely bool `json:"inclusion_likely"`
+}
+
+// EstimateFeeMultiplier recommends a fee multiplier that makes inclusion within
+// targetBlocks blocks likely.
+//
+// blocks holds the non-coinbase transactions of recently mined blocks and
+// mempool the pending transactions, sampled from mempoolTxs transactions
+// (0 = len(mempool)); the actions ahead in a sample are scaled up to the whole
+// mempool. The multiplier starts at the (rounded up) median ratio recently
+// mined and is raised until the mempool actions paying at least as much fit
+// into targetBlocks blocks.
+func EstimateFeeMultiplier(blocks [][]FeeSample, mempool []FeeSample, mempoolTxs int, targetBlocks int) (FeeEstimate, error) {
+	if targetBlocks <= 0 {
+		return FeeEstimate{}, errors.New("target blocks must be > 0")
+	}
+
+	var mined []FeeSample
+	capacity := MinBlockCapacityActions
+	for _, blk := range blocks {
+		actions := 0
+		for _, s := range blk {
+			actions += max(s.Actions, 2)
+		}
+		capacity = max(capacity, actions)
+		mined = append(mined, blk...)
+	}
+
+	est := FeeEstimate{
+		Mined:                feeRatioStats(mined),
+		Mempool:              feeRatioStats(mempool),
+		BlockCapacityActions: capacity,
+	}
+
+	start := uint64(1)
+	if est.Mined.Txs > 0 {
+		start = uint64(math.Max(1, math.Ceil(est.Mined.Median-1e-9)))
+	}
+	for m := start; m <= MaxWeightRatio; m++ {
+		ahead := 0
+		for _, s := range mempool {
+			if s.WeightRatio() >= float64(m) {
+				ahead += max(s.Actions, 2)
+			}
+		}
+		if mempoolTxs > len(mempool) && len(mempool) > 0 {
+			ahead = int(math.Ceil(float64(ahead) * float64(mempoolTxs) / float64(len(mempool))))
+		}
+		est.Multiplier = m
+		est.ActionsAhead = ahead
+		if ahead < capacity*targetBlocks {
+			est.Likely = true
+			break
+		}
+	}
+	return est, nil
+}
@@ -0,0 +1,88 @@
+package logic
+
+import "testing"
+
+func TestFeeSampleWeightRatio(t *testing.T) {
+	if got := (FeeSample{FeeZat: 10_000, Actions: 1}).WeightRatio(); got != 1 {
+		t.Fatalf("ratio=%v want 1", got)
+	}
+	if got := (FeeSample{FeeZat: 30_000, Actions: 3}).WeightRatio(); got != 2 {
+		t.Fatalf("ratio=%v want 2", got)
+	}
+	if got := (FeeSample{FeeZat: 1_000_000, Actions: 2}).WeightRatio(); got != MaxWeightRatio {
+		t.Fatalf("ratio=%v want %d", got, MaxWeightRatio)
+	}
+}
+
+func TestEstimateFeeMultiplier_QuietMempool(t *testing.T) {
+	blocks := [][]FeeSample{
+		{{FeeZat: 10_000, Actions: 2}, {FeeZat: 15_000, Actions: 3}},
+		{{FeeZat: 10_000, Actions: 2}},
+	}
+	mempool := []FeeSample{{FeeZat: 10_000, Actions: 2}}
+
+	est, err := EstimateFeeMultiplier(blocks, mempool, 0, 3)
+	if err != nil {
+		t.Fatalf("EstimateFeeMultiplier: %v", err)
+	}
+	if est.Multiplier != 1 || !est.Likely {
+		t.Fatalf("unexpected estimate: %+v", est)
+	}
+	if est.Mined.Txs != 3 || est.Mempool.Txs != 1 {
+		t.Fatalf("unexpected stats: %+v", est)
+	}
+}
+
+func TestEstimateFeeMultiplier_OutbidsBacklog(t *testing.T) {
+	blocks := [][]FeeSample{{{FeeZat: 10_000, Actions: 2}}}
+
+	// 60 actions paying 2x exceed one block of the minimum capacity (50), so
+	// only 3x gets ahead of them.
+	var mempool []FeeSample
+	for i := 0; i < 30; i++ {
+		mempool = append(mempool, FeeSample{FeeZat: 20_000, Actions: 2})
+	}
+	for i := 0; i < 30; i++ {
+		mempool = append(mempool, FeeSample{FeeZat: 10_000, Actions: 2})
+	}
+
+	est, err := EstimateFeeMultiplier(blocks, mempool, 0, 1)
+	if err != nil {
+		t.Fatalf("EstimateFeeMultiplier: %v", err)
+	}
+	if est.Multiplier != 3 || !est.Likely || est.ActionsAhead != 0 {
+		t.Fatalf("unexpected estimate: %+v", est)
+	}
+}
+
+func TestEstimateFeeMultiplier_Congested(t *testing.T) {
+	var mempool []FeeSample
+	for i := 0; i < 100; i++ {
+		mempool = append(mempool, FeeSample{FeeZat: 100_000, Actions: 2})
+	}
+	est, err := EstimateFeeMultiplier(nil, mempool, 0, 1)
+	if err != nil {
+		t.Fatalf("EstimateFeeMultiplier: %v", err)
+	}
+	if est.Multiplier != MaxWeightRatio || est.Likely {
+		t.Fatalf("unexpected estimate: %+v", est)
+	}
+}
+
+func TestEstimateFeeMultiplier_ScalesSampledMempool(t *testing.T) {
+	blocks := [][]FeeSample{{{FeeZat: 10_000, Actions: 2}}}
+
+	// 10 sampled actions paying 2x stand for 60 in a mempool six times the
+	// sample, more than one block, so only 3x gets ahead of them.
+	var mempool []FeeSample
+	for i := 0; i < 5; i++ {
+		mempool = append(mempool, FeeSample{FeeZat: 20_000, Actions: 2})
+	}
+	est, err := EstimateFeeMultiplier(blocks, mempool, 30, 1)
+	if err != nil {
+		t.Fatalf("EstimateFeeMultiplier: %v", err)
+	}
+	if est.Multiplier != 3 || !est.Likely {
+		t.Fatalf("unexpected estimate: %+v", est)
+	}
+}
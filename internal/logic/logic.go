@@ -2,6 +2,7 @@ package logic
 
 import (
 	"errors"
+	"math/bits"
 	"sort"
 	"strconv"
 	"strings"
@@ -29,6 +30,38 @@ func FilterNotesMinValue(notes []UnspentNote, minNoteZat uint64) []UnspentNote {
 type FeePolicy struct {
 	Multiplier uint64
 	AddZat     uint64
+	// Marginal fee per logical action (0 = MarginalFeeZat).
+	PerActionZat uint64
+	// Caps the fee, but never below the ZIP-317 conventional fee (0 = no cap).
+	CapZat uint64
+	// Fixed fee overriding all of the above (nil = computed). May be below the
+	// conventional fee; see UnpaidActions.
+	ExactZat *uint64
+}
+
+// Fee returns the fee for an Orchard transaction with the given spend and
+// output counts under this policy.
+func (p FeePolicy) Fee(spendCount, outputCount int) (uint64, error) {
+	if p.ExactZat != nil {
+		return *p.ExactZat, nil
+	}
+	conventional := RequiredFeeSend(spendCount, outputCount)
+	base := conventional
+	if p.PerActionZat > 0 {
+		var ok bool
+		base, ok = mulUint64(uint64(LogicalActions(spendCount, outputCount)), p.PerActionZat)
+		if !ok {
+			return 0, errors.New("overflow")
+		}
+	}
+	fee, err := p.Apply(base)
+	if err != nil {
+		return 0, err
+	}
+	if p.CapZat > 0 && fee > p.CapZat {
+		fee = max(p.CapZat, conventional)
+	}
+	return fee, nil
 }
 
 func (p FeePolicy) Apply(base uint64) (uint64, error) {
@@ -47,9 +80,61 @@ func (p FeePolicy) Apply(base uint64) (uint64, error) {
 	return v, nil
 }
 
+// FeeLimits are hard upper bounds on the final fee of a plan, independent of
+// the fee policy that produced it.
+type FeeLimits struct {
+	// Maximum fee in zatoshis (0 = no limit).
+	MaxZat uint64
+	// Maximum fee as a percentage of the amount paid to outputs (0 = no limit).
+	MaxPercent float64
+}
+
+// FeeLimitError reports a fee above FeeLimits.
+type FeeLimitError struct {
+	FeeZat   uint64
+	LimitZat uint64
+	// "max_fee_zat" or "max_fee_percent".
+	Limit string
+}
+
+func (e *FeeLimitError) Error() string {
+	return "fee " + strconv.FormatUint(e.FeeZat, 10) + " exceeds " + e.Limit + " (" + strconv.FormatUint(e.LimitZat, 10) + ")"
+}
+
+func (l FeeLimits) Validate() error {
+	if !(l.MaxPercent >= 0 && l.MaxPercent <= 100) {
+		return errors.New("max fee percent must be in [0, 100]")
+	}
+	return nil
+}
+
+// Check returns a *FeeLimitError if feeZat exceeds the limits for a
+// transaction paying amountZat to its outputs (change excluded).
+func (l FeeLimits) Check(feeZat, amountZat uint64) error {
+	if l.MaxZat > 0 && feeZat > l.MaxZat {
+		return &FeeLimitError{FeeZat: feeZat, LimitZat: l.MaxZat, Limit: "max_fee_zat"}
+	}
+	if l.MaxPercent > 0 {
+		limit := uint64(float64(amountZat) * l.MaxPercent / 100)
+		if feeZat > limit {
+			return &FeeLimitError{FeeZat: feeZat, LimitZat: limit, Limit: "max_fee_percent"}
+		}
+	}
+	return nil
+}
+
+// MarginalFeeZat is the ZIP-317 conventional fee per logical action.
+const MarginalFeeZat = 5_000
+
 // RequiredFeeSend returns the minimum ZIP-317 conventional fee for an Orchard
 // send with the given spend and output counts.
 func RequiredFeeSend(spendCount, outputCount int) uint64 {
+	return MarginalFeeZat * uint64(LogicalActions(spendCount, outputCount))
+}
+
+// LogicalActions returns the ZIP-317 logical action count of an Orchard
+// transaction, including the grace actions.
+func LogicalActions(spendCount, outputCount int) int {
 	actions := spendCount
 	if outputCount > actions {
 		actions = outputCount
@@ -57,7 +142,50 @@ func RequiredFeeSend(spendCount, outputCount int) uint64 {
 	if actions < 2 {
 		actions = 2
 	}
-	return 5_000 * uint64(actions)
+	return actions
+}
+
+// BlockUnpaidActionLimit is the default ZIP-317 block_unpaid_action_limit.
+// Block templates include at most this many unpaid actions per block, so a
+// transaction with more unpaid actions than this is never mined (and is not
+// accepted into the mempool).
+const BlockUnpaidActionLimit = 50
+
+// UnpaidActions returns the ZIP-317 unpaid actions of a transaction with the
+// given logical action count paying feeZat.
+func UnpaidActions(feeZat uint64, logicalActions int) int {
+	actions := uint64(max(logicalActions, 2))
+	paid := feeZat / MarginalFeeZat
+	if paid >= actions {
+		return 0
+	}
+	return int(actions - paid)
+}
+
+// Inclusion risks reported by InclusionRisk.
+const (
+	// Pays the conventional fee.
+	InclusionRiskLow = "low"
+	// Competes with other low-fee transactions for the per-block unpaid
+	// action budget; may take many blocks or expire.
+	InclusionRiskElevated = "elevated"
+)
+
+// InclusionRisk classifies a transaction by its unpaid actions. Transactions
+// with more than BlockUnpaidActionLimit are never mined and are refused
+// before they are classified.
+func InclusionRisk(unpaidActions int) string {
+	if unpaidActions <= 0 {
+		return InclusionRiskLow
+	}
+	return InclusionRiskElevated
+}
+
+// TxLogicalActions returns the ZIP-317 logical action count of an arbitrary
+// transaction (without grace actions). Transparent inputs and outputs are
+// approximated as one action each, which holds for P2PKH.
+func TxLogicalActions(transparentIn, transparentOut, joinSplits, saplingSpends, saplingOutputs, orchardActions int) int {
+	return max(transparentIn, transparentOut) + 2*joinSplits + max(saplingSpends, saplingOutputs) + orchardActions
 }
 
 // SuppressDustChange converts small change into fee.
@@ -94,6 +222,17 @@ func SelectNotes(notes []UnspentNote, amountZat uint64, outputCount int) ([]Unsp
 }
 
 func SelectNotesWithFeePolicy(notes []UnspentNote, amountZat uint64, outputCount int, feePolicy FeePolicy) ([]UnspentNote, uint64, error) {
+	return selectNotes(notes, amountZat, outputCount, feePolicy, false)
+}
+
+// SelectNotesFeeFromOutputs selects notes for outputs that bear the fee
+// themselves: the selected notes only need to cover amountZat, and the returned
+// fee is later deducted from the outputs (see FeeFromOutputs and SplitFee).
+func SelectNotesFeeFromOutputs(notes []UnspentNote, amountZat uint64, outputCount int, feePolicy FeePolicy) ([]UnspentNote, uint64, error) {
+	return selectNotes(notes, amountZat, outputCount, feePolicy, true)
+}
+
+func selectNotes(notes []UnspentNote, amountZat uint64, outputCount int, feePolicy FeePolicy, feeFromOutputs bool) ([]UnspentNote, uint64, error) {
 	if len(notes) == 0 {
 		return nil, 0, errors.New("insufficient funds")
 	}
@@ -125,11 +264,13 @@ func SelectNotesWithFeePolicy(notes []UnspentNote, amountZat uint64, outputCount
 	sortByAsc(notesAsc)
 
 	neededTotal := func(spendCount, outputs int) (uint64, uint64, error) {
-		feeMin := RequiredFeeSend(spendCount, outputs)
-		fee, err := feePolicy.Apply(feeMin)
+		fee, err := feePolicy.Fee(spendCount, outputs)
 		if err != nil {
 			return 0, 0, err
 		}
+		if feeFromOutputs {
+			return amountZat, fee, nil
+		}
 		need, ok := addUint64(amountZat, fee)
 		if !ok {
 			return 0, 0, errors.New("overflow")
@@ -247,6 +388,129 @@ func SelectNotesWithFeePolicy(notes []UnspentNote, amountZat uint64, outputCount
 	return nil, 0, errors.New("insufficient funds")
 }
 
+// MaxSendable returns the largest amount that can be paid to one more output
+// when all notes are spent, and the fee for that transaction.
+//
+// fixedZat is the sum of the other outputs (outputCount includes the max
+// output). reserveZat is kept back as change; a change output is only added
+// when reserveZat > 0.
+func MaxSendable(notes []UnspentNote, fixedZat, reserveZat uint64, outputCount int, feePolicy FeePolicy) (uint64, uint64, error) {
+	if len(notes) == 0 {
+		return 0, 0, errors.New("insufficient funds")
+	}
+	var totalIn uint64
+	for _, n := range notes {
+		var ok bool
+		totalIn, ok = addUint64(totalIn, n.ValueZat)
+		if !ok {
+			return 0, 0, errors.New("overflow")
+		}
+	}
+
+	outputs := outputCount
+	if reserveZat > 0 {
+		outputs++
+	}
+	fee, err := feePolicy.Fee(len(notes), outputs)
+	if err != nil {
+		return 0, 0, err
+	}
+	need, ok := addUint64(fixedZat, reserveZat)
+	if !ok {
+		return 0, 0, errors.New("overflow")
+	}
+	need, ok = addUint64(need, fee)
+	if !ok {
+		return 0, 0, errors.New("overflow")
+	}
+	if totalIn <= need {
+		return 0, 0, errors.New("insufficient funds")
+	}
+	return totalIn - need, fee, nil
+}
+
+// FeeFromOutputs computes the fee for a selection whose outputs bear the fee.
+//
+// It returns the fee deducted from the outputs (the ZIP-317 fee after the fee
+// policy) and the total fee, which additionally includes any change suppressed
+// by minChangeZat. Whether a change output exists changes the action count and
+// therefore the fee, and suppressing dust change changes whether a change
+// output exists, so the fee is re-solved until it is stable.
+func FeeFromOutputs(totalIn, totalOut uint64, spendCount, outputCount int, feePolicy FeePolicy, minChangeZat uint64) (uint64, uint64, error) {
+	if totalIn < totalOut {
+		return 0, 0, errors.New("invalid totals")
+	}
+	// Outputs bear the fee, so change does not depend on it.
+	change := totalIn - totalOut
+
+	hasChange := change > 0
+	for i := 0; i < 4; i++ {
+		changeOutputs := 0
+		if hasChange {
+			changeOutputs = 1
+		}
+		fee, err := feePolicy.Fee(spendCount, outputCount+changeOutputs)
+		if err != nil {
+			return 0, 0, err
+		}
+		if fee >= totalOut {
+			return 0, 0, errors.New("fee exceeds outputs")
+		}
+		total, suppressed, err := SuppressDustChange(totalIn, totalOut-fee, fee, minChangeZat)
+		if err != nil {
+			return 0, 0, err
+		}
+		nextHasChange := change > 0 && !suppressed
+		if nextHasChange == hasChange {
+			return fee, total, nil
+		}
+		hasChange = nextHasChange
+	}
+	return 0, 0, errors.New("fee did not converge")
+}
+
+// SplitFee splits feeZat across amounts proportionally to their values.
+//
+// Rounding remainders are assigned one zatoshi at a time in order, so the
+// shares always sum to feeZat. Every amount must stay > 0 after its share is
+// deducted.
+func SplitFee(amounts []uint64, feeZat uint64) ([]uint64, error) {
+	if len(amounts) == 0 {
+		return nil, errors.New("no outputs")
+	}
+	var total uint64
+	for _, a := range amounts {
+		var ok bool
+		total, ok = addUint64(total, a)
+		if !ok {
+			return nil, errors.New("overflow")
+		}
+	}
+	if feeZat >= total {
+		return nil, errors.New("fee exceeds outputs")
+	}
+
+	shares := make([]uint64, len(amounts))
+	var assigned uint64
+	for i, a := range amounts {
+		hi, lo := bits.Mul64(feeZat, a)
+		shares[i], _ = bits.Div64(hi, lo, total)
+		assigned += shares[i]
+	}
+	for i := 0; assigned < feeZat; i = (i + 1) % len(amounts) {
+		if shares[i] < amounts[i] {
+			shares[i]++
+			assigned++
+		}
+	}
+	for i, a := range amounts {
+		if shares[i] >= a {
+			return nil, errors.New("fee exceeds output amount")
+		}
+	}
+	return shares, nil
+}
+
 func ParseUint64Decimal(s string) (uint64, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
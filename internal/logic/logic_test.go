@@ -1,6 +1,9 @@
 package logic
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestParseZECToZat(t *testing.T) {
 	got, err := ParseZECToZat("0.24985000")
@@ -183,3 +186,182 @@ func TestExpiryHeightFromTip_Overflow(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestSelectNotesFeeFromOutputs_DoesNotAddFee(t *testing.T) {
+	notes := []UnspentNote{
+		{TxID: "a", ActionIndex: 0, ValueZat: 60_000},
+		{TxID: "b", ActionIndex: 0, ValueZat: 5_000},
+	}
+
+	// The fee is deducted from the outputs, so one 60000 note covers a 60000 payout.
+	selected, fee, err := SelectNotesFeeFromOutputs(notes, 60_000, 1, FeePolicy{})
+	if err != nil {
+		t.Fatalf("SelectNotesFeeFromOutputs: %v", err)
+	}
+	if fee != 10_000 {
+		t.Fatalf("fee=%d want %d", fee, 10_000)
+	}
+	if len(selected) != 1 || selected[0].TxID != "a" {
+		t.Fatalf("selected=%+v", selected)
+	}
+}
+
+func TestFeeFromOutputs_NoChange(t *testing.T) {
+	deduct, fee, err := FeeFromOutputs(60_000, 60_000, 1, 1, FeePolicy{}, 0)
+	if err != nil {
+		t.Fatalf("FeeFromOutputs: %v", err)
+	}
+	if deduct != 10_000 || fee != 10_000 {
+		t.Fatalf("deduct=%d fee=%d want %d", deduct, fee, 10_000)
+	}
+}
+
+func TestFeeFromOutputs_SuppressedChangeDropsChangeAction(t *testing.T) {
+	// 3 outputs + change would be 4 actions (20000); suppressing the 100 zat
+	// change leaves 3 actions (15000) deducted from the outputs.
+	deduct, fee, err := FeeFromOutputs(90_100, 90_000, 1, 3, FeePolicy{}, 1_000)
+	if err != nil {
+		t.Fatalf("FeeFromOutputs: %v", err)
+	}
+	if deduct != 15_000 {
+		t.Fatalf("deduct=%d want %d", deduct, 15_000)
+	}
+	if fee != 15_100 {
+		t.Fatalf("fee=%d want %d", fee, 15_100)
+	}
+}
+
+func TestFeeFromOutputs_FeeExceedsOutputs(t *testing.T) {
+	if _, _, err := FeeFromOutputs(20_000, 10_000, 1, 1, FeePolicy{}, 0); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestSplitFee_Proportional(t *testing.T) {
+	shares, err := SplitFee([]uint64{10_000, 30_000}, 10_001)
+	if err != nil {
+		t.Fatalf("SplitFee: %v", err)
+	}
+	if shares[0] != 2_501 || shares[1] != 7_500 {
+		t.Fatalf("shares=%v", shares)
+	}
+}
+
+func TestSplitFee_RejectsShareConsumingOutput(t *testing.T) {
+	if _, err := SplitFee([]uint64{1, 100_000}, 50_000); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestMaxSendable(t *testing.T) {
+	notes := []UnspentNote{
+		{TxID: "a", ActionIndex: 0, ValueZat: 60_000},
+		{TxID: "b", ActionIndex: 0, ValueZat: 40_000},
+		{TxID: "c", ActionIndex: 0, ValueZat: 20_000},
+	}
+
+	// 3 spends, 1 output, no change: 3 actions.
+	amount, fee, err := MaxSendable(notes, 0, 0, 1, FeePolicy{})
+	if err != nil {
+		t.Fatalf("MaxSendable: %v", err)
+	}
+	if fee != 15_000 || amount != 105_000 {
+		t.Fatalf("amount=%d fee=%d", amount, fee)
+	}
+
+	// Reserve adds a change output, still 3 actions.
+	amount, fee, err = MaxSendable(notes, 0, 25_000, 1, FeePolicy{})
+	if err != nil {
+		t.Fatalf("MaxSendable: %v", err)
+	}
+	if fee != 15_000 || amount != 80_000 {
+		t.Fatalf("amount=%d fee=%d", amount, fee)
+	}
+
+	if _, _, err := MaxSendable(notes, 0, 105_000, 1, FeePolicy{}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFeePolicyFee_PerActionAndCap(t *testing.T) {
+	fee, err := FeePolicy{PerActionZat: 10_000}.Fee(3, 2)
+	if err != nil {
+		t.Fatalf("Fee: %v", err)
+	}
+	if fee != 30_000 {
+		t.Fatalf("fee=%d want %d", fee, 30_000)
+	}
+
+	fee, err = FeePolicy{Multiplier: 10, CapZat: 40_000}.Fee(1, 1)
+	if err != nil {
+		t.Fatalf("Fee: %v", err)
+	}
+	if fee != 40_000 {
+		t.Fatalf("fee=%d want %d", fee, 40_000)
+	}
+
+	// The cap never takes the fee below the conventional fee.
+	fee, err = FeePolicy{Multiplier: 10, CapZat: 1_000}.Fee(4, 1)
+	if err != nil {
+		t.Fatalf("Fee: %v", err)
+	}
+	if fee != 20_000 {
+		t.Fatalf("fee=%d want %d", fee, 20_000)
+	}
+}
+
+func TestFeeLimitsCheck(t *testing.T) {
+	l := FeeLimits{MaxZat: 50_000, MaxPercent: 1}
+	if err := l.Check(10_000, 1_000_000); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	var fle *FeeLimitError
+	if err := l.Check(60_000, 100_000_000); !errors.As(err, &fle) || fle.Limit != "max_fee_zat" {
+		t.Fatalf("expected max_fee_zat error, got %v", err)
+	}
+	if err := l.Check(20_000, 1_000_000); !errors.As(err, &fle) || fle.Limit != "max_fee_percent" || fle.LimitZat != 10_000 {
+		t.Fatalf("expected max_fee_percent error, got %v", err)
+	}
+
+	if err := (FeeLimits{MaxPercent: 101}).Validate(); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFeePolicyFee_Exact(t *testing.T) {
+	exact := uint64(1_000)
+	fee, err := FeePolicy{Multiplier: 4, ExactZat: &exact}.Fee(3, 2)
+	if err != nil {
+		t.Fatalf("Fee: %v", err)
+	}
+	if fee != 1_000 {
+		t.Fatalf("fee=%d want %d", fee, 1_000)
+	}
+}
+
+func TestUnpaidActions(t *testing.T) {
+	cases := []struct {
+		fee     uint64
+		actions int
+		unpaid  int
+		risk    string
+	}{
+		{fee: 10_000, actions: 1, unpaid: 0, risk: InclusionRiskLow},
+		{fee: 15_000, actions: 3, unpaid: 0, risk: InclusionRiskLow},
+		{fee: 14_999, actions: 3, unpaid: 1, risk: InclusionRiskElevated},
+		{fee: 0, actions: 2, unpaid: 2, risk: InclusionRiskElevated},
+		{fee: 0, actions: 50, unpaid: 50, risk: InclusionRiskElevated},
+		{fee: 0, actions: 51, unpaid: 51, risk: InclusionRiskElevated},
+		{fee: 5_000, actions: 51, unpaid: 50, risk: InclusionRiskElevated},
+	}
+	for _, tc := range cases {
+		got := UnpaidActions(tc.fee, tc.actions)
+		if got != tc.unpaid {
+			t.Fatalf("UnpaidActions(%d, %d)=%d want %d", tc.fee, tc.actions, got, tc.unpaid)
+		}
+		if r := InclusionRisk(got); r != tc.risk {
+			t.Fatalf("InclusionRisk(%d)=%q want %q", got, r, tc.risk)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package memo encodes and validates ZIP-302 memo fields.
+package memo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Size is the length of an Orchard memo field. Shorter memos are zero-padded
+// to Size by the signer.
+const Size = 512
+
+// NoMemoHex is the ZIP-302 "no memo" marker: 0xF6 followed by zero padding.
+const NoMemoHex = "f6"
+
+// RequestIDPlaceholder is replaced by the output's request_id in templates.
+const RequestIDPlaceholder = "{request_id}"
+
+// Text encodes s as a ZIP-302 text memo (UTF-8, without padding).
+func Text(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("memo text is empty")
+	}
+	if !utf8.ValidString(s) {
+		return nil, errors.New("memo text is not valid UTF-8")
+	}
+	if len(s) > Size {
+		return nil, fmt.Errorf("memo text exceeds %d bytes (got %d)", Size, len(s))
+	}
+	if strings.HasSuffix(s, "\x00") {
+		// Trailing zeros are indistinguishable from padding.
+		return nil, errors.New("memo text ends with NUL")
+	}
+	return []byte(s), nil
+}
+
+// TextHex is Text, hex-encoded for memo_hex.
+func TextHex(s string) (string, error) {
+	b, err := Text(s)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ParseHex decodes memo_hex and checks it against ZIP-302. It returns the
+// normalized (lowercase) hex.
+func ParseHex(s string) (string, error) {
+	if len(s)%2 != 0 {
+		return "", errors.New("memo hex has odd length")
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return "", errors.New("memo hex is not valid hex")
+	}
+	if err := Validate(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(s), nil
+}
+
+// Validate checks an (unpadded) memo against the ZIP-302 first-byte rules.
+func Validate(b []byte) error {
+	if len(b) > Size {
+		return fmt.Errorf("memo exceeds %d bytes (got %d)", Size, len(b))
+	}
+	if len(b) == 0 {
+		return errors.New("memo is empty")
+	}
+	switch first := b[0]; {
+	case first <= 0xF4:
+		if !utf8.Valid(bytes.TrimRight(b, "\x00")) {
+			return errors.New("text memo is not valid UTF-8")
+		}
+	case first == 0xF5, first == 0xFF:
+		// Unspecified / arbitrary data.
+	case first == 0xF6:
+		if len(bytes.TrimRight(b[1:], "\x00")) != 0 {
+			return errors.New("no-memo marker 0xf6 must be followed by zeros")
+		}
+	default:
+		return fmt.Errorf("memo type 0x%02x is reserved", first)
+	}
+	return nil
+}
+
+// Expand substitutes RequestIDPlaceholder in tmpl with requestID.
+func Expand(tmpl, requestID string) (string, error) {
+	if !strings.Contains(tmpl, RequestIDPlaceholder) {
+		return tmpl, nil
+	}
+	if strings.TrimSpace(requestID) == "" {
+		return "", errors.New("memo template uses " + RequestIDPlaceholder + " but request_id is empty")
+	}
+	return strings.ReplaceAll(tmpl, RequestIDPlaceholder, requestID), nil
+}
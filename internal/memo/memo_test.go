@@ -0,0 +1,74 @@
+package memo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	got, err := TextHex("héllo")
+	if err != nil {
+		t.Fatalf("TextHex: %v", err)
+	}
+	if got != "68c3a96c6c6f" {
+		t.Fatalf("TextHex=%s", got)
+	}
+	if _, err := Text(strings.Repeat("a", Size)); err != nil {
+		t.Fatalf("Text(512): %v", err)
+	}
+
+	for name, s := range map[string]string{
+		"empty":        "",
+		"too long":     strings.Repeat("é", Size/2+1),
+		"invalid":      "\xff\xfe",
+		"trailing nul": "a\x00",
+	} {
+		if _, err := Text(s); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	for in, want := range map[string]string{
+		"48656C6C6F":                        "48656c6c6f",
+		NoMemoHex:                           NoMemoHex,
+		"f6" + strings.Repeat("00", Size-1): "f6" + strings.Repeat("00", Size-1),
+		"f5deadbeef":                        "f5deadbeef",
+		"ff00":                              "ff00",
+	} {
+		got, err := ParseHex(in)
+		if err != nil {
+			t.Fatalf("ParseHex(%q): %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("ParseHex(%q)=%q want %q", in, got, want)
+		}
+	}
+
+	for name, in := range map[string]string{
+		"odd":          "abc",
+		"not hex":      "zz",
+		"too long":     strings.Repeat("41", Size+1),
+		"bad utf8":     "c328",
+		"no-memo data": "f601",
+		"reserved":     "f7",
+	} {
+		if _, err := ParseHex(in); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	got, err := Expand("payout {request_id}", "w-42")
+	if err != nil || got != "payout w-42" {
+		t.Fatalf("Expand=%q, %v", got, err)
+	}
+	if got, err := Expand("static", ""); err != nil || got != "static" {
+		t.Fatalf("Expand=%q, %v", got, err)
+	}
+	if _, err := Expand("payout {request_id}", " "); err == nil {
+		t.Fatalf("expected error")
+	}
+}
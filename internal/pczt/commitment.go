@@ -0,0 +1,92 @@
+package pczt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/ffi"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// ErrCodeTxCommitmentMismatch reports a plan whose unsigned transaction does
+// not rebuild to its tx_commitment.
+const ErrCodeTxCommitmentMismatch types.ErrorCode = "tx_commitment_mismatch"
+
+// Skeleton is the unsigned v5 transaction of a plan: its header and Orchard
+// bundle without proofs and signatures (see juno_txbuild_tx_skeleton_json),
+// and the commitment to it.
+type Skeleton struct {
+	Transaction json.RawMessage      `json:"skeleton"`
+	Commitment  txbuild.TxCommitment `json:"commitment"`
+}
+
+// Commit returns plan committed to its unsigned transaction, built with a
+// fresh seed, with a new plan ID.
+func Commit(plan txbuild.TxPlan, ufvk string) (txbuild.TxPlan, error) {
+	var seed [32]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	plan.TxCommitment = nil
+	s, err := buildSkeleton(plan, ufvk, hex.EncodeToString(seed[:]))
+	if err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	plan.TxCommitment = &s.Commitment
+	plan.PlanID = ""
+	return plan.WithID()
+}
+
+// VerifyCommitment rebuilds the unsigned transaction of a committed plan and
+// checks it against the plan's tx_commitment.
+func VerifyCommitment(plan txbuild.TxPlan, ufvk string) (Skeleton, error) {
+	want := plan.TxCommitment
+	if want == nil {
+		return Skeleton{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "plan has no tx_commitment"}
+	}
+	plan.TxCommitment = nil
+	s, err := buildSkeleton(plan, ufvk, want.Seed)
+	if err != nil {
+		return Skeleton{}, err
+	}
+	if s.Commitment != *want {
+		return s, types.CodedError{Code: ErrCodeTxCommitmentMismatch, Message: "transaction " + s.Commitment.TxID + " does not match tx_commitment " + want.TxID}
+	}
+	return s, nil
+}
+
+func buildSkeleton(plan txbuild.TxPlan, ufvk, seedHex string) (Skeleton, error) {
+	req, err := newRequest(plan, ufvk)
+	if err != nil {
+		return Skeleton{}, err
+	}
+	req.SeedHex = seedHex
+	req.PlanJSON = ""
+
+	var resp struct {
+		Skeleton      json.RawMessage `json:"skeleton"`
+		HeaderDigest  string          `json:"header_digest"`
+		OrchardDigest string          `json:"orchard_digest"`
+		Sighash       string          `json:"sighash"`
+		TxID          string          `json:"txid"`
+	}
+	if err := call(ffi.TxSkeletonJSON, req, &resp); err != nil {
+		return Skeleton{}, err
+	}
+	if len(resp.Skeleton) == 0 || resp.Sighash == "" {
+		return Skeleton{}, errors.New("pczt: invalid response")
+	}
+	return Skeleton{
+		Transaction: resp.Skeleton,
+		Commitment: txbuild.TxCommitment{
+			Seed:          seedHex,
+			HeaderDigest:  resp.HeaderDigest,
+			OrchardDigest: resp.OrchardDigest,
+			Sighash:       resp.Sighash,
+			TxID:          resp.TxID,
+		},
+	}, nil
+}
@@ -0,0 +1,372 @@
+// Package pczt converts TxPlans to and from Partially Created Zcash
+// Transactions, built and parsed by the Rust crate, for signers that speak
+// PCZT, and commits plans to their unsigned transaction.
+package pczt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/address"
+	"github.com/Abdullah1738/juno-txbuild/internal/ffi"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// ErrCodeInvalidPCZT reports a PCZT that cannot be read, was not built from
+// a TxPlan, or does not match the plan it carries.
+const ErrCodeInvalidPCZT types.ErrorCode = "invalid_pczt"
+
+// magic starts every serialized PCZT.
+var magic = []byte("PCZT")
+
+// IsPCZT reports whether data looks like a serialized PCZT.
+func IsPCZT(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+type spendIn struct {
+	Position         uint32   `json:"position"`
+	Path             []string `json:"path"`
+	CMXHex           string   `json:"cmx_hex"`
+	NullifierHex     string   `json:"nullifier_hex"`
+	EphemeralKeyHex  string   `json:"ephemeral_key_hex"`
+	EncCiphertextHex string   `json:"enc_ciphertext_hex"`
+}
+
+type outputIn struct {
+	AddressHex  string `json:"address_hex"`
+	UserAddress string `json:"user_address"`
+	ValueZat    uint64 `json:"value_zat"`
+	MemoHex     string `json:"memo_hex,omitempty"`
+}
+
+type fromPlanRequest struct {
+	FVKHex            string     `json:"fvk_hex"`
+	Network           string     `json:"network"`
+	BranchID          uint32     `json:"branch_id"`
+	ExpiryHeight      uint32     `json:"expiry_height"`
+	AnchorHex         string     `json:"anchor_hex"`
+	OVKPolicy         string     `json:"ovk_policy,omitempty"`
+	FeeZat            uint64     `json:"fee_zat"`
+	Spends            []spendIn  `json:"spends"`
+	Outputs           []outputIn `json:"outputs"`
+	ChangeAddressHex  string     `json:"change_address_hex"`
+	ChangeUserAddress string     `json:"change_user_address"`
+	SeedHex           string     `json:"seed_hex,omitempty"`
+	PlanJSON          string     `json:"plan_json,omitempty"`
+}
+
+// description is what the Rust crate reads back from a PCZT.
+type description struct {
+	BranchID     uint32 `json:"branch_id"`
+	ExpiryHeight uint32 `json:"expiry_height"`
+	Anchor       string `json:"anchor"`
+	PlanJSON     string `json:"plan_json"`
+	Spends       []struct {
+		Position uint32 `json:"position"`
+		Rho      string `json:"rho"`
+		CMX      string `json:"cmx"`
+		ValueZat uint64 `json:"value_zat"`
+	} `json:"spends"`
+	Outputs []struct {
+		UserAddress string `json:"user_address"`
+		// Raw Orchard receiver the note pays.
+		Recipient string `json:"recipient"`
+		ValueZat  uint64 `json:"value_zat"`
+		// Note commitment recomputed from the note.
+		CMX     string `json:"cmx"`
+		MemoHex string `json:"memo_hex"`
+	} `json:"outputs"`
+}
+
+// FromPlan builds a PCZT of plan. The spent notes are decrypted with the
+// Orchard key of ufvk, which must be the wallet's; the PCZT carries the plan
+// so that Import can recover it. A plan with a TxCommitment gives the
+// committed transaction.
+func FromPlan(plan txbuild.TxPlan, ufvk string) ([]byte, error) {
+	req, err := newRequest(plan, ufvk)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		PCZTHex string `json:"pczt_hex"`
+	}
+	if err := call(ffi.PCZTFromPlanJSON, req, &resp); err != nil {
+		return nil, err
+	}
+	b, err := hex.DecodeString(resp.PCZTHex)
+	if err != nil || !IsPCZT(b) {
+		return nil, errors.New("pczt: invalid response")
+	}
+	return b, nil
+}
+
+func newRequest(plan txbuild.TxPlan, ufvk string) (fromPlanRequest, error) {
+	fvk, err := address.DecodeUFVK(ufvk, plan.Chain)
+	if err != nil {
+		return fromPlanRequest{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "ufvk: " + err.Error()}
+	}
+	fee, err := parseZat("fee_zat", plan.FeeZat)
+	if err != nil {
+		return fromPlanRequest{}, err
+	}
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return fromPlanRequest{}, errors.New("pczt: marshal txplan")
+	}
+	req := fromPlanRequest{
+		FVKHex:       hex.EncodeToString(fvk),
+		Network:      strings.ToLower(strings.TrimSpace(plan.Chain)),
+		BranchID:     plan.BranchID,
+		ExpiryHeight: plan.ExpiryHeight,
+		AnchorHex:    plan.Anchor,
+		OVKPolicy:    plan.OVKPolicy,
+		FeeZat:       fee,
+		PlanJSON:     string(planJSON),
+	}
+	if plan.TxCommitment != nil {
+		req.SeedHex = plan.TxCommitment.Seed
+	}
+	for _, n := range plan.Notes {
+		req.Spends = append(req.Spends, spendIn{
+			Position:         n.Position,
+			Path:             n.Path,
+			CMXHex:           n.CMX,
+			NullifierHex:     n.ActionNullifier,
+			EphemeralKeyHex:  n.EphemeralKey,
+			EncCiphertextHex: n.EncCiphertext,
+		})
+	}
+	for i, o := range plan.Outputs {
+		receiver, err := orchardReceiver(o.ToAddress)
+		if err != nil {
+			return fromPlanRequest{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].to_address: %v", i, err)}
+		}
+		amount, err := parseZat(fmt.Sprintf("outputs[%d].amount_zat", i), o.AmountZat)
+		if err != nil {
+			return fromPlanRequest{}, err
+		}
+		req.Outputs = append(req.Outputs, outputIn{
+			AddressHex:  hex.EncodeToString(receiver),
+			UserAddress: o.ToAddress,
+			ValueZat:    amount,
+			MemoHex:     o.MemoHex,
+		})
+	}
+	receiver, err := orchardReceiver(plan.ChangeAddress)
+	if err != nil {
+		return fromPlanRequest{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "change_address: " + err.Error()}
+	}
+	req.ChangeAddressHex = hex.EncodeToString(receiver)
+	req.ChangeUserAddress = plan.ChangeAddress
+	return req, nil
+}
+
+// Import recovers the plan a PCZT was built from, and checks that the PCZT
+// still spends its notes and pays its outputs.
+func Import(data []byte) (txbuild.TxPlan, error) {
+	if !IsPCZT(data) {
+		return txbuild.TxPlan{}, types.CodedError{Code: ErrCodeInvalidPCZT, Message: "not a pczt"}
+	}
+	var d description
+	if err := call(ffi.PCZTParseJSON, map[string]string{"pczt_hex": hex.EncodeToString(data)}, &d); err != nil {
+		return txbuild.TxPlan{}, types.CodedError{Code: ErrCodeInvalidPCZT, Message: err.Error()}
+	}
+	return planFromDescription(d)
+}
+
+func planFromDescription(d description) (txbuild.TxPlan, error) {
+	invalid := func(msg string) error {
+		return types.CodedError{Code: ErrCodeInvalidPCZT, Message: "pczt: " + msg}
+	}
+
+	var plan txbuild.TxPlan
+	if err := json.Unmarshal([]byte(d.PlanJSON), &plan); err != nil {
+		return txbuild.TxPlan{}, invalid("embedded txplan is not valid json")
+	}
+	if plan.BranchID != d.BranchID {
+		return txbuild.TxPlan{}, invalid(fmt.Sprintf("branch_id %08x does not match the plan (%08x)", d.BranchID, plan.BranchID))
+	}
+	if plan.ExpiryHeight != d.ExpiryHeight {
+		return txbuild.TxPlan{}, invalid(fmt.Sprintf("expiry_height %d does not match the plan (%d)", d.ExpiryHeight, plan.ExpiryHeight))
+	}
+	if !strings.EqualFold(plan.Anchor, d.Anchor) {
+		return txbuild.TxPlan{}, invalid("anchor does not match the plan")
+	}
+
+	// Spends: the plan's notes, identified by position, rho and cmx.
+	if len(d.Spends) != len(plan.Notes) {
+		return txbuild.TxPlan{}, invalid(fmt.Sprintf("%d spends, plan has %d notes", len(d.Spends), len(plan.Notes)))
+	}
+	notes := map[string]int{}
+	for _, n := range plan.Notes {
+		notes[noteKey(n.Position, n.ActionNullifier, n.CMX)]++
+	}
+	var totalIn uint64
+	for _, s := range d.Spends {
+		k := noteKey(s.Position, s.Rho, s.CMX)
+		if notes[k] == 0 {
+			return txbuild.TxPlan{}, invalid(fmt.Sprintf("spend of the note at position %d is not in the plan", s.Position))
+		}
+		notes[k]--
+		if totalIn+s.ValueZat < totalIn {
+			return txbuild.TxPlan{}, invalid("spent values overflow")
+		}
+		totalIn += s.ValueZat
+	}
+
+	// Outputs: the plan's outputs, plus at most one change output without a
+	// memo. Each pays the Orchard receiver of its address.
+	outputs := map[string]int{}
+	for i, o := range plan.Outputs {
+		amount, err := parseZat(fmt.Sprintf("outputs[%d].amount_zat", i), o.AmountZat)
+		if err != nil {
+			return txbuild.TxPlan{}, err
+		}
+		receiver, err := orchardReceiver(o.ToAddress)
+		if err != nil {
+			return txbuild.TxPlan{}, invalid(fmt.Sprintf("outputs[%d].to_address: %v", i, err))
+		}
+		memo, err := memoBytes(o.MemoHex)
+		if err != nil {
+			return txbuild.TxPlan{}, invalid(fmt.Sprintf("outputs[%d].memo_hex: %v", i, err))
+		}
+		outputs[outputKey(o.ToAddress, receiver, amount, memo)]++
+	}
+	changeReceiver, err := orchardReceiver(plan.ChangeAddress)
+	if err != nil {
+		return txbuild.TxPlan{}, invalid("change_address: " + err.Error())
+	}
+	noMemo, _ := memoBytes("")
+	change := false
+	var totalOut uint64
+	for _, o := range d.Outputs {
+		recipient, err := hex.DecodeString(o.Recipient)
+		if err != nil || len(recipient) != address.OrchardReceiverLen {
+			return txbuild.TxPlan{}, invalid(fmt.Sprintf("output of %d to %s has no recipient", o.ValueZat, o.UserAddress))
+		}
+		if cmx, err := hex.DecodeString(o.CMX); err != nil || len(cmx) != 32 {
+			return txbuild.TxPlan{}, invalid(fmt.Sprintf("output of %d to %s has no cmx", o.ValueZat, o.UserAddress))
+		}
+		memo, err := hex.DecodeString(o.MemoHex)
+		if err != nil || len(memo) != memoLen {
+			return txbuild.TxPlan{}, invalid(fmt.Sprintf("output of %d to %s has no memo", o.ValueZat, o.UserAddress))
+		}
+		if totalOut+o.ValueZat < totalOut {
+			return txbuild.TxPlan{}, invalid("output values overflow")
+		}
+		totalOut += o.ValueZat
+
+		k := outputKey(o.UserAddress, recipient, o.ValueZat, memo)
+		if outputs[k] > 0 {
+			outputs[k]--
+			continue
+		}
+		if change || o.UserAddress != plan.ChangeAddress || !bytes.Equal(recipient, changeReceiver) || !bytes.Equal(memo, noMemo) {
+			return txbuild.TxPlan{}, invalid(fmt.Sprintf("output of %d to %s is not in the plan", o.ValueZat, o.UserAddress))
+		}
+		change = true
+	}
+	for k, n := range outputs {
+		if n > 0 {
+			return txbuild.TxPlan{}, invalid("plan output " + k + " is missing")
+		}
+	}
+	fee, err := parseZat("fee_zat", plan.FeeZat)
+	if err != nil {
+		return txbuild.TxPlan{}, err
+	}
+	if totalIn < totalOut || totalIn-totalOut != fee {
+		return txbuild.TxPlan{}, invalid(fmt.Sprintf("fee does not match the plan (%s)", plan.FeeZat))
+	}
+	return plan, nil
+}
+
+func noteKey(position uint32, rho, cmx string) string {
+	return fmt.Sprintf("%d/%s/%s", position, strings.ToLower(rho), strings.ToLower(cmx))
+}
+
+func outputKey(addr string, receiver []byte, amount uint64, memo []byte) string {
+	return fmt.Sprintf("%d to %s (receiver %x, memo %x)", amount, addr, receiver, bytes.TrimRight(memo, "\x00"))
+}
+
+// memoLen is the length of an Orchard memo.
+const memoLen = 512
+
+// memoBytes returns the memo an output of memoHex carries: the bytes padded
+// with zeros, or 0xF6 (no memo) if memoHex is empty, as the Rust crate
+// builds it.
+func memoBytes(memoHex string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimSpace(memoHex))
+	if err != nil {
+		return nil, errors.New("invalid hex")
+	}
+	if len(b) > memoLen {
+		return nil, errors.New("longer than 512 bytes")
+	}
+	memo := make([]byte, memoLen)
+	if len(b) == 0 {
+		memo[0] = 0xF6
+	}
+	copy(memo, b)
+	return memo, nil
+}
+
+func orchardReceiver(addr string) ([]byte, error) {
+	ua, err := address.DecodeUnified(addr)
+	if err != nil {
+		return nil, err
+	}
+	receiver, ok := ua.Orchard()
+	if !ok {
+		return nil, errors.New("address has no Orchard receiver")
+	}
+	return receiver, nil
+}
+
+func parseZat(name, s string) (uint64, error) {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, types.CodedError{Code: txbuild.ErrCodeInvalidPlan, Message: name + " must be a decimal amount"}
+	}
+	return v, nil
+}
+
+func call(fn func(string) (string, error), req any, out any) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return errors.New("pczt: marshal request")
+	}
+	raw, err := fn(string(b))
+	if err != nil {
+		return err
+	}
+
+	var status struct {
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(raw), &status); err != nil {
+		return errors.New("pczt: invalid response")
+	}
+	switch status.Status {
+	case "ok":
+		if err := json.Unmarshal([]byte(raw), out); err != nil {
+			return errors.New("pczt: invalid response")
+		}
+		return nil
+	case "err":
+		if status.Error == "" {
+			return errors.New("pczt: failed")
+		}
+		return errors.New("pczt: " + status.Error)
+	default:
+		return errors.New("pczt: invalid response")
+	}
+}
@@ -0,0 +1,136 @@
+package pczt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/address"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// testAddress returns an Orchard-only regtest address of receiver bytes b.
+func testAddress(t *testing.T, b byte) (string, string) {
+	t.Helper()
+	receiver := bytes.Repeat([]byte{b}, address.OrchardReceiverLen)
+	addr, err := address.OrchardAddress(receiver, "regtest")
+	if err != nil {
+		t.Fatalf("OrchardAddress: %v", err)
+	}
+	return addr, hex.EncodeToString(receiver)
+}
+
+func testDescription(t *testing.T) (txbuild.TxPlan, description) {
+	t.Helper()
+	to, toReceiver := testAddress(t, 0x11)
+	changeAddr, changeReceiver := testAddress(t, 0x22)
+	memo := "68690000" + strings.Repeat("00", 508)
+	noMemo := "f6" + strings.Repeat("00", 511)
+	plan := txbuild.TxPlan{
+		Version:       types.V0,
+		Kind:          types.TxPlanKindWithdrawal,
+		WalletID:      "hot",
+		Chain:         "regtest",
+		BranchID:      0xc8e71055,
+		Anchor:        strings.Repeat("ab", 32),
+		ExpiryHeight:  1040,
+		Outputs:       []txbuild.TxOutput{{TxOutput: types.TxOutput{ToAddress: to, AmountZat: "100000", MemoHex: "6869"}}},
+		ChangeAddress: changeAddr,
+		FeeZat:        "10000",
+		Notes: []txbuild.SpendNote{
+			{OrchardSpendNote: types.OrchardSpendNote{Position: 7, ActionNullifier: strings.Repeat("01", 32), CMX: strings.Repeat("02", 32)}},
+			{OrchardSpendNote: types.OrchardSpendNote{Position: 9, ActionNullifier: strings.Repeat("03", 32), CMX: strings.Repeat("04", 32)}},
+		},
+	}
+	b, _ := json.Marshal(plan)
+	var d description
+	if err := json.Unmarshal([]byte(`{
+		"branch_id": 3370586197,
+		"expiry_height": 1040,
+		"anchor": "`+strings.Repeat("AB", 32)+`",
+		"spends": [
+			{"position": 9, "rho": "`+strings.Repeat("03", 32)+`", "cmx": "`+strings.Repeat("04", 32)+`", "value_zat": 60000},
+			{"position": 7, "rho": "`+strings.Repeat("01", 32)+`", "cmx": "`+strings.Repeat("02", 32)+`", "value_zat": 70000}
+		],
+		"outputs": [
+			{"user_address": "`+changeAddr+`", "recipient": "`+changeReceiver+`", "value_zat": 20000, "cmx": "`+strings.Repeat("05", 32)+`", "memo_hex": "`+noMemo+`"},
+			{"user_address": "`+to+`", "recipient": "`+toReceiver+`", "value_zat": 100000, "cmx": "`+strings.Repeat("06", 32)+`", "memo_hex": "`+memo+`"}
+		]
+	}`), &d); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	d.PlanJSON = string(b)
+	return plan, d
+}
+
+func TestPlanFromDescription(t *testing.T) {
+	plan, d := testDescription(t)
+	got, err := planFromDescription(d)
+	if err != nil {
+		t.Fatalf("planFromDescription: %v", err)
+	}
+	if got.WalletID != plan.WalletID || len(got.Notes) != 2 || got.FeeZat != plan.FeeZat {
+		t.Fatalf("unexpected plan: %+v", got)
+	}
+
+	for name, mutate := range map[string]func(*description){
+		"not from a plan": func(d *description) { d.PlanJSON = "" },
+		"branch id":       func(d *description) { d.BranchID++ },
+		"expiry":          func(d *description) { d.ExpiryHeight++ },
+		"anchor":          func(d *description) { d.Anchor = strings.Repeat("00", 32) },
+		"missing spend":   func(d *description) { d.Spends = d.Spends[:1] },
+		"other note":      func(d *description) { d.Spends[0].Position = 8 },
+		"other output":    func(d *description) { d.Outputs[1].UserAddress = "j1b" },
+		"other recipient": func(d *description) { d.Outputs[1].Recipient = strings.Repeat("33", 43) },
+		"other memo":      func(d *description) { d.Outputs[1].MemoHex = "f6" + strings.Repeat("00", 511) },
+		"no recipient":    func(d *description) { d.Outputs[1].Recipient = "" },
+		"no cmx":          func(d *description) { d.Outputs[1].CMX = "" },
+		"change recipient": func(d *description) {
+			d.Outputs[0].Recipient = strings.Repeat("33", 43)
+		},
+		"change memo":    func(d *description) { d.Outputs[0].MemoHex = d.Outputs[1].MemoHex },
+		"missing output": func(d *description) { d.Outputs = d.Outputs[:1] },
+		"fee":            func(d *description) { d.Outputs[0].ValueZat-- },
+		"output overflow": func(d *description) {
+			d.Outputs[0].ValueZat = math.MaxUint64 - 50000
+			d.Outputs = append(d.Outputs, d.Outputs[0])
+		},
+		"two changes": func(d *description) {
+			d.Outputs[0].ValueZat = 10000
+			d.Outputs = append(d.Outputs, d.Outputs[0])
+		},
+	} {
+		_, d := testDescription(t)
+		mutate(&d)
+		_, err := planFromDescription(d)
+		var ce types.CodedError
+		if !errors.As(err, &ce) || ce.Code != ErrCodeInvalidPCZT {
+			t.Fatalf("%s: expected invalid_pczt, got %v", name, err)
+		}
+	}
+}
+
+func TestImport_NotPCZT(t *testing.T) {
+	if !IsPCZT([]byte("PCZT\x01\x00\x00\x00")) || IsPCZT([]byte(`{"version":"v0"}`)) {
+		t.Fatalf("IsPCZT")
+	}
+	_, err := Import([]byte(`{"version":"v0"}`))
+	var ce types.CodedError
+	if !errors.As(err, &ce) || ce.Code != ErrCodeInvalidPCZT {
+		t.Fatalf("expected invalid_pczt, got %v", err)
+	}
+}
+
+func TestVerifyCommitment_NoCommitment(t *testing.T) {
+	plan, _ := testDescription(t)
+	_, err := VerifyCommitment(plan, "jviewregtest1abc")
+	var ce types.CodedError
+	if !errors.As(err, &ce) || ce.Code != types.ErrCodeInvalidRequest {
+		t.Fatalf("expected invalid_request, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Package pkcs11key signs with an Ed25519 key held in a PKCS#11 token (an
+// HSM, or SoftHSM in tests).
+package pkcs11key
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/pkcs11"
+)
+
+// PKCS#11 3.0 constants missing from github.com/miekg/pkcs11.
+const (
+	ckkECEdwards = 0x00000040
+	ckmEdDSA     = 0x00001057
+)
+
+// Config locates the key.
+type Config struct {
+	// Path of the PKCS#11 module (e.g. /usr/lib/softhsm/libsofthsm2.so).
+	Module string
+	// Label of the token holding the key.
+	Token string
+	// CKA_LABEL of the private key and its public key.
+	KeyLabel string
+	// User PIN of the token.
+	PIN string
+}
+
+// Key is an open session on an Ed25519 private key. It implements
+// crypto.Signer; signing hashes nothing (pure Ed25519).
+type Key struct {
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	// Set once session is open.
+	opened bool
+	priv   pkcs11.ObjectHandle
+	pub    ed25519.PublicKey
+}
+
+// Open logs in to the token and finds the key. Close releases it.
+func Open(cfg Config) (*Key, error) {
+	if strings.TrimSpace(cfg.Module) == "" || strings.TrimSpace(cfg.Token) == "" || strings.TrimSpace(cfg.KeyLabel) == "" {
+		return nil, errors.New("pkcs11: module, token and key label required")
+	}
+	ctx := pkcs11.New(cfg.Module)
+	if ctx == nil {
+		return nil, fmt.Errorf("pkcs11: cannot load module %s", cfg.Module)
+	}
+	k := &Key{ctx: ctx}
+	if err := k.open(cfg); err != nil {
+		k.Close()
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *Key) open(cfg Config) error {
+	if err := k.ctx.Initialize(); err != nil {
+		return fmt.Errorf("pkcs11: initialize: %w", err)
+	}
+	slot, err := findSlot(k.ctx, cfg.Token)
+	if err != nil {
+		return err
+	}
+	k.session, err = k.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return fmt.Errorf("pkcs11: open session: %w", err)
+	}
+	k.opened = true
+	if err := k.ctx.Login(k.session, pkcs11.CKU_USER, cfg.PIN); err != nil {
+		return fmt.Errorf("pkcs11: login: %w", err)
+	}
+
+	k.priv, err = k.findObject(pkcs11.CKO_PRIVATE_KEY, cfg.KeyLabel)
+	if err != nil {
+		return err
+	}
+	pubObj, err := k.findObject(pkcs11.CKO_PUBLIC_KEY, cfg.KeyLabel)
+	if err != nil {
+		return err
+	}
+	attrs, err := k.ctx.GetAttributeValue(k.session, pubObj, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil)})
+	if err != nil {
+		return fmt.Errorf("pkcs11: read public key: %w", err)
+	}
+	k.pub, err = parseECPoint(attrs[0].Value)
+	return err
+}
+
+func findSlot(ctx *pkcs11.Ctx, token string) (uint, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("pkcs11: list slots: %w", err)
+	}
+	for _, s := range slots {
+		info, err := ctx.GetTokenInfo(s)
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(info.Label) == token {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("pkcs11: token %q not found", token)
+}
+
+func (k *Key) findObject(class uint, label string) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, ckkECEdwards),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := k.ctx.FindObjectsInit(k.session, template); err != nil {
+		return 0, fmt.Errorf("pkcs11: find key: %w", err)
+	}
+	objs, _, err := k.ctx.FindObjects(k.session, 2)
+	if ferr := k.ctx.FindObjectsFinal(k.session); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("pkcs11: find key: %w", err)
+	}
+	switch len(objs) {
+	case 0:
+		return 0, fmt.Errorf("pkcs11: no Ed25519 key labelled %q", label)
+	case 1:
+		return objs[0], nil
+	default:
+		return 0, fmt.Errorf("pkcs11: several Ed25519 keys labelled %q", label)
+	}
+}
+
+// parseECPoint decodes CKA_EC_POINT of an Ed25519 public key: a DER OCTET
+// STRING holding the 32-byte key (some tokens return the raw key).
+func parseECPoint(v []byte) (ed25519.PublicKey, error) {
+	if len(v) == ed25519.PublicKeySize {
+		return ed25519.PublicKey(v), nil
+	}
+	var raw []byte
+	if rest, err := asn1.Unmarshal(v, &raw); err != nil || len(rest) > 0 || len(raw) != ed25519.PublicKeySize {
+		return nil, errors.New("pkcs11: public key is not an Ed25519 point")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// Public returns the ed25519.PublicKey of the key.
+func (k *Key) Public() crypto.PublicKey {
+	return k.pub
+}
+
+// Sign signs msg with CKM_EDDSA. opts must not request a hash.
+func (k *Key) Sign(_ io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != 0 {
+		return nil, errors.New("pkcs11: Ed25519 signs the message, not a digest")
+	}
+	if err := k.ctx.SignInit(k.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(ckmEdDSA, nil)}, k.priv); err != nil {
+		return nil, fmt.Errorf("pkcs11: sign: %w", err)
+	}
+	sig, err := k.ctx.Sign(k.session, msg)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs11: sign: %w", err)
+	}
+	return sig, nil
+}
+
+// Close logs out and unloads the module.
+func (k *Key) Close() {
+	if k.opened {
+		_ = k.ctx.Logout(k.session)
+		_ = k.ctx.CloseSession(k.session)
+	}
+	_ = k.ctx.Finalize()
+	k.ctx.Destroy()
+}
@@ -0,0 +1,121 @@
+//go:build integration
+
+package pkcs11key
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+)
+
+// softHSMModule returns the SoftHSM module (SOFTHSM2_MODULE, or the usual
+// install paths) and skips the test without one.
+func softHSMModule(t *testing.T) string {
+	t.Helper()
+	candidates := []string{
+		os.Getenv("SOFTHSM2_MODULE"),
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+	}
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		if _, err := os.Stat(c); err == nil {
+			return c
+		}
+	}
+	t.Skip("SoftHSM not installed (set SOFTHSM2_MODULE)")
+	return ""
+}
+
+// initToken creates a SoftHSM token with an Ed25519 key pair labelled label.
+func initToken(t *testing.T, module, token, label, pin string) {
+	t.Helper()
+	if _, err := exec.LookPath("softhsm2-util"); err != nil {
+		t.Skip("softhsm2-util not installed")
+	}
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "softhsm2.conf")
+	if err := os.MkdirAll(filepath.Join(dir, "tokens"), 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(conf, []byte("directories.tokendir = "+filepath.Join(dir, "tokens")+"\nobjectstore.backend = file\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("SOFTHSM2_CONF", conf)
+	if out, err := exec.Command("softhsm2-util", "--init-token", "--free", "--label", token, "--pin", pin, "--so-pin", "0000").CombinedOutput(); err != nil {
+		t.Fatalf("init token: %v: %s", err, out)
+	}
+
+	ctx := pkcs11.New(module)
+	if err := ctx.Initialize(); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	defer func() {
+		_ = ctx.Finalize()
+		ctx.Destroy()
+	}()
+	slot, err := findSlot(ctx, token)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		t.Fatalf("open session: %v", err)
+	}
+	defer func() { _ = ctx.CloseSession(session) }()
+	if err := ctx.Login(session, pkcs11.CKU_USER, pin); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	// CKA_EC_PARAMS: DER OID 1.3.101.112 (id-Ed25519).
+	ecParams := []byte{0x06, 0x03, 0x2b, 0x65, 0x70}
+	_, _, err = ctx.GenerateKeyPair(session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(0x00001055, nil)}, // CKM_EC_EDWARDS_KEY_PAIR_GEN
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, ecParams),
+			pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		})
+	if err != nil {
+		t.Fatalf("generate key pair: %v", err)
+	}
+}
+
+func TestIntegration_SoftHSMSign(t *testing.T) {
+	module := softHSMModule(t)
+	initToken(t, module, "txbuild", "builder", "1234")
+
+	k, err := Open(Config{Module: module, Token: "txbuild", KeyLabel: "builder", PIN: "1234"})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer k.Close()
+
+	msg := []byte("juno-txplan-signature/v1\n{}")
+	sig, err := k.Sign(nil, msg, crypto.Hash(0))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if !ed25519.Verify(k.Public().(ed25519.PublicKey), msg, sig) {
+		t.Fatalf("signature does not verify")
+	}
+
+	if _, err := Open(Config{Module: module, Token: "txbuild", KeyLabel: "missing", PIN: "1234"}); err == nil {
+		t.Fatalf("expected error for a missing key")
+	}
+}
@@ -0,0 +1,19 @@
+package pkcs11key
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseECPoint(t *testing.T) {
+	raw := bytes.Repeat([]byte{9}, 32)
+	for _, v := range [][]byte{raw, append([]byte{0x04, 0x20}, raw...)} {
+		pub, err := parseECPoint(v)
+		if err != nil || !bytes.Equal(pub, raw) {
+			t.Fatalf("parseECPoint(%x) = %x, %v", v, pub, err)
+		}
+	}
+	if _, err := parseECPoint([]byte{0x04, 0x02, 1, 2}); err == nil {
+		t.Fatalf("expected error for a short point")
+	}
+}
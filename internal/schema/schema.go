@@ -0,0 +1,197 @@
+// Package schema validates juno-txbuild JSON documents against the schemas in
+// api/.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Abdullah1738/juno-txbuild/api"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Error is one validation failure.
+type Error struct {
+	// JSON pointer (RFC 6901) to the offending value; "" is the document root.
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	ptr := e.Pointer
+	if ptr == "" {
+		ptr = "(root)"
+	}
+	return ptr + ": " + e.Message
+}
+
+// Errors is the list of failures of one document.
+type Errors []Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Decode parses data as exactly one JSON value. Unlike encoding/json it
+// rejects duplicate object keys and trailing data. Numbers are json.Number.
+func Decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := decodeValue(dec, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, Errors{{Pointer: "", Message: fmt.Sprintf("trailing data after JSON value at byte offset %d", dec.InputOffset())}}
+	}
+	return v, nil
+}
+
+func decodeValue(dec *json.Decoder, ptr string) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, syntaxError(dec, ptr, err)
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := make(map[string]any)
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, syntaxError(dec, ptr, err)
+			}
+			key := kt.(string)
+			if _, dup := obj[key]; dup {
+				return nil, Errors{{Pointer: ptr, Message: fmt.Sprintf("duplicate key %q", key)}}
+			}
+			v, err := decodeValue(dec, ptr+"/"+escapePointer(key))
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = v
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, syntaxError(dec, ptr, err)
+		}
+		return obj, nil
+	case '[':
+		arr := []any{}
+		for i := 0; dec.More(); i++ {
+			v, err := decodeValue(dec, ptr+"/"+strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, syntaxError(dec, ptr, err)
+		}
+		return arr, nil
+	default:
+		return nil, Errors{{Pointer: ptr, Message: fmt.Sprintf("unexpected %q", delim)}}
+	}
+}
+
+func syntaxError(dec *json.Decoder, ptr string, err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return Errors{{Pointer: ptr, Message: fmt.Sprintf("invalid json at byte offset %d: %v", dec.InputOffset(), err)}}
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+var (
+	compileMu sync.Mutex
+	compiled  = make(map[string]*jsonschema.Schema)
+)
+
+func load(name string) (*jsonschema.Schema, error) {
+	compileMu.Lock()
+	defer compileMu.Unlock()
+
+	if s, ok := compiled[name]; ok {
+		return s, nil
+	}
+	b, err := api.Schemas.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("schema: unknown schema %q", name)
+	}
+	c := jsonschema.NewCompiler()
+	c.Draft = jsonschema.Draft2020
+	url := "https://juno-txbuild.invalid/api/" + name
+	if err := c.AddResource(url, bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("schema: %s: %v", name, err)
+	}
+	s, err := c.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("schema: %s: %v", name, err)
+	}
+	compiled[name] = s
+	return s, nil
+}
+
+// Validate checks a decoded document (see Decode) against the named schema
+// (api.TxOutputsSchema, api.TxPlanV0Schema). Validation failures are Errors.
+func Validate(name string, v any) error {
+	s, err := load(name)
+	if err != nil {
+		return err
+	}
+	err = s.Validate(v)
+	if err == nil {
+		return nil
+	}
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+
+	var out Errors
+	seen := make(map[Error]bool)
+	var walk func(*jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			ve := Error{Pointer: e.InstanceLocation, Message: e.Message}
+			if !seen[ve] {
+				seen[ve] = true
+				out = append(out, ve)
+			}
+			return
+		}
+		for _, c := range e.Causes {
+			walk(c)
+		}
+	}
+	walk(ve)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Pointer < out[j].Pointer })
+	return out
+}
+
+// ValidateJSON decodes data strictly and validates it against the named schema.
+func ValidateJSON(name string, data []byte) error {
+	v, err := Decode(data)
+	if err != nil {
+		return err
+	}
+	return Validate(name, v)
+}
@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Abdullah1738/juno-txbuild/api"
+)
+
+func TestDecode_Strict(t *testing.T) {
+	if _, err := Decode([]byte(` [{"a": 1, "b": [true, null, "x"]}] `)); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	for in, want := range map[string]string{
+		`[{"a": 1, "a": 2}]`:               `/0: duplicate key "a"`,
+		`{"x": {"a/b": {"k": 1, "k": 2}}}`: `/x/a~1b: duplicate key "k"`,
+		`[] []`:                            "(root): trailing data",
+		`{"a": 1} x`:                       "(root): trailing data",
+		`[{"a": }]`:                        "/0/a: invalid json",
+		`[1, 2`:                            "invalid json",
+		``:                                 "invalid json",
+	} {
+		_, err := Decode([]byte(in))
+		var errs Errors
+		if !errors.As(err, &errs) || !strings.Contains(err.Error(), want) {
+			t.Fatalf("Decode(%q)=%v want %q", in, err, want)
+		}
+	}
+}
+
+func TestValidateJSON_Outputs(t *testing.T) {
+	ok := `[{"to_address": "j1a", "amount_zat": "1000", "memo_text": "hi", "request_id": "r1"}, {"to_address": "j1b", "amount_zat": "max"}]`
+	if err := ValidateJSON(api.TxOutputsSchema, []byte(ok)); err != nil {
+		t.Fatalf("ValidateJSON: %v", err)
+	}
+
+	err := ValidateJSON(api.TxOutputsSchema, []byte(`[{"to_address": "j1a", "amount_zat": "1000"}, {"to_address": "j1b", "amount_zat": "1.5", "colour": "red"}]`))
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	var sawAmount, sawUnknown bool
+	for _, e := range errs {
+		switch {
+		case e.Pointer == "/1/amount_zat":
+			sawAmount = true
+		case e.Pointer == "/1" && strings.Contains(e.Message, "colour"):
+			sawUnknown = true
+		}
+	}
+	if !sawAmount || !sawUnknown {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	if err := ValidateJSON(api.TxOutputsSchema, []byte(`[]`)); err == nil {
+		t.Fatalf("expected error for empty outputs")
+	}
+}
+
+func TestValidate_UnknownSchema(t *testing.T) {
+	if err := Validate("nope.schema.json", nil); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,76 @@
+package ur
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strings"
+)
+
+// bytewords are the 256 four-letter words of BCR-2020-012, one per byte
+// value. The minimal encoding uses the first and last letter of each word.
+const bytewords = "" +
+	"ableacidalsoapexaquaarchatomauntawayaxisbackbaldbarnbeltbetabias" +
+	"bluebodybragbrewbulbbuzzcalmcashcatschefcityclawcodecolacookcost" +
+	"cruxcurlcuspcyandarkdatadaysdelidicedietdoordowndrawdropdrumdull" +
+	"dutyeacheasyechoedgeepicevenexamexiteyesfactfairfernfigsfilmfish" +
+	"fizzflapflewfluxfoxyfreefrogfuelfundgalagamegeargemsgiftgirlglow" +
+	"goodgraygrimgurugushgyrohalfhanghardhawkheathelphighhillholyhope" +
+	"hornhutsicedideaidleinchinkyintoirisironitemjadejazzjoinjoltjowl" +
+	"judojugsjumpjunkjurykeepkenokeptkeyskickkilnkingkitekiwiknoblamb" +
+	"lavalazyleaflegsliarlimplionlistlogoloudloveluaulucklungmainmany" +
+	"mathmazememomenumeowmildmintmissmonknailnavyneednewsnextnoonnote" +
+	"numbobeyoboeomitonyxopenovalowlspaidpartpeckplaypluspoempoolpose" +
+	"puffpumapurrquadquizraceramprealredorichroadrockroofrubyruinruns" +
+	"rustsafesagascarsetssilkskewslotsoapsolosongstubsurfswantacotask" +
+	"taxitenttiedtimetinytoiltombtoystriptunatwinuglyundouniturgeuser" +
+	"vastveryvetovialvibeviewvisavoidvowswallwandwarmwaspwavewaxywebs" +
+	"whatwhenwhizwolfworkyankyawnyellyogayurtzapszerozestzinczonezoom"
+
+// minimalIndex maps a minimal byteword (first and last letter) to its byte.
+var minimalIndex = func() map[string]byte {
+	m := make(map[string]byte, 256)
+	for i := 0; i < 256; i++ {
+		w := bytewords[i*4 : i*4+4]
+		m[w[:1]+w[3:]] = byte(i)
+	}
+	return m
+}()
+
+// encodeMinimal encodes data followed by its CRC-32 as minimal bytewords.
+func encodeMinimal(data []byte) string {
+	buf := binary.BigEndian.AppendUint32(append([]byte{}, data...), crc32.ChecksumIEEE(data))
+	var sb strings.Builder
+	sb.Grow(2 * len(buf))
+	for _, b := range buf {
+		w := bytewords[int(b)*4 : int(b)*4+4]
+		sb.WriteByte(w[0])
+		sb.WriteByte(w[3])
+	}
+	return sb.String()
+}
+
+// decodeMinimal decodes minimal bytewords (in either case) and checks and
+// removes the trailing CRC-32.
+func decodeMinimal(s string) ([]byte, error) {
+	s = strings.ToLower(s)
+	if len(s)%2 != 0 {
+		return nil, errors.New("ur: bytewords: odd length")
+	}
+	buf := make([]byte, 0, len(s)/2)
+	for i := 0; i < len(s); i += 2 {
+		b, ok := minimalIndex[s[i:i+2]]
+		if !ok {
+			return nil, errors.New("ur: bytewords: invalid word " + s[i:i+2])
+		}
+		buf = append(buf, b)
+	}
+	if len(buf) < 5 {
+		return nil, errors.New("ur: bytewords: too short")
+	}
+	data, sum := buf[:len(buf)-4], buf[len(buf)-4:]
+	if binary.BigEndian.Uint32(sum) != crc32.ChecksumIEEE(data) {
+		return nil, errors.New("ur: bytewords: checksum mismatch")
+	}
+	return data, nil
+}
@@ -0,0 +1,233 @@
+package ur
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// part is one fountain-coded part: the XOR of the message fragments picked
+// by chooseFragments(SeqNum, SeqLen, Checksum). It is encoded as the CBOR
+// array [seq_num, seq_len, message_len, checksum, data].
+type part struct {
+	_          struct{} `cbor:",toarray"`
+	SeqNum     uint32
+	SeqLen     int
+	MessageLen int
+	Checksum   uint32
+	Data       []byte
+}
+
+// nominalFragmentLen returns the smallest fragment length of at most
+// maxFragmentLen that splits messageLen bytes into equal fragments.
+func nominalFragmentLen(messageLen, minFragmentLen, maxFragmentLen int) int {
+	maxCount := max(messageLen/minFragmentLen, 1)
+	fragmentLen := messageLen
+	for count := 1; count <= maxCount; count++ {
+		fragmentLen = (messageLen + count - 1) / count
+		if fragmentLen <= maxFragmentLen {
+			break
+		}
+	}
+	return fragmentLen
+}
+
+// chooseFragments returns the indexes of the fragments mixed into part
+// seqNum. Parts 1..seqLen are the fragments themselves.
+func chooseFragments(seqNum uint32, seqLen int, checksum uint32) []int {
+	if int(seqNum) <= seqLen {
+		return []int{int(seqNum) - 1}
+	}
+	var seed [8]byte
+	binary.BigEndian.PutUint32(seed[:4], seqNum)
+	binary.BigEndian.PutUint32(seed[4:], checksum)
+	rng := newXoshiro256(seed[:])
+
+	weights := make([]float64, seqLen)
+	for i := range weights {
+		weights[i] = 1 / float64(i+1)
+	}
+	degree := newRandomSampler(weights).next(rng) + 1
+
+	remaining := make([]int, seqLen)
+	for i := range remaining {
+		remaining[i] = i
+	}
+	shuffled := make([]int, 0, seqLen)
+	for len(remaining) > 0 {
+		i := rng.nextInt(0, len(remaining)-1)
+		shuffled = append(shuffled, remaining[i])
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	indexes := shuffled[:degree]
+	sort.Ints(indexes)
+	return indexes
+}
+
+// fountainEncoder emits an unbounded sequence of parts of a message.
+type fountainEncoder struct {
+	messageLen int
+	checksum   uint32
+	fragments  [][]byte
+	seqNum     uint32
+}
+
+func newFountainEncoder(message []byte, maxFragmentLen int) *fountainEncoder {
+	const minFragmentLen = 10
+	fragmentLen := nominalFragmentLen(len(message), minFragmentLen, maxFragmentLen)
+	padded := make([]byte, (len(message)+fragmentLen-1)/fragmentLen*fragmentLen)
+	copy(padded, message)
+	e := &fountainEncoder{messageLen: len(message), checksum: crc32.ChecksumIEEE(message)}
+	for i := 0; i < len(padded); i += fragmentLen {
+		e.fragments = append(e.fragments, padded[i:i+fragmentLen])
+	}
+	return e
+}
+
+func (e *fountainEncoder) seqLen() int { return len(e.fragments) }
+
+func (e *fountainEncoder) nextPart() part {
+	e.seqNum++
+	data := make([]byte, len(e.fragments[0]))
+	for _, i := range chooseFragments(e.seqNum, e.seqLen(), e.checksum) {
+		xorInto(data, e.fragments[i])
+	}
+	return part{SeqNum: e.seqNum, SeqLen: e.seqLen(), MessageLen: e.messageLen, Checksum: e.checksum, Data: data}
+}
+
+// mixedPart is a received part whose known fragments have been XORed out.
+type mixedPart struct {
+	indexes []int
+	data    []byte
+}
+
+// fountainDecoder reassembles a message from parts received in any order.
+type fountainDecoder struct {
+	seqLen     int
+	messageLen int
+	checksum   uint32
+	fragLen    int
+	simple     map[int][]byte
+	mixed      []mixedPart
+	message    []byte
+}
+
+func (d *fountainDecoder) complete() bool { return d.message != nil }
+
+// receive adds p. Parts of another message are an error.
+func (d *fountainDecoder) receive(p part) error {
+	if p.SeqNum == 0 || p.SeqLen < 1 || p.MessageLen < 1 || len(p.Data) == 0 {
+		return errors.New("ur: invalid part")
+	}
+	if d.simple == nil {
+		if len(p.Data)*p.SeqLen < p.MessageLen || len(p.Data)*(p.SeqLen-1) >= p.MessageLen {
+			return errors.New("ur: invalid part length")
+		}
+		d.seqLen, d.messageLen, d.checksum, d.fragLen = p.SeqLen, p.MessageLen, p.Checksum, len(p.Data)
+		d.simple = map[int][]byte{}
+	} else if p.SeqLen != d.seqLen || p.MessageLen != d.messageLen || p.Checksum != d.checksum || len(p.Data) != d.fragLen {
+		return errors.New("ur: part belongs to a different message")
+	}
+	if d.complete() {
+		return nil
+	}
+
+	m := mixedPart{indexes: chooseFragments(p.SeqNum, p.SeqLen, p.Checksum), data: append([]byte{}, p.Data...)}
+	m = d.reduce(m)
+	switch len(m.indexes) {
+	case 0:
+		return nil
+	case 1:
+		d.addSimple(m.indexes[0], m.data)
+	default:
+		d.mixed = append(d.mixed, m)
+	}
+	if len(d.simple) == d.seqLen {
+		return d.finish()
+	}
+	return nil
+}
+
+// reduce XORs the known fragments out of m.
+func (d *fountainDecoder) reduce(m mixedPart) mixedPart {
+	var rest []int
+	for _, i := range m.indexes {
+		if f, ok := d.simple[i]; ok {
+			xorInto(m.data, f)
+		} else {
+			rest = append(rest, i)
+		}
+	}
+	m.indexes = rest
+	return m
+}
+
+// addSimple records fragment i and peels it off the mixed parts, which may
+// reveal more fragments.
+func (d *fountainDecoder) addSimple(i int, data []byte) {
+	queue := []mixedPart{{indexes: []int{i}, data: data}}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if _, ok := d.simple[s.indexes[0]]; ok {
+			continue
+		}
+		d.simple[s.indexes[0]] = s.data
+		kept := d.mixed[:0]
+		for _, m := range d.mixed {
+			m = d.reduce(m)
+			switch len(m.indexes) {
+			case 0:
+			case 1:
+				queue = append(queue, m)
+			default:
+				kept = append(kept, m)
+			}
+		}
+		d.mixed = kept
+	}
+}
+
+func (d *fountainDecoder) finish() error {
+	msg := make([]byte, 0, d.seqLen*d.fragLen)
+	for i := 0; i < d.seqLen; i++ {
+		msg = append(msg, d.simple[i]...)
+	}
+	msg = msg[:d.messageLen]
+	if crc32.ChecksumIEEE(msg) != d.checksum {
+		return errors.New("ur: message checksum mismatch")
+	}
+	d.message = msg
+	return nil
+}
+
+// progress returns the number of fragments recovered and needed.
+func (d *fountainDecoder) progress() (int, int) {
+	return len(d.simple), d.seqLen
+}
+
+func xorInto(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+func encodePart(p part) ([]byte, error) {
+	b, err := cbor.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("ur: %w", err)
+	}
+	return b, nil
+}
+
+func decodePart(b []byte) (part, error) {
+	var p part
+	if err := cbor.Unmarshal(b, &p); err != nil {
+		return part{}, fmt.Errorf("ur: invalid part: %v", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,157 @@
+// Package ur encodes messages as Uniform Resources (Blockchain Commons
+// BCR-2020-005): "ur:<type>/..." strings of minimal bytewords, split into
+// fountain-coded multipart frames for animated QR codes.
+package ur
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxMessageLen bounds the messages a Decoder accepts.
+const maxMessageLen = 16 << 20
+
+// Encoder splits a CBOR message into UR frames. A message that fits in one
+// fragment is a single-part UR; otherwise NextPart returns an unbounded
+// sequence of parts, of which any SeqLen (or a few more) usually suffice.
+type Encoder struct {
+	urType string
+	fe     *fountainEncoder
+}
+
+// NewEncoder returns an encoder of message with fragments of at most
+// maxFragmentLen bytes.
+func NewEncoder(urType string, message []byte, maxFragmentLen int) (*Encoder, error) {
+	if !validType(urType) {
+		return nil, fmt.Errorf("ur: invalid type %q", urType)
+	}
+	if len(message) == 0 {
+		return nil, errors.New("ur: empty message")
+	}
+	if maxFragmentLen < 10 {
+		return nil, errors.New("ur: fragment length must be at least 10")
+	}
+	return &Encoder{urType: urType, fe: newFountainEncoder(message, maxFragmentLen)}, nil
+}
+
+// SeqLen is the number of fragments of the message.
+func (e *Encoder) SeqLen() int { return e.fe.seqLen() }
+
+// NextPart returns the next frame.
+func (e *Encoder) NextPart() (string, error) {
+	p := e.fe.nextPart()
+	if p.SeqLen == 1 {
+		return "ur:" + e.urType + "/" + encodeMinimal(p.Data[:p.MessageLen]), nil
+	}
+	b, err := encodePart(p)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ur:%s/%d-%d/%s", e.urType, p.SeqNum, p.SeqLen, encodeMinimal(b)), nil
+}
+
+// Decoder reassembles a message from the frames of one UR, in any order,
+// with duplicates ignored.
+type Decoder struct {
+	urType  string
+	fd      fountainDecoder
+	message []byte
+}
+
+// Receive adds a frame (in either case).
+func (d *Decoder) Receive(frame string) error {
+	frame = strings.ToLower(strings.TrimSpace(frame))
+	rest, ok := strings.CutPrefix(frame, "ur:")
+	if !ok {
+		return errors.New("ur: frame does not start with ur:")
+	}
+	comps := strings.Split(rest, "/")
+	if len(comps) != 2 && len(comps) != 3 {
+		return errors.New("ur: invalid frame")
+	}
+	if !validType(comps[0]) {
+		return fmt.Errorf("ur: invalid type %q", comps[0])
+	}
+	if d.urType != "" && comps[0] != d.urType {
+		return fmt.Errorf("ur: frame of type %s, expected %s", comps[0], d.urType)
+	}
+	d.urType = comps[0]
+
+	data, err := decodeMinimal(comps[len(comps)-1])
+	if err != nil {
+		return err
+	}
+	if len(comps) == 2 {
+		if d.fd.simple != nil {
+			return errors.New("ur: single-part frame within a multipart UR")
+		}
+		d.message = data
+		return nil
+	}
+	if d.message != nil && d.fd.simple == nil {
+		return errors.New("ur: multipart frame after a single-part UR")
+	}
+	seqNum, seqLen, err := parseSeq(comps[1])
+	if err != nil {
+		return err
+	}
+	p, err := decodePart(data)
+	if err != nil {
+		return err
+	}
+	if p.SeqNum != seqNum || p.SeqLen != seqLen {
+		return errors.New("ur: frame sequence does not match its part")
+	}
+	if p.MessageLen > maxMessageLen {
+		return errors.New("ur: message too large")
+	}
+	if err := d.fd.receive(p); err != nil {
+		return err
+	}
+	if d.fd.complete() {
+		d.message = d.fd.message
+	}
+	return nil
+}
+
+// Complete reports whether the message has been reassembled.
+func (d *Decoder) Complete() bool { return d.message != nil }
+
+// Progress returns the number of fragments recovered and needed (0 before
+// the first multipart frame).
+func (d *Decoder) Progress() (int, int) {
+	if d.fd.simple == nil && d.message != nil {
+		return 1, 1
+	}
+	return d.fd.progress()
+}
+
+// Result returns the UR type and message once Complete.
+func (d *Decoder) Result() (string, []byte) { return d.urType, d.message }
+
+func parseSeq(s string) (uint32, int, error) {
+	a, b, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("ur: invalid sequence %q", s)
+	}
+	seqNum, err1 := strconv.ParseUint(a, 10, 32)
+	seqLen, err2 := strconv.ParseUint(b, 10, 32)
+	if err1 != nil || err2 != nil || seqNum == 0 || seqLen == 0 || seqLen > maxMessageLen {
+		return 0, 0, fmt.Errorf("ur: invalid sequence %q", s)
+	}
+	return uint32(seqNum), int(seqLen), nil
+}
+
+func validType(t string) bool {
+	if t == "" {
+		return false
+	}
+	for _, c := range t {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
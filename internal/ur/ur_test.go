@@ -0,0 +1,201 @@
+package ur
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func TestBytewords(t *testing.T) {
+	// Vector from the BC-UR reference implementation.
+	if got := encodeMinimal([]byte{0, 1, 2, 128, 255}); got != "aeadaolazmjendeoti" {
+		t.Fatalf("encodeMinimal = %s", got)
+	}
+	got, err := decodeMinimal("AEADAOLAZMJENDEOTI")
+	if err != nil || !bytes.Equal(got, []byte{0, 1, 2, 128, 255}) {
+		t.Fatalf("decodeMinimal = %x, %v", got, err)
+	}
+	if _, err := decodeMinimal("aeadaolazmjendeotj"); err == nil {
+		t.Fatalf("expected checksum error")
+	}
+}
+
+func TestXoshiro256(t *testing.T) {
+	// Vector from the BC-UR reference implementation.
+	want := []uint64{42, 81, 85, 8, 82, 84, 76, 73, 70, 88, 2, 74, 40, 48, 77, 54, 88, 7, 5, 88}
+	rng := newXoshiro256([]byte("Wolf"))
+	for i, w := range want {
+		if got := rng.next() % 100; got != w {
+			t.Fatalf("value %d: %d, want %d", i, got, w)
+		}
+	}
+}
+
+func testMessage(n int) []byte {
+	return seededMessage("message", n)
+}
+
+// seededMessage is the reference implementation's make_message.
+func seededMessage(seed string, n int) []byte {
+	rng := newXoshiro256([]byte(seed))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(rng.nextInt(0, 255))
+	}
+	return b
+}
+
+func TestChooseFragments(t *testing.T) {
+	// Vector from the BC-UR reference implementation: a 1024-byte message
+	// in 11 fragments of at most 100 bytes.
+	want := [][]int{
+		{0}, {1}, {2}, {3}, {4}, {5}, {6}, {7}, {8}, {9}, {10},
+		{9},
+		{2, 5, 6, 8, 9, 10},
+		{8},
+		{1, 5},
+		{1},
+		{0, 2, 4, 5, 8, 10},
+		{5},
+		{2},
+		{2},
+		{0, 1, 3, 4, 5, 7, 9, 10},
+		{0, 1, 2, 3, 5, 6, 8, 9, 10},
+		{0, 2, 4, 5, 7, 8, 9, 10},
+		{3, 5},
+		{4},
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		{0, 1, 3, 4, 5, 6, 7, 9, 10},
+		{6},
+		{5, 6},
+		{7},
+	}
+	msg := seededMessage("Wolf", 1024)
+	e := newFountainEncoder(msg, 100)
+	if e.seqLen() != 11 {
+		t.Fatalf("seqLen = %d", e.seqLen())
+	}
+	for i, w := range want {
+		if got := chooseFragments(uint32(i+1), e.seqLen(), e.checksum); !slices.Equal(got, w) {
+			t.Fatalf("part %d: %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+// referenceParts are the first 20 frames of the BC-UR reference encoder for
+// a "bytes" UR of the 256-byte message seeded with "Wolf", in fragments of
+// at most 30 bytes.
+var referenceParts = []string{
+	"ur:bytes/1-9/lpadascfadaxcywenbpljkhdcahkadaemejtswhhylkepmykhhtsytsnoyoyaxaedsuttydmmhhpktpmsrjtdkgslpgh",
+	"ur:bytes/2-9/lpaoascfadaxcywenbpljkhdcagwdpfnsboxgwlbaawzuefywkdplrsrjynbvygabwjldapfcsgmghhkhstlrdcxaefz",
+	"ur:bytes/3-9/lpaxascfadaxcywenbpljkhdcahelbknlkuejnbadmssfhfrdpsbiegecpasvssovlgeykssjykklronvsjksopdzmol",
+	"ur:bytes/4-9/lpaaascfadaxcywenbpljkhdcasotkhemthydawydtaxneurlkosgwcekonertkbrlwmplssjtammdplolsbrdzcrtas",
+	"ur:bytes/5-9/lpahascfadaxcywenbpljkhdcatbbdfmssrkzmcwnezelennjpfzbgmuktrhtejscktelgfpdlrkfyfwdajldejokbwf",
+	"ur:bytes/6-9/lpamascfadaxcywenbpljkhdcackjlhkhybssklbwefectpfnbbectrljectpavyrolkzczcpkmwidmwoxkilghdsowp",
+	"ur:bytes/7-9/lpatascfadaxcywenbpljkhdcavszmwnjkwtclrtvaynhpahrtoxmwvwatmedibkaegdosftvandiodagdhthtrlnnhy",
+	"ur:bytes/8-9/lpayascfadaxcywenbpljkhdcadmsponkkbbhgsoltjntegepmttmoonftnbuoiyrehfrtsabzsttorodklubbuyaetk",
+	"ur:bytes/9-9/lpasascfadaxcywenbpljkhdcajskecpmdckihdyhphfotjojtfmlnwmadspaxrkytbztpbauotbgtgtaeaevtgavtny",
+	"ur:bytes/10-9/lpbkascfadaxcywenbpljkhdcahkadaemejtswhhylkepmykhhtsytsnoyoyaxaedsuttydmmhhpktpmsrjtwdkiplzs",
+	"ur:bytes/11-9/lpbdascfadaxcywenbpljkhdcahelbknlkuejnbadmssfhfrdpsbiegecpasvssovlgeykssjykklronvsjkvetiiapk",
+	"ur:bytes/12-9/lpbnascfadaxcywenbpljkhdcarllaluzmdmgstospeyiefmwejlwtpedamktksrvlcygmzemovovllarodtmtbnptrs",
+	"ur:bytes/13-9/lpbtascfadaxcywenbpljkhdcamtkgtpknghchchyketwsvwgwfdhpgmgtylctotzopdrpayoschcmhplffziachrfgd",
+	"ur:bytes/14-9/lpbaascfadaxcywenbpljkhdcapazewnvonnvdnsbyleynwtnsjkjndeoldydkbkdslgjkbbkortbelomueekgvstegt",
+	"ur:bytes/15-9/lpbsascfadaxcywenbpljkhdcaynmhpddpzmversbdqdfyrehnqzlugmjzmnmtwmrouohtstgsbsahpawkditkckynwt",
+	"ur:bytes/16-9/lpbeascfadaxcywenbpljkhdcawygekobamwtlihsnpalnsghenskkiynthdzotsimtojetprsttmukirlrsbtamjtpd",
+	"ur:bytes/17-9/lpbyascfadaxcywenbpljkhdcamklgftaxykpewyrtqzhydntpnytyisincxmhtbceaykolduortotiaiaiafhiaoyce",
+	"ur:bytes/18-9/lpbgascfadaxcywenbpljkhdcahkadaemejtswhhylkepmykhhtsytsnoyoyaxaedsuttydmmhhpktpmsrjtntwkbkwy",
+	"ur:bytes/19-9/lpbwascfadaxcywenbpljkhdcadekicpaajootjzpsdrbalpeywllbdsnbinaerkurspbncxgslgftvtsrjtksplcpeo",
+	"ur:bytes/20-9/lpbbascfadaxcywenbpljkhdcayapmrleeleaxpasfrtrdkncffwjyjzgyetdmlewtkpktgllepfrltataztksmhkbot",
+}
+
+func TestEncoder_ReferenceParts(t *testing.T) {
+	message, err := cbor.Marshal(seededMessage("Wolf", 256))
+	if err != nil {
+		t.Fatalf("cbor: %v", err)
+	}
+	enc, err := NewEncoder("bytes", message, 30)
+	if err != nil {
+		t.Fatalf("NewEncoder: %v", err)
+	}
+	for i, want := range referenceParts {
+		got, err := enc.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		if got != want {
+			t.Fatalf("part %d:\n got %s\nwant %s", i+1, got, want)
+		}
+	}
+
+	// The reference frames decode without the pure fragments 2, 4 and 6.
+	var dec Decoder
+	for i, f := range referenceParts {
+		if i == 1 || i == 3 || i == 5 {
+			continue
+		}
+		if err := dec.Receive(f); err != nil {
+			t.Fatalf("Receive(%s): %v", f, err)
+		}
+	}
+	urType, got := dec.Result()
+	if !dec.Complete() || urType != "bytes" || !bytes.Equal(got, message) {
+		t.Fatalf("decoded %s %x", urType, got)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, n := range []int{1, 9, 100, 1000, 32768} {
+		msg := testMessage(n)
+		enc, err := NewEncoder("juno-txplan", msg, 100)
+		if err != nil {
+			t.Fatalf("NewEncoder: %v", err)
+		}
+		var dec Decoder
+		// Drop the pure fragments of every other part, so that mixed parts
+		// have to be peeled.
+		for i := 0; !dec.Complete(); i++ {
+			if i > 10*enc.SeqLen()+20 {
+				t.Fatalf("n=%d: not complete after %d frames", n, i)
+			}
+			frame, err := enc.NextPart()
+			if err != nil {
+				t.Fatalf("NextPart: %v", err)
+			}
+			if enc.SeqLen() > 1 && i < enc.SeqLen() && i%2 == 0 {
+				continue
+			}
+			if err := dec.Receive(strings.ToUpper(frame)); err != nil {
+				t.Fatalf("n=%d: Receive(%s): %v", n, frame, err)
+			}
+		}
+		urType, got := dec.Result()
+		if urType != "juno-txplan" || !bytes.Equal(got, msg) {
+			t.Fatalf("n=%d: round trip changed the message", n)
+		}
+	}
+}
+
+func TestDecoder_Rejects(t *testing.T) {
+	a, _ := NewEncoder("juno-txplan", testMessage(1000), 100)
+	b, _ := NewEncoder("juno-txplan", testMessage(999), 100)
+	var dec Decoder
+	frame, _ := a.NextPart()
+	if err := dec.Receive(frame); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	other, _ := b.NextPart()
+	for _, f := range []string{
+		other,
+		strings.Replace(frame, "juno-txplan", "bytes", 1),
+		strings.Replace(frame, "/1-", "/2-", 1),
+		frame[:len(frame)-2],
+		"juno-txplan/1-10/ae",
+	} {
+		if err := dec.Receive(f); err == nil {
+			t.Fatalf("expected error for %s", f)
+		}
+	}
+}
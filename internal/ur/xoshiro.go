@@ -0,0 +1,107 @@
+package ur
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// xoshiro256 is the xoshiro256** generator the UR fountain code uses to pick
+// the fragments of each mixed part, seeded from the SHA-256 of a seed.
+type xoshiro256 struct {
+	s [4]uint64
+}
+
+func newXoshiro256(seed []byte) *xoshiro256 {
+	digest := sha256.Sum256(seed)
+	var x xoshiro256
+	for i := range x.s {
+		x.s[i] = binary.BigEndian.Uint64(digest[i*8:])
+	}
+	return &x
+}
+
+func (x *xoshiro256) next() uint64 {
+	s := &x.s
+	result := bits.RotateLeft64(s[1]*5, 7) * 9
+	t := s[1] << 17
+	s[2] ^= s[0]
+	s[3] ^= s[1]
+	s[1] ^= s[2]
+	s[0] ^= s[3]
+	s[2] ^= t
+	s[3] = bits.RotateLeft64(s[3], 45)
+	return result
+}
+
+// nextDouble returns a value in [0, 1).
+func (x *xoshiro256) nextDouble() float64 {
+	return float64(x.next()) / (float64(math.MaxUint64) + 1)
+}
+
+// nextInt returns a value in [low, high].
+func (x *xoshiro256) nextInt(low, high int) int {
+	return int(x.nextDouble()*float64(high-low+1)) + low
+}
+
+// randomSampler draws indexes with given weights (Vose's alias method, with
+// the index order of the UR reference implementation).
+type randomSampler struct {
+	probs   []float64
+	aliases []int
+}
+
+func newRandomSampler(weights []float64) randomSampler {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	n := len(weights)
+	p := make([]float64, n)
+	for i, w := range weights {
+		p[i] = w * float64(n) / sum
+	}
+	var small, large []int
+	for i := n - 1; i >= 0; i-- {
+		if p[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	probs := make([]float64, n)
+	aliases := make([]int, n)
+	for len(small) > 0 && len(large) > 0 {
+		a := small[len(small)-1]
+		small = small[:len(small)-1]
+		g := large[len(large)-1]
+		large = large[:len(large)-1]
+		probs[a] = p[a]
+		aliases[a] = g
+		p[g] += p[a] - 1
+		if p[g] < 1 {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+	for _, i := range large {
+		probs[i] = 1
+	}
+	// Only reached through rounding.
+	for _, i := range small {
+		probs[i] = 1
+	}
+	return randomSampler{probs: probs, aliases: aliases}
+}
+
+func (r randomSampler) next(rng *xoshiro256) int {
+	r1 := rng.nextDouble()
+	r2 := rng.nextDouble()
+	i := int(float64(len(r.probs)) * r1)
+	if r2 < r.probs[i] {
+		return i
+	}
+	return r.aliases[i]
+}
@@ -0,0 +1,187 @@
+// Package zip321 parses ZIP-321 payment request URIs with Juno addresses.
+package zip321
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/logic"
+	"github.com/Abdullah1738/juno-txbuild/internal/memo"
+)
+
+// Schemes accepted for payment URIs ("zcash:" for ZIP-321 compatibility).
+var Schemes = []string{"juno", "zcash"}
+
+// MaxIndex is the largest payment index (param.N) allowed by ZIP-321.
+const MaxIndex = 9999
+
+// Payment is one payment of a request.
+type Payment struct {
+	Index     int
+	Address   string
+	AmountZat uint64
+	// Decoded memo bytes (nil = none).
+	Memo    []byte
+	Label   string
+	Message string
+}
+
+// TxOutput returns the payment as a plan output.
+func (p Payment) TxOutput() types.TxOutput {
+	return types.TxOutput{
+		ToAddress: p.Address,
+		AmountZat: strconv.FormatUint(p.AmountZat, 10),
+		MemoHex:   hex.EncodeToString(p.Memo),
+	}
+}
+
+var (
+	amountRE = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,8})?$`)
+	indexRE  = regexp.MustCompile(`^[1-9][0-9]{0,3}$`)
+	// ZIP-321 paramname: ALPHA *( ALPHA / DIGIT / "+" / "-" ).
+	nameRE = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+-]*$`)
+)
+
+// Parse parses a payment request URI into payments ordered by index.
+func Parse(uri string) ([]Payment, error) {
+	uri = strings.TrimSpace(uri)
+	scheme, rest, ok := strings.Cut(uri, ":")
+	if !ok || !knownScheme(scheme) {
+		return nil, fmt.Errorf("zip321: scheme must be one of %s", strings.Join(Schemes, ", "))
+	}
+	path, query, _ := strings.Cut(rest, "?")
+	if strings.Contains(path, "/") || strings.Contains(rest, "#") {
+		return nil, errors.New("zip321: malformed uri")
+	}
+
+	byIndex := make(map[int]*Payment)
+	seen := make(map[string]bool)
+	payment := func(i int) *Payment {
+		p, ok := byIndex[i]
+		if !ok {
+			p = &Payment{Index: i}
+			byIndex[i] = p
+		}
+		return p
+	}
+
+	if path != "" {
+		addr, err := url.PathUnescape(path)
+		if err != nil {
+			return nil, errors.New("zip321: address: invalid percent-encoding")
+		}
+		payment(0).Address = addr
+		seen[rawKeyFor("address", 0)] = true
+	}
+
+	if query != "" {
+		for _, kv := range strings.Split(query, "&") {
+			rawKey, rawVal, hasVal := strings.Cut(kv, "=")
+			if !hasVal {
+				return nil, fmt.Errorf("zip321: parameter %q has no value", rawKey)
+			}
+			name, idx, err := splitParam(rawKey)
+			if err != nil {
+				return nil, err
+			}
+			if seen[rawKeyFor(name, idx)] {
+				return nil, fmt.Errorf("zip321: duplicate parameter %q", rawKey)
+			}
+			seen[rawKeyFor(name, idx)] = true
+
+			val, err := url.PathUnescape(rawVal)
+			if err != nil {
+				return nil, fmt.Errorf("zip321: %s: invalid percent-encoding", rawKey)
+			}
+
+			p := payment(idx)
+			switch name {
+			case "address":
+				if val == "" {
+					return nil, fmt.Errorf("zip321: %s: empty address", rawKey)
+				}
+				p.Address = val
+			case "amount":
+				if !amountRE.MatchString(val) {
+					return nil, fmt.Errorf("zip321: %s: invalid amount %q (want decimal JUNO with at most 8 decimals)", rawKey, val)
+				}
+				amt, err := logic.ParseZECToZat(val)
+				if err != nil {
+					return nil, fmt.Errorf("zip321: %s: invalid amount %q: %v", rawKey, val, err)
+				}
+				p.AmountZat = amt
+			case "memo":
+				b, err := base64.RawURLEncoding.DecodeString(val)
+				if err != nil {
+					return nil, fmt.Errorf("zip321: %s: invalid base64url memo", rawKey)
+				}
+				if err := memo.Validate(b); err != nil {
+					return nil, fmt.Errorf("zip321: %s: %v", rawKey, err)
+				}
+				p.Memo = b
+			case "label":
+				p.Label = val
+			case "message":
+				p.Message = val
+			default:
+				if strings.HasPrefix(name, "req-") {
+					return nil, fmt.Errorf("zip321: unsupported required parameter %q", rawKey)
+				}
+				// Unknown optional parameters are ignored.
+			}
+		}
+	}
+
+	if len(byIndex) == 0 {
+		return nil, errors.New("zip321: no payments")
+	}
+	out := make([]Payment, 0, len(byIndex))
+	for _, p := range byIndex {
+		if p.Address == "" {
+			return nil, fmt.Errorf("zip321: payment %d: address required", p.Index)
+		}
+		if p.AmountZat == 0 {
+			return nil, fmt.Errorf("zip321: payment %d: amount required", p.Index)
+		}
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out, nil
+}
+
+func knownScheme(s string) bool {
+	for _, k := range Schemes {
+		if strings.EqualFold(s, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitParam splits "name.N" into name and index (0 when unindexed).
+func splitParam(key string) (string, int, error) {
+	name, rawIdx, indexed := strings.Cut(key, ".")
+	if !nameRE.MatchString(name) {
+		return "", 0, fmt.Errorf("zip321: invalid parameter name %q", key)
+	}
+	if !indexed {
+		return name, 0, nil
+	}
+	if !indexRE.MatchString(rawIdx) {
+		return "", 0, fmt.Errorf("zip321: %s: invalid payment index (want 1-%d without leading zeros)", key, MaxIndex)
+	}
+	idx, _ := strconv.Atoi(rawIdx)
+	return name, idx, nil
+}
+
+func rawKeyFor(name string, idx int) string {
+	return name + "." + strconv.Itoa(idx)
+}
@@ -0,0 +1,73 @@
+package zip321
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse_SinglePayment(t *testing.T) {
+	got, err := Parse("juno:jtest1abc?amount=1.5&memo=VGhpcyBpcyBhIHNpbXBsZSBtZW1vLg&label=Rent%20%26%20fees&unknown=1")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("payments=%d", len(got))
+	}
+	p := got[0]
+	if p.Address != "jtest1abc" || p.AmountZat != 150_000_000 || string(p.Memo) != "This is a simple memo." || p.Label != "Rent & fees" {
+		t.Fatalf("unexpected payment: %+v", p)
+	}
+	out := p.TxOutput()
+	if out.AmountZat != "150000000" || out.MemoHex != "5468697320697320612073696d706c65206d656d6f2e" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+}
+
+func TestParse_MultiplePayments(t *testing.T) {
+	got, err := Parse("zcash:?address=j1a&amount=0.00000001&address.2=j1c&amount.2=3&address.1=j1b&amount.1=2&message.1=hi")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("payments=%d", len(got))
+	}
+	want := []struct {
+		addr string
+		amt  uint64
+	}{{"j1a", 1}, {"j1b", 200_000_000}, {"j1c", 300_000_000}}
+	for i, w := range want {
+		if got[i].Index != i || got[i].Address != w.addr || got[i].AmountZat != w.amt {
+			t.Fatalf("payment %d: %+v", i, got[i])
+		}
+	}
+	if got[1].Message != "hi" {
+		t.Fatalf("message=%q", got[1].Message)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for uri, want := range map[string]string{
+		"bitcoin:j1a?amount=1":                "scheme",
+		"juno:j1a":                            "payment 0: amount required",
+		"juno:?amount=1":                      "payment 0: address required",
+		"juno:j1a?address=j1b&amount=1":       "duplicate parameter \"address\"",
+		"juno:j1a?amount=1&amount=2":          "duplicate parameter",
+		"juno:j1a?amount=1.123456789":         "at most 8 decimals",
+		"juno:j1a?amount=-1":                  "invalid amount",
+		"juno:j1a?amount=1e3":                 "invalid amount",
+		"juno:j1a?amount=1&memo=***":          "invalid base64url memo",
+		"juno:j1a?amount=1&memo=9w":           "reserved",
+		"juno:j1a?amount=1&req-expiry=10":     "unsupported required parameter \"req-expiry\"",
+		"juno:j1a?amount=1&address.01=j1b":    "invalid payment index",
+		"juno:j1a?amount=1&address.10000=j1b": "invalid payment index",
+		"juno:j1a?amount":                     "has no value",
+		"juno:j1a?amount=1&label=%zz":         "invalid percent-encoding",
+		"juno:j1a?amount=1&address.1=j1b":     "payment 1: amount required",
+		"juno:j1a?amount=1&1abc=2":            "invalid parameter name",
+	} {
+		_, err := Parse(uri)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("Parse(%q)=%v want %q", uri, err, want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package plansig
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+)
+
+// ErrCodeInsufficientApprovals reports a plan approved by fewer trusted
+// approvers than its policy requires.
+const ErrCodeInsufficientApprovals types.ErrorCode = "insufficient_approvals"
+
+// Approvals file fields.
+const (
+	TypePlanApprovals = "juno-txplan-approvals"
+	ApprovalsVersion  = "v1"
+)
+
+// approvalDomain prefixes the signed plan ID, keeping approvals apart from
+// plan signatures by the same key.
+const approvalDomain = "juno-txplan-approval/v1\n"
+
+// Approvals collects the approvals of one plan.
+type Approvals struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	// Plan ID of the approved plan (see txbuild.PlanIDJSON).
+	PlanID    string     `json:"plan_id"`
+	Approvals []Approval `json:"approvals"`
+}
+
+// Approval is one approver's Ed25519 signature over the plan ID.
+type Approval struct {
+	Alg string `json:"alg"`
+	// Ed25519 public key and signature, lowercase hex.
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+	// RFC 3339, UTC. Informational; not signed.
+	ApprovedAt string `json:"approved_at"`
+}
+
+// NewApprovals returns an empty approvals file for planID.
+func NewApprovals(planID string) Approvals {
+	return Approvals{
+		Type:      TypePlanApprovals,
+		Version:   ApprovalsVersion,
+		PlanID:    planID,
+		Approvals: []Approval{},
+	}
+}
+
+// Approve adds signer's approval to a, replacing an earlier approval by the
+// same key.
+func Approve(a Approvals, signer crypto.Signer, now time.Time) (Approvals, error) {
+	if err := checkApprovalsHeader(a); err != nil {
+		return Approvals{}, err
+	}
+	pub, ok := signer.Public().(ed25519.PublicKey)
+	if !ok || len(pub) != ed25519.PublicKeySize {
+		return Approvals{}, errors.New("plansig: signing key is not an Ed25519 key")
+	}
+	msg := []byte(approvalDomain + a.PlanID)
+	sig, err := signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	if err != nil {
+		return Approvals{}, fmt.Errorf("plansig: sign: %w", err)
+	}
+	if !ed25519.Verify(pub, msg, sig) {
+		return Approvals{}, errors.New("plansig: signing key produced an invalid signature")
+	}
+	approval := Approval{
+		Alg:        AlgEd25519,
+		PublicKey:  hex.EncodeToString(pub),
+		Signature:  hex.EncodeToString(sig),
+		ApprovedAt: now.UTC().Format(time.RFC3339),
+	}
+
+	out := a
+	out.Approvals = make([]Approval, 0, len(a.Approvals)+1)
+	for _, x := range a.Approvals {
+		if x.PublicKey != approval.PublicKey {
+			out.Approvals = append(out.Approvals, x)
+		}
+	}
+	out.Approvals = append(out.Approvals, approval)
+	return out, nil
+}
+
+// Quorum is an M-of-N approval policy: at least Threshold of Approvers must
+// approve. A zero Threshold requires no approvals.
+type Quorum struct {
+	Threshold int
+	Approvers []TrustedKey
+}
+
+// CheckApprovals verifies every approval in a against planID and returns the
+// approvers of q that approved. Approvals by other keys are verified but not
+// counted. It fails with ErrCodeInvalidSignature on any bad approval and
+// with ErrCodeInsufficientApprovals below the threshold.
+func CheckApprovals(planID string, a Approvals, q Quorum) ([]TrustedKey, error) {
+	if err := checkApprovalsHeader(a); err != nil {
+		return nil, err
+	}
+	if a.PlanID != planID {
+		return nil, types.CodedError{Code: ErrCodeInvalidSignature, Message: fmt.Sprintf("approvals are for plan %s, not %s", a.PlanID, planID)}
+	}
+	msg := []byte(approvalDomain + planID)
+	var approved []TrustedKey
+	for i, x := range a.Approvals {
+		if x.Alg != AlgEd25519 {
+			return nil, types.CodedError{Code: ErrCodeInvalidSignature, Message: fmt.Sprintf("approvals[%d]: unsupported alg %q", i, x.Alg)}
+		}
+		pub, err := decodeHex(x.PublicKey, ed25519.PublicKeySize)
+		if err != nil {
+			return nil, types.CodedError{Code: ErrCodeInvalidSignature, Message: fmt.Sprintf("approvals[%d].public_key: %v", i, err)}
+		}
+		sig, err := decodeHex(x.Signature, ed25519.SignatureSize)
+		if err != nil {
+			return nil, types.CodedError{Code: ErrCodeInvalidSignature, Message: fmt.Sprintf("approvals[%d].signature: %v", i, err)}
+		}
+		if !ed25519.Verify(pub, msg, sig) {
+			return nil, types.CodedError{Code: ErrCodeInvalidSignature, Message: fmt.Sprintf("approvals[%d]: signature by %s does not match the plan", i, x.PublicKey)}
+		}
+		for _, k := range q.Approvers {
+			if k.PublicKey.Equal(ed25519.PublicKey(pub)) && !containsKey(approved, k) {
+				approved = append(approved, k)
+			}
+		}
+	}
+	if len(approved) < q.Threshold {
+		return approved, types.CodedError{Code: ErrCodeInsufficientApprovals, Message: fmt.Sprintf("plan %s has %d of %d required approvals", planID, len(approved), q.Threshold)}
+	}
+	return approved, nil
+}
+
+func checkApprovalsHeader(a Approvals) error {
+	if a.Type != TypePlanApprovals || a.Version != ApprovalsVersion {
+		return types.CodedError{Code: ErrCodeInvalidSignature, Message: fmt.Sprintf("unsupported approvals file (type %q, version %q)", a.Type, a.Version)}
+	}
+	if _, err := decodeHex(a.PlanID, 32); err != nil {
+		return types.CodedError{Code: ErrCodeInvalidSignature, Message: "plan_id: " + err.Error()}
+	}
+	return nil
+}
+
+func containsKey(keys []TrustedKey, k TrustedKey) bool {
+	for _, x := range keys {
+		if x.PublicKey.Equal(k.PublicKey) {
+			return true
+		}
+	}
+	return false
+}
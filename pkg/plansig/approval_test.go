@@ -0,0 +1,64 @@
+package plansig
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+)
+
+func TestApprovals(t *testing.T) {
+	planID := strings.Repeat("ab", 32)
+	alice, bob, carol, mallory := testKey(t, 1), testKey(t, 2), testKey(t, 3), testKey(t, 4)
+	trusted := func(k ed25519.PrivateKey, name string) TrustedKey {
+		return TrustedKey{PublicKey: k.Public().(ed25519.PublicKey), Name: name}
+	}
+	q := Quorum{Threshold: 2, Approvers: []TrustedKey{trusted(alice, "alice"), trusted(bob, "bob"), trusted(carol, "carol")}}
+
+	codeOf := func(err error) types.ErrorCode {
+		var ce types.CodedError
+		if !errors.As(err, &ce) {
+			t.Fatalf("expected a coded error, got %v", err)
+		}
+		return ce.Code
+	}
+
+	a := NewApprovals(planID)
+	var err error
+	for _, k := range []ed25519.PrivateKey{alice, mallory, alice} {
+		if a, err = Approve(a, k, time.Unix(0, 0)); err != nil {
+			t.Fatalf("Approve: %v", err)
+		}
+	}
+	if len(a.Approvals) != 2 {
+		t.Fatalf("re-approving should replace: %d approvals", len(a.Approvals))
+	}
+	// mallory is not an approver, and alice counts once.
+	if got, err := CheckApprovals(planID, a, q); codeOf(err) != ErrCodeInsufficientApprovals || len(got) != 1 {
+		t.Fatalf("1 of 2: %v, %v", got, err)
+	}
+
+	if a, err = Approve(a, carol, time.Unix(0, 0)); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	got, err := CheckApprovals(planID, a, q)
+	if err != nil || len(got) != 2 || got[0].Name != "alice" || got[1].Name != "carol" {
+		t.Fatalf("2 of 2: %+v, %v", got, err)
+	}
+
+	if _, err := CheckApprovals(strings.Repeat("cd", 32), a, q); codeOf(err) != ErrCodeInvalidSignature {
+		t.Fatalf("other plan: %v", err)
+	}
+	// An approval moved to another plan does not verify.
+	moved := NewApprovals(strings.Repeat("cd", 32))
+	moved.Approvals = a.Approvals
+	if _, err := CheckApprovals(moved.PlanID, moved, q); codeOf(err) != ErrCodeInvalidSignature {
+		t.Fatalf("moved approval: %v", err)
+	}
+	if _, err := CheckApprovals(planID, a, Quorum{}); err != nil {
+		t.Fatalf("zero threshold: %v", err)
+	}
+}
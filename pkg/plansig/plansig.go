@@ -0,0 +1,192 @@
+// Package plansig signs TxPlans with Ed25519 and verifies the signatures.
+//
+// A signature is a detached Envelope over the canonical plan (see
+// txbuild.CanonicalPlanJSON), so it stays valid across reformatting of the
+// plan file and breaks on any change to its content.
+package plansig
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/pkg/txbuild"
+)
+
+// Error codes for signatures that do not verify.
+const (
+	// The signature does not match the plan, or the envelope is malformed.
+	ErrCodeInvalidSignature types.ErrorCode = "invalid_signature"
+	// The signature is valid but its key is not pinned.
+	ErrCodeUntrustedKey types.ErrorCode = "untrusted_key"
+)
+
+// Envelope fields.
+const (
+	TypePlanSignature = "juno-txplan-signature"
+	EnvelopeVersion   = "v1"
+	AlgEd25519        = "ed25519"
+)
+
+// planDomain prefixes the signed message, so a plan signature cannot be
+// replayed as any other Ed25519 signature by the same key.
+const planDomain = "juno-txplan-signature/v1\n"
+
+// Envelope is a detached signature of a plan.
+type Envelope struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	Alg     string `json:"alg"`
+	// Plan ID of the signed plan (see txbuild.PlanIDJSON).
+	PlanID string `json:"plan_id"`
+	// Ed25519 public key and signature, lowercase hex.
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+	// RFC 3339, UTC. Informational; not signed.
+	SignedAt string `json:"signed_at"`
+}
+
+// Sign signs the TxPlan JSON in plan with signer, which must hold an Ed25519
+// key (an ed25519.PrivateKey or a PKCS#11 key).
+func Sign(plan []byte, signer crypto.Signer, now time.Time) (Envelope, error) {
+	pub, ok := signer.Public().(ed25519.PublicKey)
+	if !ok || len(pub) != ed25519.PublicKeySize {
+		return Envelope{}, errors.New("plansig: signing key is not an Ed25519 key")
+	}
+	canon, err := txbuild.CanonicalPlanJSON(plan)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("plansig: %w", err)
+	}
+	id, err := txbuild.PlanIDJSON(plan)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("plansig: %w", err)
+	}
+	sig, err := signer.Sign(rand.Reader, append([]byte(planDomain), canon...), crypto.Hash(0))
+	if err != nil {
+		return Envelope{}, fmt.Errorf("plansig: sign: %w", err)
+	}
+	if !ed25519.Verify(pub, append([]byte(planDomain), canon...), sig) {
+		return Envelope{}, errors.New("plansig: signing key produced an invalid signature")
+	}
+	return Envelope{
+		Type:      TypePlanSignature,
+		Version:   EnvelopeVersion,
+		Alg:       AlgEd25519,
+		PlanID:    id,
+		PublicKey: hex.EncodeToString(pub),
+		Signature: hex.EncodeToString(sig),
+		SignedAt:  now.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// Verify checks that env is a signature of the TxPlan JSON in plan by one of
+// the trusted keys, and returns that key.
+func Verify(plan []byte, env Envelope, trusted []TrustedKey) (TrustedKey, error) {
+	if env.Type != TypePlanSignature || env.Version != EnvelopeVersion || env.Alg != AlgEd25519 {
+		return TrustedKey{}, types.CodedError{Code: ErrCodeInvalidSignature, Message: fmt.Sprintf("unsupported signature envelope (type %q, version %q, alg %q)", env.Type, env.Version, env.Alg)}
+	}
+	pub, err := decodeHex(env.PublicKey, ed25519.PublicKeySize)
+	if err != nil {
+		return TrustedKey{}, types.CodedError{Code: ErrCodeInvalidSignature, Message: "public_key: " + err.Error()}
+	}
+	sig, err := decodeHex(env.Signature, ed25519.SignatureSize)
+	if err != nil {
+		return TrustedKey{}, types.CodedError{Code: ErrCodeInvalidSignature, Message: "signature: " + err.Error()}
+	}
+	canon, err := txbuild.CanonicalPlanJSON(plan)
+	if err != nil {
+		return TrustedKey{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "invalid txplan json: " + err.Error()}
+	}
+	id, err := txbuild.PlanIDJSON(plan)
+	if err != nil {
+		return TrustedKey{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "invalid txplan json: " + err.Error()}
+	}
+	if env.PlanID != id {
+		return TrustedKey{}, types.CodedError{Code: ErrCodeInvalidSignature, Message: fmt.Sprintf("signature is for plan %s, not %s", env.PlanID, id)}
+	}
+	if !ed25519.Verify(pub, append([]byte(planDomain), canon...), sig) {
+		return TrustedKey{}, types.CodedError{Code: ErrCodeInvalidSignature, Message: "signature does not match the plan"}
+	}
+	for _, k := range trusted {
+		if k.PublicKey.Equal(ed25519.PublicKey(pub)) {
+			return k, nil
+		}
+	}
+	return TrustedKey{}, types.CodedError{Code: ErrCodeUntrustedKey, Message: fmt.Sprintf("plan is signed by %s, which is not a trusted key", env.PublicKey)}
+}
+
+// TrustedKey is a pinned public key.
+type TrustedKey struct {
+	PublicKey ed25519.PublicKey
+	// Optional name from the key list.
+	Name string
+}
+
+// ParseTrustedKeys reads a pinned key list: one key per line as 64 hex
+// characters, optionally followed by whitespace and a name. Blank lines and
+// lines starting with # are ignored.
+func ParseTrustedKeys(r io.Reader) ([]TrustedKey, error) {
+	var keys []TrustedKey
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		pub, err := decodeHex(fields[0], ed25519.PublicKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("plansig: trusted keys line %d: %v", line, err)
+		}
+		keys = append(keys, TrustedKey{
+			PublicKey: ed25519.PublicKey(pub),
+			Name:      strings.Join(fields[1:], " "),
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("plansig: trusted keys: %v", err)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("plansig: trusted keys: no keys")
+	}
+	return keys, nil
+}
+
+// ParsePrivateKey parses a PEM "PRIVATE KEY" (PKCS #8) Ed25519 key, as
+// written by `openssl genpkey -algorithm ed25519`.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("plansig: key file is not a PEM PRIVATE KEY")
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("plansig: %v", err)
+	}
+	priv, ok := k.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("plansig: key file is not an Ed25519 key")
+	}
+	return priv, nil
+}
+
+func decodeHex(s string, size int) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, errors.New("invalid hex")
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("want %d bytes, got %d", size, len(b))
+	}
+	return b, nil
+}
@@ -0,0 +1,94 @@
+package plansig
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+)
+
+const testPlanJSON = `{"version":"v0","kind":"withdrawal","wallet_id":"hot","fee_zat":"10000","outputs":[{"to_address":"j1a","amount_zat":"100000"}],"notes":[]}`
+
+func testKey(t *testing.T, seed byte) ed25519.PrivateKey {
+	t.Helper()
+	return ed25519.NewKeyFromSeed([]byte(strings.Repeat(string(rune(seed)), ed25519.SeedSize)))
+}
+
+func TestSignVerify(t *testing.T) {
+	key := testKey(t, 1)
+	env, err := Sign([]byte(testPlanJSON), key, time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	trusted := []TrustedKey{{PublicKey: key.Public().(ed25519.PublicKey), Name: "builder-1"}}
+
+	// Reformatting the plan keeps the signature valid.
+	reformatted := strings.ReplaceAll(testPlanJSON, ",", ",\n  ")
+	got, err := Verify([]byte(reformatted), env, trusted)
+	if err != nil || got.Name != "builder-1" {
+		t.Fatalf("Verify: %+v, %v", got, err)
+	}
+
+	codeOf := func(err error) types.ErrorCode {
+		var ce types.CodedError
+		if !errors.As(err, &ce) {
+			t.Fatalf("expected a coded error, got %v", err)
+		}
+		return ce.Code
+	}
+
+	tampered := strings.Replace(testPlanJSON, `"100000"`, `"100001"`, 1)
+	if _, err := Verify([]byte(tampered), env, trusted); codeOf(err) != ErrCodeInvalidSignature {
+		t.Fatalf("tampered plan: %v", err)
+	}
+
+	wrongID := env
+	wrongID.PlanID = ""
+	other := testKey(t, 2)
+	if _, err := Verify([]byte(testPlanJSON), wrongID, trusted); codeOf(err) != ErrCodeInvalidSignature {
+		t.Fatalf("plan_id mismatch: %v", err)
+	}
+	if _, err := Verify([]byte(testPlanJSON), env, []TrustedKey{{PublicKey: other.Public().(ed25519.PublicKey)}}); codeOf(err) != ErrCodeUntrustedKey {
+		t.Fatalf("untrusted key: %v", err)
+	}
+
+	swapped := env
+	swapped.PublicKey = hex.EncodeToString(other.Public().(ed25519.PublicKey))
+	if _, err := Verify([]byte(testPlanJSON), swapped, append(trusted, TrustedKey{PublicKey: other.Public().(ed25519.PublicKey)})); codeOf(err) != ErrCodeInvalidSignature {
+		t.Fatalf("swapped key: %v", err)
+	}
+}
+
+func TestParseTrustedKeys(t *testing.T) {
+	pub := hex.EncodeToString(testKey(t, 1).Public().(ed25519.PublicKey))
+	keys, err := ParseTrustedKeys(strings.NewReader("# builders\n\n" + pub + "  builder 1\n"))
+	if err != nil || len(keys) != 1 || keys[0].Name != "builder 1" {
+		t.Fatalf("ParseTrustedKeys: %+v, %v", keys, err)
+	}
+	for _, bad := range []string{"", "# none\n", "abcd\n", pub[:62] + "zz\n"} {
+		if _, err := ParseTrustedKeys(strings.NewReader(bad)); err == nil {
+			t.Fatalf("ParseTrustedKeys(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := testKey(t, 3)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil || !got.Equal(key) {
+		t.Fatalf("ParsePrivateKey: %v", err)
+	}
+	if _, err := ParsePrivateKey([]byte("not a key")); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,376 @@
+package txbuild
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// The compact binary TxPlan encoding is CBOR (RFC 8949), prefixed with the
+// self-describe tag 55799, of a map with small integer keys. Hex fields are
+// byte strings, decimal amounts are integers and the Merkle path nodes of all
+// notes are stored once, in a table the notes index into. It decodes to the
+// same TxPlan (and plan_id) as the JSON it was encoded from.
+
+// cborFormatV1 is the version of the encoding (map key 0).
+const cborFormatV1 = 1
+
+// cborSelfDescribeTag (RFC 8949 section 3.4.6) starts every encoded plan, as
+// the bytes cborSelfDescribe.
+const cborSelfDescribeTag = 55799
+
+var cborSelfDescribe = []byte{0xd9, 0xd9, 0xf7}
+
+var (
+	cborEnc cbor.EncMode
+	cborDec cbor.DecMode
+)
+
+func init() {
+	var err error
+	if cborEnc, err = cbor.CoreDetEncOptions().EncMode(); err != nil {
+		panic(err)
+	}
+	if cborDec, err = (cbor.DecOptions{
+		DupMapKey:         cbor.DupMapKeyEnforcedAPF,
+		ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
+	}).DecMode(); err != nil {
+		panic(err)
+	}
+}
+
+type cborPlan struct {
+	Format         uint         `cbor:"0,keyasint"`
+	Version        string       `cbor:"1,keyasint"`
+	PlanID         hexString    `cbor:"2,keyasint,omitempty"`
+	Kind           string       `cbor:"3,keyasint"`
+	WalletID       string       `cbor:"4,keyasint"`
+	CoinType       uint32       `cbor:"5,keyasint"`
+	Account        uint32       `cbor:"6,keyasint"`
+	Chain          string       `cbor:"7,keyasint"`
+	BranchID       uint32       `cbor:"8,keyasint"`
+	AnchorHeight   uint32       `cbor:"9,keyasint"`
+	Anchor         hexString    `cbor:"10,keyasint"`
+	ExpiryHeight   uint32       `cbor:"11,keyasint"`
+	Outputs        []cborOutput `cbor:"12,keyasint"`
+	ChangeAddress  string       `cbor:"13,keyasint"`
+	FeeZat         decString    `cbor:"14,keyasint"`
+	Notes          []cborNote   `cbor:"15,keyasint"`
+	Metadata       string       `cbor:"16,keyasint,omitempty"` // compact JSON
+	OVKPolicy      hexString    `cbor:"17,keyasint,omitempty"`
+	IdempotencyKey string       `cbor:"18,keyasint,omitempty"`
+	FeePolicy      *FeePolicy   `cbor:"19,keyasint,omitempty"`
+	FeeAnalysis    *FeeAnalysis `cbor:"20,keyasint,omitempty"`
+	TotalInputZat  decString    `cbor:"21,keyasint,omitempty"`
+	ChangeZat      decString    `cbor:"22,keyasint,omitempty"`
+	TipHeight      uint32       `cbor:"23,keyasint,omitempty"`
+	TipHash        hexString    `cbor:"24,keyasint,omitempty"`
+	CreatedAt      string       `cbor:"25,keyasint,omitempty"`
+	ToolVersion    string       `cbor:"26,keyasint,omitempty"`
+	// Distinct Merkle path nodes of all notes.
+	PathNodes    []hexString       `cbor:"27,keyasint"`
+	TxCommitment *cborTxCommitment `cbor:"28,keyasint,omitempty"`
+}
+
+type cborTxCommitment struct {
+	Seed          hexString `cbor:"0,keyasint"`
+	HeaderDigest  hexString `cbor:"1,keyasint"`
+	OrchardDigest hexString `cbor:"2,keyasint"`
+	Sighash       hexString `cbor:"3,keyasint"`
+	TxID          hexString `cbor:"4,keyasint"`
+}
+
+type cborOutput struct {
+	ToAddress string    `cbor:"0,keyasint"`
+	AmountZat decString `cbor:"1,keyasint"`
+	MemoHex   hexString `cbor:"2,keyasint,omitempty"`
+	Label     string    `cbor:"3,keyasint,omitempty"`
+	RequestID string    `cbor:"4,keyasint,omitempty"`
+	Metadata  string    `cbor:"5,keyasint,omitempty"` // compact JSON
+}
+
+type cborNote struct {
+	NoteID          string    `cbor:"0,keyasint,omitempty"`
+	ActionNullifier hexString `cbor:"1,keyasint"`
+	CMX             hexString `cbor:"2,keyasint"`
+	Position        uint32    `cbor:"3,keyasint"`
+	// Indices into cborPlan.PathNodes.
+	Path          []uint32  `cbor:"4,keyasint"`
+	EphemeralKey  hexString `cbor:"5,keyasint"`
+	EncCiphertext hexString `cbor:"6,keyasint"`
+	ValueZat      decString `cbor:"7,keyasint,omitempty"`
+	Height        uint32    `cbor:"8,keyasint,omitempty"`
+	BlockHash     hexString `cbor:"9,keyasint,omitempty"`
+}
+
+// hexString is encoded as a byte string if it is lowercase hex, else as a
+// text string, so that any string decodes unchanged.
+type hexString string
+
+func (h hexString) MarshalCBOR() ([]byte, error) {
+	if b, err := hex.DecodeString(string(h)); err == nil && len(b) > 0 && hex.EncodeToString(b) == string(h) {
+		return cborEnc.Marshal(b)
+	}
+	return cborEnc.Marshal(string(h))
+}
+
+func (h *hexString) UnmarshalCBOR(data []byte) error {
+	var v any
+	if err := cborDec.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case []byte:
+		*h = hexString(hex.EncodeToString(v))
+	case string:
+		*h = hexString(v)
+	default:
+		return fmt.Errorf("want a byte or text string, got %T", v)
+	}
+	return nil
+}
+
+// decString is encoded as an unsigned integer if it is a canonical decimal,
+// else as a text string.
+type decString string
+
+func (d decString) MarshalCBOR() ([]byte, error) {
+	if v, err := strconv.ParseUint(string(d), 10, 64); err == nil && strconv.FormatUint(v, 10) == string(d) {
+		return cborEnc.Marshal(v)
+	}
+	return cborEnc.Marshal(string(d))
+}
+
+func (d *decString) UnmarshalCBOR(data []byte) error {
+	var v any
+	if err := cborDec.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case uint64:
+		*d = decString(strconv.FormatUint(v, 10))
+	case string:
+		*d = decString(v)
+	default:
+		return fmt.Errorf("want an unsigned integer or text string, got %T", v)
+	}
+	return nil
+}
+
+// IsPlanCBOR reports whether data starts like a CBOR-encoded plan.
+func IsPlanCBOR(data []byte) bool {
+	return bytes.HasPrefix(data, cborSelfDescribe)
+}
+
+// MarshalPlanCBOR encodes plan in the compact binary encoding. It fails if
+// the encoding would not decode to a plan with the same plan ID.
+func MarshalPlanCBOR(plan TxPlan) ([]byte, error) {
+	c := cborPlan{
+		Format:         cborFormatV1,
+		Version:        string(plan.Version),
+		PlanID:         hexString(plan.PlanID),
+		Kind:           string(plan.Kind),
+		WalletID:       plan.WalletID,
+		CoinType:       plan.CoinType,
+		Account:        plan.Account,
+		Chain:          plan.Chain,
+		BranchID:       plan.BranchID,
+		AnchorHeight:   plan.AnchorHeight,
+		Anchor:         hexString(plan.Anchor),
+		ExpiryHeight:   plan.ExpiryHeight,
+		Outputs:        make([]cborOutput, 0, len(plan.Outputs)),
+		ChangeAddress:  plan.ChangeAddress,
+		FeeZat:         decString(plan.FeeZat),
+		Notes:          make([]cborNote, 0, len(plan.Notes)),
+		OVKPolicy:      hexString(plan.OVKPolicy),
+		IdempotencyKey: plan.IdempotencyKey,
+		FeePolicy:      plan.FeePolicy,
+		FeeAnalysis:    plan.FeeAnalysis,
+		TotalInputZat:  decString(plan.TotalInputZat),
+		ChangeZat:      decString(plan.ChangeZat),
+		TipHeight:      plan.TipHeight,
+		TipHash:        hexString(plan.TipHash),
+		CreatedAt:      plan.CreatedAt,
+		ToolVersion:    plan.ToolVersion,
+		PathNodes:      []hexString{},
+	}
+	if t := plan.TxCommitment; t != nil {
+		c.TxCommitment = &cborTxCommitment{
+			Seed:          hexString(t.Seed),
+			HeaderDigest:  hexString(t.HeaderDigest),
+			OrchardDigest: hexString(t.OrchardDigest),
+			Sighash:       hexString(t.Sighash),
+			TxID:          hexString(t.TxID),
+		}
+	}
+	var err error
+	if c.Metadata, err = compactJSON(plan.Metadata); err != nil {
+		return nil, fmt.Errorf("txbuild: metadata: %w", err)
+	}
+	for i, o := range plan.Outputs {
+		co := cborOutput{
+			ToAddress: o.ToAddress,
+			AmountZat: decString(o.AmountZat),
+			MemoHex:   hexString(o.MemoHex),
+			Label:     o.Label,
+			RequestID: o.RequestID,
+		}
+		if co.Metadata, err = compactJSON(o.Metadata); err != nil {
+			return nil, fmt.Errorf("txbuild: outputs[%d].metadata: %w", i, err)
+		}
+		c.Outputs = append(c.Outputs, co)
+	}
+	nodes := map[string]uint32{}
+	for _, n := range plan.Notes {
+		cn := cborNote{
+			NoteID:          n.NoteID,
+			ActionNullifier: hexString(n.ActionNullifier),
+			CMX:             hexString(n.CMX),
+			Position:        n.Position,
+			Path:            make([]uint32, 0, len(n.Path)),
+			EphemeralKey:    hexString(n.EphemeralKey),
+			EncCiphertext:   hexString(n.EncCiphertext),
+			ValueZat:        decString(n.ValueZat),
+			Height:          n.Height,
+			BlockHash:       hexString(n.BlockHash),
+		}
+		for _, node := range n.Path {
+			idx, ok := nodes[node]
+			if !ok {
+				idx = uint32(len(c.PathNodes))
+				nodes[node] = idx
+				c.PathNodes = append(c.PathNodes, hexString(node))
+			}
+			cn.Path = append(cn.Path, idx)
+		}
+		c.Notes = append(c.Notes, cn)
+	}
+
+	b, err := cborEnc.Marshal(cbor.Tag{Number: cborSelfDescribeTag, Content: c})
+	if err != nil {
+		return nil, fmt.Errorf("txbuild: cbor: %w", err)
+	}
+
+	want, err := PlanID(plan)
+	if err != nil {
+		return nil, err
+	}
+	back, err := UnmarshalPlanCBOR(b)
+	if err != nil {
+		return nil, err
+	}
+	got, err := PlanID(back)
+	if err != nil {
+		return nil, err
+	}
+	if got != want {
+		return nil, errors.New("txbuild: plan does not round-trip through cbor")
+	}
+	return b, nil
+}
+
+// UnmarshalPlanCBOR decodes a plan encoded by MarshalPlanCBOR.
+func UnmarshalPlanCBOR(data []byte) (TxPlan, error) {
+	if !IsPlanCBOR(data) {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "invalid txplan cbor: missing self-describe tag"}
+	}
+	var c cborPlan
+	if err := cborDec.Unmarshal(data[len(cborSelfDescribe):], &c); err != nil {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "invalid txplan cbor: " + err.Error()}
+	}
+	if c.Format != cborFormatV1 {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("invalid txplan cbor: unsupported format %d", c.Format)}
+	}
+
+	plan := TxPlan{
+		Version:        types.Version(c.Version),
+		PlanID:         string(c.PlanID),
+		Kind:           types.TxPlanKind(c.Kind),
+		WalletID:       c.WalletID,
+		CoinType:       c.CoinType,
+		Account:        c.Account,
+		Chain:          c.Chain,
+		BranchID:       c.BranchID,
+		AnchorHeight:   c.AnchorHeight,
+		Anchor:         string(c.Anchor),
+		ExpiryHeight:   c.ExpiryHeight,
+		Outputs:        make([]TxOutput, 0, len(c.Outputs)),
+		ChangeAddress:  c.ChangeAddress,
+		FeeZat:         string(c.FeeZat),
+		Notes:          make([]SpendNote, 0, len(c.Notes)),
+		OVKPolicy:      string(c.OVKPolicy),
+		IdempotencyKey: c.IdempotencyKey,
+		FeePolicy:      c.FeePolicy,
+		FeeAnalysis:    c.FeeAnalysis,
+		TotalInputZat:  string(c.TotalInputZat),
+		ChangeZat:      string(c.ChangeZat),
+		TipHeight:      c.TipHeight,
+		TipHash:        string(c.TipHash),
+		CreatedAt:      c.CreatedAt,
+		ToolVersion:    c.ToolVersion,
+	}
+	if t := c.TxCommitment; t != nil {
+		plan.TxCommitment = &TxCommitment{
+			Seed:          string(t.Seed),
+			HeaderDigest:  string(t.HeaderDigest),
+			OrchardDigest: string(t.OrchardDigest),
+			Sighash:       string(t.Sighash),
+			TxID:          string(t.TxID),
+		}
+	}
+	if c.Metadata != "" {
+		plan.Metadata = json.RawMessage(c.Metadata)
+	}
+	for _, o := range c.Outputs {
+		out := TxOutput{
+			TxOutput:  types.TxOutput{ToAddress: o.ToAddress, AmountZat: string(o.AmountZat), MemoHex: string(o.MemoHex)},
+			Label:     o.Label,
+			RequestID: o.RequestID,
+		}
+		if o.Metadata != "" {
+			out.Metadata = json.RawMessage(o.Metadata)
+		}
+		plan.Outputs = append(plan.Outputs, out)
+	}
+	for i, n := range c.Notes {
+		path := make([]string, 0, len(n.Path))
+		for _, idx := range n.Path {
+			if int(idx) >= len(c.PathNodes) {
+				return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("invalid txplan cbor: notes[%d]: path node %d out of range", i, idx)}
+			}
+			path = append(path, string(c.PathNodes[idx]))
+		}
+		plan.Notes = append(plan.Notes, SpendNote{
+			OrchardSpendNote: types.OrchardSpendNote{
+				NoteID:          n.NoteID,
+				ActionNullifier: string(n.ActionNullifier),
+				CMX:             string(n.CMX),
+				Position:        n.Position,
+				Path:            path,
+				EphemeralKey:    string(n.EphemeralKey),
+				EncCiphertext:   string(n.EncCiphertext),
+			},
+			ValueZat:  string(n.ValueZat),
+			Height:    n.Height,
+			BlockHash: string(n.BlockHash),
+		})
+	}
+	return plan, nil
+}
+
+func compactJSON(m json.RawMessage) (string, error) {
+	if len(m) == 0 {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, m); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
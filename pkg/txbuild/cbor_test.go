@@ -0,0 +1,115 @@
+package txbuild
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+)
+
+// testSweepPlan returns a v1 plan spending n notes whose Merkle paths share
+// their upper levels, like notes of one wallet do.
+func testSweepPlan(n int) TxPlan {
+	plan := testPlanV1()
+	plan.Kind = types.TxPlanKindSweep
+	plan.Metadata = json.RawMessage(`{ "batch": 7, "big": 12345678901234567890 }`)
+	plan.Outputs[0].Label = "cold"
+	plan.Outputs[0].MemoHex = "f6"
+	plan.Outputs[0].Metadata = json.RawMessage(`["a", 1.5]`)
+	plan.OVKPolicy = "internal"
+	plan.FeePolicy = &FeePolicy{PerActionZat: "5000", Multiplier: 1, AddZat: "0"}
+	plan.TxCommitment = &TxCommitment{
+		Seed:          strings.Repeat("01", 32),
+		HeaderDigest:  strings.Repeat("02", 32),
+		OrchardDigest: strings.Repeat("03", 32),
+		Sighash:       strings.Repeat("a4", 32),
+		TxID:          strings.Repeat("a4", 32),
+	}
+	plan.Notes = nil
+	for i := 0; i < n; i++ {
+		path := make([]string, 32)
+		for level := range path {
+			// Level l is shared by notes with the same position >> l.
+			path[level] = fmt.Sprintf("%02x%062x", level, i>>level)
+		}
+		plan.Notes = append(plan.Notes, SpendNote{
+			OrchardSpendNote: types.OrchardSpendNote{
+				NoteID:          fmt.Sprintf("%064x:%d", i, i%2),
+				ActionNullifier: fmt.Sprintf("%064x", i+1),
+				CMX:             fmt.Sprintf("%064x", i+2),
+				Position:        uint32(i),
+				Path:            path,
+				EphemeralKey:    fmt.Sprintf("%064x", i+3),
+				EncCiphertext:   strings.Repeat(hex.EncodeToString([]byte{byte(i)}), 580),
+			},
+			ValueZat:  "1000",
+			Height:    uint32(10 + i),
+			BlockHash: strings.Repeat("cd", 32),
+		})
+	}
+	plan, err := plan.WithID()
+	if err != nil {
+		panic(err)
+	}
+	return plan
+}
+
+func TestPlanCBOR_RoundTrip(t *testing.T) {
+	plan := testSweepPlan(200)
+	b, err := MarshalPlanCBOR(plan)
+	if err != nil {
+		t.Fatalf("MarshalPlanCBOR: %v", err)
+	}
+	if !IsPlanCBOR(b) {
+		t.Fatalf("missing self-describe tag: %x", b[:8])
+	}
+	js, _ := json.Marshal(plan)
+	if len(b)*3 > len(js) {
+		t.Fatalf("cbor is %d bytes, json %d", len(b), len(js))
+	}
+
+	got, err := UnmarshalPlanCBOR(b)
+	if err != nil {
+		t.Fatalf("UnmarshalPlanCBOR: %v", err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	if string(gotJSON) != string(js) {
+		t.Fatalf("round trip changed the plan:\n%s\n%s", gotJSON, js)
+	}
+	if id, _ := PlanID(got); id != plan.PlanID {
+		t.Fatalf("plan_id %s, want %s", id, plan.PlanID)
+	}
+
+	// Non-canonical hex and decimals still round-trip, as text.
+	odd := testPlanV1()
+	odd.Anchor = "ABCD"
+	odd.Outputs[0].AmountZat = "0100000"
+	if _, err := MarshalPlanCBOR(odd); err != nil {
+		t.Fatalf("MarshalPlanCBOR: %v", err)
+	}
+	b, _ = MarshalPlanCBOR(odd)
+	back, _ := UnmarshalPlanCBOR(b)
+	if !reflect.DeepEqual(back, odd) {
+		t.Fatalf("round trip changed the plan:\n%+v\n%+v", back, odd)
+	}
+}
+
+func TestUnmarshalPlanCBOR_Rejects(t *testing.T) {
+	b, err := MarshalPlanCBOR(testPlanV1())
+	if err != nil {
+		t.Fatalf("MarshalPlanCBOR: %v", err)
+	}
+	for name, data := range map[string][]byte{
+		"json":      []byte(`{"version":"v0"}`),
+		"trailing":  append(append([]byte{}, b...), 0x00),
+		"truncated": b[:len(b)-1],
+	} {
+		if _, err := UnmarshalPlanCBOR(data); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
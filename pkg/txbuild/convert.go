@@ -0,0 +1,328 @@
+package txbuild
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Abdullah1738/juno-sdk-go/junocashd"
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/chain"
+	"github.com/Abdullah1738/juno-txbuild/internal/schema"
+)
+
+// ErrCodeLossyConversion is returned when converting a plan would drop fields
+// the target version cannot carry.
+const ErrCodeLossyConversion types.ErrorCode = "lossy_conversion"
+
+// ConvertConfig configures ConvertPlan. The node (and juno-scan, if set) are
+// only contacted to upgrade v0 plans.
+type ConvertConfig struct {
+	RPCURL  string
+	RPCUser string
+	RPCPass string
+
+	ScanURL         string
+	ScanBearerToken string
+
+	// Target version, "v0" or "v1".
+	Version string
+}
+
+// v1OnlyFields are the TxPlan v1 fields dropped by a downgrade to v0, as
+// JSON pointers with * for array indices. v0 signers derive the same values
+// from the notes, so nothing they enforce is lost.
+var v1OnlyFields = map[string]bool{
+	"/total_input_zat":    true,
+	"/change_zat":         true,
+	"/tip_height":         true,
+	"/tip_hash":           true,
+	"/created_at":         true,
+	"/tool_version":       true,
+	"/notes/*/value_zat":  true,
+	"/notes/*/height":     true,
+	"/notes/*/block_hash": true,
+}
+
+// ConvertPlan converts the TxPlan JSON in data to cfg.Version.
+//
+// A v0 plan is upgraded by reading the value, height and block of every note
+// from juno-scan (with ScanURL) or the node wallet; the notes must still be
+// unspent. The tip a v0 plan was built at is unknown, so the upgraded plan has
+// no tip_height or tip_hash. A v1 plan is downgraded by dropping the v1
+// fields.
+//
+// Fields of the source that the result would not carry (for example fields
+// added by a newer builder) fail with ErrCodeLossyConversion, as the signer
+// of the target version could not enforce them.
+func ConvertPlan(ctx context.Context, cfg ConvertConfig, data []byte) (TxPlan, error) {
+	target, err := ParsePlanVersion(cfg.Version)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	doc, err := schema.Decode(data)
+	if err != nil {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "invalid txplan json: " + err.Error()}
+	}
+	var plan TxPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "invalid txplan json"}
+	}
+	if plan.PlanID != "" {
+		id, err := PlanIDJSON(data)
+		if err != nil {
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "invalid txplan json: " + err.Error()}
+		}
+		if id != plan.PlanID {
+			return TxPlan{}, types.CodedError{Code: ErrCodeInvalidPlan, Message: fmt.Sprintf("txplan: plan_id %s does not match the plan (%s)", plan.PlanID, id)}
+		}
+	}
+	// Fields unknown to TxPlan are checked below, so the ID is recomputed
+	// over the fields it carries.
+	if plan, err = plan.WithID(); err != nil {
+		return TxPlan{}, err
+	}
+	if err := ValidatePlan(plan); err != nil {
+		return TxPlan{}, err
+	}
+
+	out := plan
+	allowed := map[string]bool{}
+	switch {
+	case plan.Version == target:
+	case target == types.V0:
+		if out, err = plan.V0().WithID(); err != nil {
+			return TxPlan{}, err
+		}
+		allowed = v1OnlyFields
+	default:
+		out, err = upgradePlan(ctx, cfg, plan)
+		if err != nil {
+			return TxPlan{}, err
+		}
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return TxPlan{}, errors.New("txbuild: marshal txplan")
+	}
+	converted, err := schema.Decode(b)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	var lost []string
+	for _, ptr := range droppedFields(doc, converted, "") {
+		if !allowed[fieldPattern(ptr)] {
+			lost = append(lost, ptr)
+		}
+	}
+	if len(lost) > 0 {
+		return TxPlan{}, types.CodedError{
+			Code:    ErrCodeLossyConversion,
+			Message: fmt.Sprintf("converting to %s would drop %s, which a %s signer cannot enforce", target, strings.Join(lost, ", "), target),
+		}
+	}
+	if err := ValidatePlan(out); err != nil {
+		return TxPlan{}, err
+	}
+	return out, nil
+}
+
+// upgradePlan turns a v0 plan into v1.
+func upgradePlan(ctx context.Context, cfg ConvertConfig, plan TxPlan) (TxPlan, error) {
+	cfg.RPCURL = strings.TrimSpace(cfg.RPCURL)
+	if cfg.RPCURL == "" {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "rpc url required to upgrade a v0 plan"}
+	}
+	rpc := junocashd.New(cfg.RPCURL, strings.TrimSpace(cfg.RPCUser), strings.TrimSpace(cfg.RPCPass))
+
+	chainInfo, err := chain.GetChainInfo(ctx, rpc)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	if !strings.EqualFold(strings.TrimSpace(chainInfo.Chain), strings.TrimSpace(plan.Chain)) {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("plan is for chain %q, node is on %q", plan.Chain, chainInfo.Chain)}
+	}
+
+	var heights map[string]noteInfo
+	if scanURL := strings.TrimSpace(cfg.ScanURL); scanURL != "" {
+		sc, err := newScanClient(scanURL, cfg.ScanBearerToken)
+		if err != nil {
+			return TxPlan{}, err
+		}
+		notes, err := listSpendableNotesFromScan(ctx, sc, plan.WalletID, chainInfo.Height, 1, 0)
+		if err != nil {
+			return TxPlan{}, err
+		}
+		heights = make(map[string]noteInfo, len(notes))
+		for _, n := range notes {
+			heights[fmt.Sprintf("%s:%d", n.TxID, n.ActionIndex)] = noteInfo{Height: n.Height, ValueZat: n.ValueZat}
+		}
+	} else {
+		heights, err = listOrchardNoteInfo(ctx, rpc, chainInfo.Height, plan.Account)
+		if err != nil {
+			return TxPlan{}, err
+		}
+	}
+
+	blockCache := make(map[int64]blockV2)
+	notes := make([]SpendNote, len(plan.Notes))
+	for i, n := range plan.Notes {
+		txid, idx, ok := parseNoteID(n.NoteID)
+		if !ok {
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("notes[%d]: note_id %q is not txid:action_index", i, n.NoteID)}
+		}
+		info, ok := heights[fmt.Sprintf("%s:%d", txid, idx)]
+		if !ok {
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeNotFound, Message: fmt.Sprintf("notes[%d]: note %s is not an unspent note of the wallet", i, n.NoteID)}
+		}
+		act, err := orchardActionForNote(ctx, rpc, blockCache, info.Height, txid, idx)
+		if err != nil {
+			return TxPlan{}, err
+		}
+		if act.CMX != strings.ToLower(n.CMX) || act.Nullifier != strings.ToLower(n.ActionNullifier) {
+			return TxPlan{}, types.CodedError{Code: ErrCodeInvalidPlan, Message: fmt.Sprintf("notes[%d]: cmx or nullifier does not match the chain", i)}
+		}
+		notes[i] = SpendNote{
+			OrchardSpendNote: n.OrchardSpendNote,
+			ValueZat:         strconv.FormatUint(info.ValueZat, 10),
+			Height:           act.Height,
+			BlockHash:        act.BlockHash,
+		}
+	}
+	plan.Notes = notes
+	// v0 plans may carry uppercase memo_hex; v1 requires lowercase.
+	outputs := make([]TxOutput, len(plan.Outputs))
+	for i, o := range plan.Outputs {
+		o.MemoHex = strings.ToLower(o.MemoHex)
+		outputs[i] = o
+	}
+	plan.Outputs = outputs
+	// v0 plans do not record the tip: plans built from juno-scan anchor
+	// below it, so the anchor height is not the tip either.
+	plan.TipHeight = 0
+	plan.TipHash = ""
+	return finishPlan(PlanVersionV1, plan)
+}
+
+// noteInfo is the value and block height of a wallet note.
+type noteInfo struct {
+	Height   int64
+	ValueZat uint64
+}
+
+// listOrchardNoteInfo returns the mined unspent Orchard notes of the node
+// wallet account by note ID (txid:action_index).
+func listOrchardNoteInfo(ctx context.Context, rpc *junocashd.Client, tipHeight int64, account uint32) (map[string]noteInfo, error) {
+	var raw []struct {
+		TxID          string      `json:"txid"`
+		Pool          string      `json:"pool"`
+		OutIndex      uint32      `json:"outindex"`
+		Confirmations int64       `json:"confirmations"`
+		Account       *uint32     `json:"account,omitempty"`
+		Amount        json.Number `json:"amount"`
+	}
+	if err := rpc.Call(ctx, "z_listunspent", []any{1, 9999999, true}, &raw); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]noteInfo, len(raw))
+	for _, n := range raw {
+		if strings.ToLower(strings.TrimSpace(n.Pool)) != "orchard" {
+			continue
+		}
+		if n.Account != nil && *n.Account != account {
+			continue
+		}
+		if n.Confirmations < 1 || n.Confirmations > tipHeight+1 {
+			continue
+		}
+		v, err := parseZECToZat(n.Amount.String())
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%s:%d", strings.ToLower(strings.TrimSpace(n.TxID)), n.OutIndex)
+		out[key] = noteInfo{Height: tipHeight - n.Confirmations + 1, ValueZat: v}
+	}
+	return out, nil
+}
+
+var noteIDRE = regexp.MustCompile(`^([0-9a-f]{64}):([0-9]+)$`)
+
+// parseNoteID splits a note ID of the form txid:action_index.
+func parseNoteID(id string) (string, uint32, bool) {
+	m := noteIDRE.FindStringSubmatch(id)
+	if m == nil {
+		return "", 0, false
+	}
+	idx, err := strconv.ParseUint(m[2], 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	return m[1], uint32(idx), true
+}
+
+// droppedFields returns JSON pointers to the non-empty object members of in
+// (decoded with schema.Decode) that are missing from out.
+func droppedFields(in, out any, ptr string) []string {
+	var lost []string
+	switch v := in.(type) {
+	case map[string]any:
+		o, _ := out.(map[string]any)
+		for k, iv := range v {
+			ov, ok := o[k]
+			if !ok {
+				if !isEmptyJSON(iv) {
+					lost = append(lost, ptr+"/"+k)
+				}
+				continue
+			}
+			lost = append(lost, droppedFields(iv, ov, ptr+"/"+k)...)
+		}
+	case []any:
+		o, _ := out.([]any)
+		for i, iv := range v {
+			if i < len(o) {
+				lost = append(lost, droppedFields(iv, o[i], ptr+"/"+strconv.Itoa(i))...)
+			}
+		}
+	}
+	sort.Strings(lost)
+	return lost
+}
+
+func isEmptyJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case json.Number:
+		return v == "0"
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
+
+var pointerIndexRE = regexp.MustCompile(`/[0-9]+(/|$)`)
+
+// fieldPattern replaces the array indices of a JSON pointer with *.
+func fieldPattern(ptr string) string {
+	for {
+		p := pointerIndexRE.ReplaceAllString(ptr, "/*$1")
+		if p == ptr {
+			return p
+		}
+		ptr = p
+	}
+}
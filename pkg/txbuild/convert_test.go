@@ -0,0 +1,92 @@
+package txbuild
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+)
+
+func TestConvertPlan_Downgrade(t *testing.T) {
+	b, err := json.Marshal(testPlanV1())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	v0, err := ConvertPlan(context.Background(), ConvertConfig{Version: "v0"}, b)
+	if err != nil {
+		t.Fatalf("ConvertPlan: %v", err)
+	}
+	if v0.Version != types.V0 || v0.ChangeZat != "" || v0.Notes[0].ValueZat != "" {
+		t.Fatalf("unexpected v0 plan: %+v", v0)
+	}
+
+	same, err := ConvertPlan(context.Background(), ConvertConfig{Version: "v1"}, b)
+	if err != nil || same.TipHash != testPlanV1().TipHash {
+		t.Fatalf("ConvertPlan to v1: %+v, %v", same, err)
+	}
+}
+
+func TestConvertPlan_RefusesLoss(t *testing.T) {
+	b, err := json.Marshal(testPlanV1())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	// A field from a newer builder, covered by the plan ID.
+	var doc map[string]any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	doc["spend_limit_zat"] = "5"
+	b, _ = json.Marshal(doc)
+	if doc["plan_id"], err = PlanIDJSON(b); err != nil {
+		t.Fatalf("PlanIDJSON: %v", err)
+	}
+	b, _ = json.Marshal(doc)
+
+	_, err = ConvertPlan(context.Background(), ConvertConfig{Version: "v0"}, b)
+	var ce types.CodedError
+	if !errors.As(err, &ce) || ce.Code != ErrCodeLossyConversion || !strings.Contains(ce.Message, "/spend_limit_zat") {
+		t.Fatalf("expected lossy_conversion for /spend_limit_zat, got %v", err)
+	}
+
+	plan := testPlanV1()
+	plan.ChangeZat = "1"
+	b, _ = json.Marshal(plan)
+	if _, err := ConvertPlan(context.Background(), ConvertConfig{Version: "v0"}, b); err == nil {
+		t.Fatalf("expected error converting a plan that fails the accounting check")
+	}
+}
+
+func TestConvertPlan_UpgradeNeedsRPC(t *testing.T) {
+	b, err := json.Marshal(testPlanV1().V0())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	_, err = ConvertPlan(context.Background(), ConvertConfig{Version: "v1"}, b)
+	var ce types.CodedError
+	if !errors.As(err, &ce) || ce.Code != types.ErrCodeInvalidRequest {
+		t.Fatalf("expected invalid_request, got %v", err)
+	}
+}
+
+func TestFieldPattern(t *testing.T) {
+	for in, want := range map[string]string{
+		"/notes/3/value_zat":    "/notes/*/value_zat",
+		"/outputs/0/metadata/1": "/outputs/*/metadata/*",
+		"/tip_hash":             "/tip_hash",
+	} {
+		if got := fieldPattern(in); got != want {
+			t.Fatalf("fieldPattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if _, _, ok := parseNoteID("ab:1"); ok {
+		t.Fatalf("parseNoteID accepted a short txid")
+	}
+	if txid, idx, ok := parseNoteID(strings.Repeat("ab", 32) + ":7"); !ok || idx != 7 || len(txid) != 64 {
+		t.Fatalf("parseNoteID failed")
+	}
+}
@@ -0,0 +1,92 @@
+package txbuild
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Abdullah1738/juno-sdk-go/junocashd"
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/chain"
+	"github.com/Abdullah1738/juno-txbuild/internal/logic"
+)
+
+// FeePriorityAuto selects a fee multiplier from EstimateFee instead of a
+// configured preset.
+const FeePriorityAuto = "auto"
+
+type EstimateFeeConfig struct {
+	RPCURL  string
+	RPCUser string
+	RPCPass string
+
+	// Recent blocks to sample (0 = 20).
+	Blocks int
+	// Blocks within which inclusion should be likely (0 = 3).
+	TargetBlocks int
+}
+
+type FeeEstimate struct {
+	Chain        string `json:"chain"`
+	TipHeight    int64  `json:"tip_height"`
+	Blocks       int    `json:"blocks"`
+	TargetBlocks int    `json:"target_blocks"`
+	MempoolTxs   int64  `json:"mempool_txs"`
+	MempoolBytes int64  `json:"mempool_bytes"`
+
+	logic.FeeEstimate
+}
+
+// EstimateFee samples recently mined blocks and the mempool and recommends a
+// ZIP-317 fee multiplier.
+func EstimateFee(ctx context.Context, cfg EstimateFeeConfig) (FeeEstimate, error) {
+	cfg.RPCURL = strings.TrimSpace(cfg.RPCURL)
+	cfg.RPCUser = strings.TrimSpace(cfg.RPCUser)
+	cfg.RPCPass = strings.TrimSpace(cfg.RPCPass)
+
+	if cfg.RPCURL == "" {
+		return FeeEstimate{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "rpc url required"}
+	}
+	if cfg.Blocks == 0 {
+		cfg.Blocks = 20
+	}
+	if cfg.TargetBlocks == 0 {
+		cfg.TargetBlocks = 3
+	}
+	if cfg.Blocks < 0 || cfg.Blocks > 1000 {
+		return FeeEstimate{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "blocks must be between 1 and 1000"}
+	}
+	if cfg.TargetBlocks < 0 {
+		return FeeEstimate{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "target_blocks must be > 0"}
+	}
+
+	rpc := junocashd.New(cfg.RPCURL, cfg.RPCUser, cfg.RPCPass)
+
+	chainInfo, err := chain.GetChainInfo(ctx, rpc)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+
+	blocks, err := chain.RecentBlockFees(ctx, rpc, chainInfo.Height, cfg.Blocks)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+	mempool, info, err := chain.MempoolFees(ctx, rpc)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+
+	est, err := logic.EstimateFeeMultiplier(blocks, mempool, int(info.Size), cfg.TargetBlocks)
+	if err != nil {
+		return FeeEstimate{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: err.Error()}
+	}
+
+	return FeeEstimate{
+		Chain:        chainInfo.Chain,
+		TipHeight:    chainInfo.Height,
+		Blocks:       len(blocks),
+		TargetBlocks: cfg.TargetBlocks,
+		MempoolTxs:   info.Size,
+		MempoolBytes: info.Bytes,
+		FeeEstimate:  est,
+	}, nil
+}
@@ -0,0 +1,413 @@
+package txbuild
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/api"
+	"github.com/Abdullah1738/juno-txbuild/internal/logic"
+	"github.com/Abdullah1738/juno-txbuild/internal/schema"
+)
+
+// TxPlan is the TxPlan produced by juno-txbuild.
+//
+// It carries every field of types.TxPlan (and decodes into it) plus optional
+// fields recorded by the builder. Signers that only know types.TxPlan ignore
+// the extra fields.
+type TxPlan struct {
+	Version types.Version `json:"version"` // v0 (default) or v1
+	// SHA-256 of the canonical plan (see PlanIDJSON).
+	PlanID        string           `json:"plan_id,omitempty"`
+	Kind          types.TxPlanKind `json:"kind"`
+	WalletID      string           `json:"wallet_id"`
+	CoinType      uint32           `json:"coin_type"`
+	Account       uint32           `json:"account"`
+	Chain         string           `json:"chain"`
+	BranchID      uint32           `json:"branch_id"`
+	AnchorHeight  uint32           `json:"anchor_height"`
+	Anchor        string           `json:"anchor"`
+	ExpiryHeight  uint32           `json:"expiry_height"`
+	Outputs       []TxOutput       `json:"outputs"`
+	ChangeAddress string           `json:"change_address"`
+	FeeZat        string           `json:"fee_zat"`
+	Notes         []SpendNote      `json:"notes"`
+	// Caller-provided metadata, echoed verbatim.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+
+	// Outgoing viewing key the signer encrypts outputs to; see ParseOVKPolicy.
+	// Omitted means the signer's default (sender).
+	OVKPolicy string `json:"ovk_policy,omitempty"`
+	// Key of the request in the idempotency store, if one was used.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	FeePolicy   *FeePolicy   `json:"fee_policy,omitempty"`
+	FeeAnalysis *FeeAnalysis `json:"fee_analysis,omitempty"`
+
+	// Commitment to the unsigned transaction, if one was requested.
+	TxCommitment *TxCommitment `json:"tx_commitment,omitempty"`
+
+	// TxPlan v1 accounting: total_input_zat = sum of note values =
+	// outputs + change_zat + fee_zat. Empty in v0 plans.
+	TotalInputZat string `json:"total_input_zat,omitempty"`
+	ChangeZat     string `json:"change_zat,omitempty"`
+	// TxPlan v1 provenance: the chain tip the plan was built at, when and by
+	// what. Empty in v0 plans.
+	TipHeight   uint32 `json:"tip_height,omitempty"`
+	TipHash     string `json:"tip_hash,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"` // RFC 3339, UTC
+	ToolVersion string `json:"tool_version,omitempty"`
+}
+
+// SpendNote is a note spent by a plan: the types.OrchardSpendNote fields plus
+// the note's value and block, which TxPlan v1 records.
+type SpendNote struct {
+	types.OrchardSpendNote
+	ValueZat  string `json:"value_zat,omitempty"`
+	Height    uint32 `json:"height,omitempty"`
+	BlockHash string `json:"block_hash,omitempty"`
+}
+
+// TxCommitment commits a plan to its unsigned v5 transaction: the Orchard
+// bundle built from the plan with all randomness drawn from a ChaCha20 RNG
+// seeded with Seed, without proofs and signatures. A signer that rebuilds the
+// transaction from the plan and Seed checks that it gets the same digests
+// before signing.
+type TxCommitment struct {
+	Seed string `json:"seed"` // 32 bytes, hex
+	// ZIP-244 digests of the unsigned transaction (hex).
+	HeaderDigest  string `json:"header_digest"`
+	OrchardDigest string `json:"orchard_digest"`
+	// ZIP-244 txid digest, which the spend authorizations sign (the plans
+	// have no transparent inputs).
+	Sighash string `json:"sighash"`
+	// Sighash byte-reversed, as the node displays the txid.
+	TxID string `json:"txid"`
+}
+
+// PlanVersionV1 is the TxPlan version with accounting and provenance fields
+// (api/txplan.v1.schema.json). types.V0 remains the default.
+const PlanVersionV1 types.Version = "v1"
+
+// ParsePlanVersion normalizes a plan version. "" means types.V0.
+func ParsePlanVersion(s string) (types.Version, error) {
+	switch v := types.Version(strings.ToLower(strings.TrimSpace(s))); v {
+	case "", types.V0:
+		return types.V0, nil
+	case PlanVersionV1:
+		return v, nil
+	}
+	return "", types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("unsupported plan version %q (want v0 or v1)", s)}
+}
+
+// V0 returns the plan without its v1 fields (and without its plan ID, which
+// changes with them).
+func (p TxPlan) V0() TxPlan {
+	p.Version = types.V0
+	p.PlanID = ""
+	p.TotalInputZat = ""
+	p.ChangeZat = ""
+	p.TipHeight = 0
+	p.TipHash = ""
+	p.CreatedAt = ""
+	p.ToolVersion = ""
+	notes := make([]SpendNote, len(p.Notes))
+	for i, n := range p.Notes {
+		notes[i] = SpendNote{OrchardSpendNote: n.OrchardSpendNote}
+	}
+	p.Notes = notes
+	return p
+}
+
+// Base returns the plan as types.TxPlan, without the fields juno-txbuild adds
+// to it.
+func (p TxPlan) Base() types.TxPlan {
+	out := types.TxPlan{
+		Version:       p.Version,
+		Kind:          p.Kind,
+		WalletID:      p.WalletID,
+		CoinType:      p.CoinType,
+		Account:       p.Account,
+		Chain:         p.Chain,
+		BranchID:      p.BranchID,
+		AnchorHeight:  p.AnchorHeight,
+		Anchor:        p.Anchor,
+		ExpiryHeight:  p.ExpiryHeight,
+		Outputs:       make([]types.TxOutput, len(p.Outputs)),
+		ChangeAddress: p.ChangeAddress,
+		FeeZat:        p.FeeZat,
+		Notes:         make([]types.OrchardSpendNote, len(p.Notes)),
+	}
+	for i, o := range p.Outputs {
+		out.Outputs[i] = o.TxOutput
+	}
+	for i, n := range p.Notes {
+		out.Notes[i] = n.OrchardSpendNote
+	}
+	if len(p.Metadata) > 0 {
+		out.Metadata = p.Metadata
+	}
+	return out
+}
+
+func basePlan(plan TxPlan, err error) (types.TxPlan, error) {
+	if err != nil {
+		return types.TxPlan{}, err
+	}
+	return plan.Base(), nil
+}
+
+// finishPlan completes a built plan as version: v1 plans get the accounting
+// and provenance fields, v0 plans have them removed. The tip is the one the
+// plan was built at, if known.
+func finishPlan(version types.Version, plan TxPlan) (TxPlan, error) {
+	if version != PlanVersionV1 {
+		return plan.V0().WithID()
+	}
+
+	var totalIn uint64
+	for i, n := range plan.Notes {
+		v, err := parseUint64Decimal(n.ValueZat)
+		if err != nil {
+			return TxPlan{}, fmt.Errorf("txbuild: notes[%d]: missing value", i)
+		}
+		var ok bool
+		if totalIn, ok = addUint64(totalIn, v); !ok {
+			return TxPlan{}, errors.New("txbuild: selected notes sum overflow")
+		}
+	}
+	spent, err := planSpentZat(plan)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	if spent > totalIn {
+		return TxPlan{}, errors.New("txbuild: outputs and fee exceed inputs")
+	}
+	if plan.TipHeight != 0 && plan.TipHash == "" {
+		return TxPlan{}, errors.New("txbuild: chain tip hash unknown")
+	}
+
+	plan.Version = PlanVersionV1
+	plan.TotalInputZat = strconv.FormatUint(totalIn, 10)
+	plan.ChangeZat = strconv.FormatUint(totalIn-spent, 10)
+	plan.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	plan.ToolVersion = toolVersion()
+	return plan.WithID()
+}
+
+// planSpentZat returns the sum of the plan's outputs and fee.
+func planSpentZat(plan TxPlan) (uint64, error) {
+	fee, err := parseUint64Decimal(plan.FeeZat)
+	if err != nil {
+		return 0, errors.New("txbuild: fee_zat invalid")
+	}
+	total := fee
+	for i, o := range plan.Outputs {
+		v, err := parseUint64Decimal(o.AmountZat)
+		if err != nil {
+			return 0, fmt.Errorf("txbuild: outputs[%d].amount_zat invalid", i)
+		}
+		var ok bool
+		if total, ok = addUint64(total, v); !ok {
+			return 0, errors.New("txbuild: outputs sum overflow")
+		}
+	}
+	return total, nil
+}
+
+// CheckAccounting checks the TxPlan v1 rule: the note values sum to
+// total_input_zat, which equals the outputs + change_zat + fee_zat.
+func CheckAccounting(plan TxPlan) error {
+	totalIn, err := parseUint64Decimal(plan.TotalInputZat)
+	if err != nil {
+		return errors.New("total_input_zat invalid")
+	}
+	change, err := parseUint64Decimal(plan.ChangeZat)
+	if err != nil {
+		return errors.New("change_zat invalid")
+	}
+	var notes uint64
+	for i, n := range plan.Notes {
+		v, err := parseUint64Decimal(n.ValueZat)
+		if err != nil {
+			return fmt.Errorf("notes[%d].value_zat invalid", i)
+		}
+		var ok bool
+		if notes, ok = addUint64(notes, v); !ok {
+			return errors.New("note values overflow")
+		}
+	}
+	if notes != totalIn {
+		return fmt.Errorf("note values sum to %d, total_input_zat is %d", notes, totalIn)
+	}
+	spent, err := planSpentZat(plan)
+	if err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "txbuild: "))
+	}
+	if sum, ok := addUint64(spent, change); !ok || sum != totalIn {
+		return fmt.Errorf("outputs + change_zat + fee_zat = %d, total_input_zat is %d", spent+change, totalIn)
+	}
+	return nil
+}
+
+// toolVersion identifies the builder in v1 plans, using the module version
+// from the build info.
+func toolVersion() string {
+	v := "(devel)"
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if bi.Main.Path == modulePath && bi.Main.Version != "" {
+			v = bi.Main.Version
+		}
+		for _, d := range bi.Deps {
+			if d.Path == modulePath {
+				v = d.Version
+			}
+		}
+	}
+	return "juno-txbuild/" + v
+}
+
+const modulePath = "github.com/Abdullah1738/juno-txbuild"
+
+// TxOutput is a plan output: the types.TxOutput fields plus optional caller
+// annotations. The annotations are echoed into the plan verbatim and take no
+// part in planning or signing.
+type TxOutput struct {
+	types.TxOutput
+	Label     string `json:"label,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// Arbitrary JSON value.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// validateMetadata checks that caller metadata, if set, is a single JSON
+// value.
+func validateMetadata(name string, m json.RawMessage) error {
+	if len(m) > 0 && !json.Valid(m) {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: name + " is not valid JSON"}
+	}
+	return nil
+}
+
+// FeePolicy records the fee policy a plan was built with.
+type FeePolicy struct {
+	// Fee priority preset name, if one was selected.
+	Priority     string `json:"priority,omitempty"`
+	PerActionZat string `json:"per_action_zat"`
+	Multiplier   uint64 `json:"multiplier"`
+	AddZat       string `json:"add_zat"`
+	CapZat       string `json:"cap_zat,omitempty"`
+	// Exact fee override; the other fields are then unused.
+	ExactZat string `json:"exact_zat,omitempty"`
+}
+
+// FeeAnalysis is the ZIP-317 analysis of a plan's fee.
+type FeeAnalysis struct {
+	LogicalActions     int    `json:"logical_actions"`
+	ConventionalFeeZat string `json:"conventional_fee_zat"`
+	// Logical actions not covered by the fee; see logic.UnpaidActions.
+	UnpaidActions int `json:"unpaid_actions"`
+	// "low" or "elevated"; see logic.InclusionRisk.
+	InclusionRisk string `json:"inclusion_risk"`
+}
+
+func appliedFeePolicy(priority string, p logic.FeePolicy) *FeePolicy {
+	perAction := p.PerActionZat
+	if perAction == 0 {
+		perAction = logic.MarginalFeeZat
+	}
+	mult := p.Multiplier
+	if mult == 0 {
+		mult = 1
+	}
+	out := &FeePolicy{
+		Priority:     priority,
+		PerActionZat: strconv.FormatUint(perAction, 10),
+		Multiplier:   mult,
+		AddZat:       strconv.FormatUint(p.AddZat, 10),
+	}
+	if p.CapZat > 0 {
+		out.CapZat = strconv.FormatUint(p.CapZat, 10)
+	}
+	if p.ExactZat != nil {
+		out.ExactZat = strconv.FormatUint(*p.ExactZat, 10)
+	}
+	return out
+}
+
+// OVK policies (ovk_policy). Besides these, a custom 32-byte outgoing viewing
+// key may be given as 64 hex characters.
+const (
+	// Outputs are recoverable with the spending account's external OVK.
+	OVKPolicySender = "sender"
+	// Outputs are recoverable with the account's internal OVK only.
+	OVKPolicyInternal = "internal"
+	// Outputs are not recoverable by the sender.
+	OVKPolicyNone = "none"
+)
+
+// ParseOVKPolicy normalizes an ovk_policy value. "" is returned unchanged.
+func ParseOVKPolicy(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", OVKPolicySender, OVKPolicyInternal, OVKPolicyNone:
+		return s, nil
+	}
+	if b, err := hex.DecodeString(s); err == nil && len(b) == 32 {
+		return s, nil
+	}
+	return "", errors.New("ovk_policy must be sender, internal, none or a 32-byte hex ovk")
+}
+
+// ErrCodeInvalidPlan is returned for a plan that does not validate against
+// the schema of its version (or, for v1, breaks the accounting rule).
+const ErrCodeInvalidPlan types.ErrorCode = "invalid_plan"
+
+// PlanSchema returns the api schema name of a plan version.
+func PlanSchema(version types.Version) (string, bool) {
+	switch version {
+	case types.V0:
+		return api.TxPlanV0Schema, true
+	case PlanVersionV1:
+		return api.TxPlanV1Schema, true
+	}
+	return "", false
+}
+
+// ValidatePlan checks plan against the schema of its version
+// (api/txplan.v0.schema.json or api/txplan.v1.schema.json), for v1 the
+// accounting rule, and the plan ID if set.
+func ValidatePlan(plan TxPlan) error {
+	name, ok := PlanSchema(plan.Version)
+	if !ok {
+		return types.CodedError{Code: ErrCodeInvalidPlan, Message: fmt.Sprintf("txplan: unsupported version %q", plan.Version)}
+	}
+	b, err := json.Marshal(plan)
+	if err != nil {
+		return types.CodedError{Code: ErrCodeInvalidPlan, Message: "marshal txplan"}
+	}
+	if err := schema.ValidateJSON(name, b); err != nil {
+		return types.CodedError{Code: ErrCodeInvalidPlan, Message: "txplan: " + err.Error()}
+	}
+	if plan.Version == PlanVersionV1 {
+		if err := CheckAccounting(plan); err != nil {
+			return types.CodedError{Code: ErrCodeInvalidPlan, Message: "txplan: " + err.Error()}
+		}
+	}
+	if plan.PlanID != "" {
+		id, err := PlanIDJSON(b)
+		if err != nil {
+			return types.CodedError{Code: ErrCodeInvalidPlan, Message: "txplan: " + err.Error()}
+		}
+		if id != plan.PlanID {
+			return types.CodedError{Code: ErrCodeInvalidPlan, Message: fmt.Sprintf("txplan: plan_id %s does not match the plan (%s)", plan.PlanID, id)}
+		}
+	}
+	return nil
+}
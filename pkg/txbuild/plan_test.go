@@ -0,0 +1,214 @@
+package txbuild
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Abdullah1738/juno-sdk-go/types"
+)
+
+func TestParseOVKPolicy(t *testing.T) {
+	custom := strings.Repeat("Ab", 32)
+	for in, want := range map[string]string{
+		"":         "",
+		" Sender ": OVKPolicySender,
+		"internal": OVKPolicyInternal,
+		"none":     OVKPolicyNone,
+		custom:     strings.ToLower(custom),
+	} {
+		got, err := ParseOVKPolicy(in)
+		if err != nil {
+			t.Fatalf("ParseOVKPolicy(%q): %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("ParseOVKPolicy(%q)=%q want %q", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"external", strings.Repeat("ab", 31), strings.Repeat("zz", 32)} {
+		if _, err := ParseOVKPolicy(in); err == nil {
+			t.Fatalf("ParseOVKPolicy(%q): expected error", in)
+		}
+	}
+}
+
+func TestTxPlan_Base(t *testing.T) {
+	plan := TxPlan{
+		Version: types.V0,
+		Kind:    types.TxPlanKindWithdrawal,
+		Outputs: []TxOutput{{
+			TxOutput: types.TxOutput{ToAddress: "j1a", AmountZat: "1000"},
+			Label:    "Alice",
+		}},
+		FeeZat:    "10000",
+		Notes:     []SpendNote{{OrchardSpendNote: types.OrchardSpendNote{ActionNullifier: "aa"}, ValueZat: "20000"}},
+		FeePolicy: &FeePolicy{PerActionZat: "5000", Multiplier: 1, AddZat: "0"},
+	}
+	base := plan.Base()
+	b, err := json.Marshal(base)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, unwanted := range []string{"label", "value_zat", "fee_policy", "metadata"} {
+		if strings.Contains(string(b), unwanted) {
+			t.Fatalf("base plan %s has %s", b, unwanted)
+		}
+	}
+	if base.Outputs[0].AmountZat != "1000" || base.Notes[0].ActionNullifier != "aa" || base.FeeZat != "10000" {
+		t.Fatalf("base plan %+v", base)
+	}
+}
+
+func TestTxPlan_EchoesAnnotations(t *testing.T) {
+	plan := TxPlan{
+		Version: types.V0,
+		Outputs: []TxOutput{{
+			TxOutput:  types.TxOutput{ToAddress: "j1a", AmountZat: "1000"},
+			Label:     "Alice",
+			RequestID: "w-1",
+			Metadata:  json.RawMessage(`{"ledger":12345678901234567890}`),
+		}},
+		Metadata: json.RawMessage(`["batch",7]`),
+	}
+	b, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, want := range []string{
+		`"label":"Alice"`,
+		`"request_id":"w-1"`,
+		`"metadata":{"ledger":12345678901234567890}`,
+		`"metadata":["batch",7]`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("plan json %s missing %s", b, want)
+		}
+	}
+
+	var sdk types.TxPlan
+	if err := json.Unmarshal(b, &sdk); err != nil {
+		t.Fatalf("decode as types.TxPlan: %v", err)
+	}
+	if sdk.Outputs[0].ToAddress != "j1a" || sdk.Outputs[0].AmountZat != "1000" {
+		t.Fatalf("unexpected sdk output: %+v", sdk.Outputs[0])
+	}
+
+	if err := validateMetadata("metadata", json.RawMessage(`{"a":`)); err == nil {
+		t.Fatalf("expected invalid metadata error")
+	}
+}
+
+func TestParsePlanVersion(t *testing.T) {
+	for in, want := range map[string]types.Version{
+		"":     types.V0,
+		"v0":   types.V0,
+		" V1 ": PlanVersionV1,
+	} {
+		got, err := ParsePlanVersion(in)
+		if err != nil || got != want {
+			t.Fatalf("ParsePlanVersion(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParsePlanVersion("v2"); err == nil {
+		t.Fatalf("expected error for v2")
+	}
+}
+
+func testPlanV1() TxPlan {
+	hash := strings.Repeat("ab", 32)
+	plan, err := TxPlan{
+		Version:       PlanVersionV1,
+		Kind:          types.TxPlanKindWithdrawal,
+		WalletID:      "hot",
+		Chain:         "regtest",
+		Outputs:       []TxOutput{{TxOutput: types.TxOutput{ToAddress: "j1a", AmountZat: "100000"}}},
+		ChangeAddress: "j1change",
+		FeeZat:        "10000",
+		Notes: []SpendNote{{
+			OrchardSpendNote: types.OrchardSpendNote{
+				ActionNullifier: "00",
+				CMX:             "00",
+				Path:            []string{},
+				EphemeralKey:    "00",
+				EncCiphertext:   "00",
+			},
+			ValueZat:  "150000",
+			Height:    90,
+			BlockHash: hash,
+		}},
+		TotalInputZat: "150000",
+		ChangeZat:     "40000",
+		TipHeight:     100,
+		TipHash:       hash,
+		CreatedAt:     "2026-01-02T03:04:05Z",
+		ToolVersion:   "juno-txbuild/v1.7.0",
+	}.WithID()
+	if err != nil {
+		panic(err)
+	}
+	return plan
+}
+
+func TestValidatePlan_V1(t *testing.T) {
+	plan := testPlanV1()
+	if err := ValidatePlan(plan); err != nil {
+		t.Fatalf("ValidatePlan: %v", err)
+	}
+
+	plan.ChangeZat = "40001"
+	err := ValidatePlan(plan)
+	var ce types.CodedError
+	if !errors.As(err, &ce) || ce.Code != ErrCodeInvalidPlan || !strings.Contains(ce.Message, "total_input_zat") {
+		t.Fatalf("expected accounting error, got %v", err)
+	}
+
+	plan = testPlanV1()
+	plan.Notes[0].ValueZat = "150001"
+	if err := ValidatePlan(plan); err == nil {
+		t.Fatalf("expected note value mismatch error")
+	}
+
+	plan = testPlanV1()
+	plan.TipHash = ""
+	if err := ValidatePlan(plan); err == nil {
+		t.Fatalf("expected schema error for missing tip_hash")
+	}
+
+	// Plans upgraded from v0 carry no tip at all.
+	plan = testPlanV1()
+	plan.TipHeight, plan.TipHash = 0, ""
+	if plan, err = plan.WithID(); err != nil {
+		t.Fatalf("WithID: %v", err)
+	}
+	if err := ValidatePlan(plan); err != nil {
+		t.Fatalf("ValidatePlan(no tip): %v", err)
+	}
+}
+
+func TestTxPlan_V0(t *testing.T) {
+	v0 := testPlanV1().V0()
+	if err := ValidatePlan(v0); err != nil {
+		t.Fatalf("ValidatePlan(v0): %v", err)
+	}
+	b, err := json.Marshal(v0)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	// v0 accepts memo_hex in either case, as plans from earlier versions may
+	// carry; v1 requires lowercase.
+	upper := testPlanV1()
+	upper.Outputs[0].MemoHex = "F6AB"
+	if v0, err := upper.V0().WithID(); err != nil || ValidatePlan(v0) != nil {
+		t.Fatalf("ValidatePlan(v0, uppercase memo): %v %v", err, ValidatePlan(v0))
+	}
+	if v1, err := upper.WithID(); err != nil || ValidatePlan(v1) == nil {
+		t.Fatalf("expected schema error for uppercase memo_hex in v1 (%v)", err)
+	}
+	for _, field := range []string{"total_input_zat", "change_zat", "tip_height", "tip_hash", "created_at", "tool_version", "value_zat", "block_hash"} {
+		if strings.Contains(string(b), `"`+field+`"`) {
+			t.Fatalf("v0 plan %s still has %s", b, field)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package txbuild
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/Abdullah1738/juno-txbuild/internal/jcs"
+	"github.com/Abdullah1738/juno-txbuild/internal/schema"
+)
+
+// CanonicalPlanJSON returns the RFC 8785 (JCS) canonical form of the TxPlan
+// JSON in data, without its plan_id member. It is the input of the plan ID
+// and of plan signatures, so every field of the file, known or not, counts.
+func CanonicalPlanJSON(data []byte) ([]byte, error) {
+	doc, err := schema.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, errors.New("txplan: not a JSON object")
+	}
+	delete(obj, "plan_id")
+	return jcs.Marshal(obj)
+}
+
+// PlanIDJSON returns the plan ID of the TxPlan JSON in data: the SHA-256 of
+// CanonicalPlanJSON, as lowercase hex.
+func PlanIDJSON(data []byte) (string, error) {
+	b, err := CanonicalPlanJSON(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// PlanID returns the plan ID of plan (see PlanIDJSON).
+func PlanID(plan TxPlan) (string, error) {
+	b, err := json.Marshal(plan)
+	if err != nil {
+		return "", errors.New("txbuild: marshal txplan")
+	}
+	return PlanIDJSON(b)
+}
+
+// WithID returns the plan with PlanID set. Call it after the last change to
+// the plan.
+func (p TxPlan) WithID() (TxPlan, error) {
+	id, err := PlanID(p)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	p.PlanID = id
+	return p, nil
+}
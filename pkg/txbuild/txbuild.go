@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,11 +16,86 @@ import (
 	"github.com/Abdullah1738/juno-sdk-go/junocashd"
 	"github.com/Abdullah1738/juno-sdk-go/junoscan"
 	"github.com/Abdullah1738/juno-sdk-go/types"
+	"github.com/Abdullah1738/juno-txbuild/internal/address"
 	"github.com/Abdullah1738/juno-txbuild/internal/chain"
+	"github.com/Abdullah1738/juno-txbuild/internal/keys"
 	"github.com/Abdullah1738/juno-txbuild/internal/logic"
+	"github.com/Abdullah1738/juno-txbuild/internal/memo"
 	"github.com/Abdullah1738/juno-txbuild/internal/witness"
 )
 
+// PlanOptions are the plan settings shared by SendConfig, PlanConfig,
+// SweepConfig and ConsolidateConfig.
+type PlanOptions struct {
+	// Unified full viewing key of the wallet. With it, an empty ChangeAddress
+	// is derived from the key's internal Orchard scope, and an explicit one
+	// must belong to the key.
+	UFVK string
+	// With UFVK: derive the change address at a random diversifier index
+	// instead of index 0, so every plan pays change to a fresh address.
+	FreshChangeAddress bool
+	// Outgoing viewing key policy for the signer (see ParseOVKPolicy).
+	// "" leaves the signer's default.
+	OVKPolicy string
+	// Plan metadata (any JSON value), echoed into the plan verbatim.
+	Metadata json.RawMessage
+	// TxPlan version to build, "v0" (default) or "v1" (see ParsePlanVersion).
+	PlanVersion string
+
+	// Marginal fee per logical action (0 = ZIP-317 conventional 5000).
+	FeePerActionZat uint64
+	// Caps the fee, but never below the ZIP-317 conventional fee (0 = no cap).
+	FeeCapZat uint64
+	// Exact fee in zatoshis, replacing the fee policy ("" = computed). It may
+	// be below the ZIP-317 conventional fee, but plans whose unpaid actions
+	// exceed logic.BlockUnpaidActionLimit are refused.
+	FeeZat string
+	// Hard limits on the final fee, checked after every fee adjustment
+	// (including dust change moved into the fee). A plan exceeding them fails
+	// with ErrCodeFeeLimitExceeded. 0 = no limit.
+	MaxFeeZat uint64
+	// Percentage of the amount paid to outputs (change excluded).
+	MaxFeePercent float64
+	// Name of the fee priority preset the fee fields came from (recorded in
+	// the plan only).
+	FeePriority string
+}
+
+// normalize trims the options and checks the OVK policy, metadata and fee
+// limits.
+func (o *PlanOptions) normalize() error {
+	o.UFVK = strings.TrimSpace(o.UFVK)
+	o.FeeZat = strings.TrimSpace(o.FeeZat)
+	ovkPolicy, err := ParseOVKPolicy(o.OVKPolicy)
+	if err != nil {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: err.Error()}
+	}
+	o.OVKPolicy = ovkPolicy
+	if err := validateMetadata("metadata", o.Metadata); err != nil {
+		return err
+	}
+	if err := o.feeLimits().Validate(); err != nil {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: err.Error()}
+	}
+	return nil
+}
+
+func (o PlanOptions) feeLimits() logic.FeeLimits {
+	return logic.FeeLimits{MaxZat: o.MaxFeeZat, MaxPercent: o.MaxFeePercent}
+}
+
+// feePolicy is the fee policy of the options with the config's multiplier and
+// flat addition.
+func (o PlanOptions) feePolicy(multiplier, addZat uint64) logic.FeePolicy {
+	return logic.FeePolicy{
+		Multiplier:   multiplier,
+		AddZat:       addZat,
+		PerActionZat: o.FeePerActionZat,
+		CapZat:       o.FeeCapZat,
+		ExactZat:     exactFee(o.FeeZat),
+	}
+}
+
 type SendConfig struct {
 	RPCURL  string
 	RPCUser string
@@ -35,8 +111,12 @@ type SendConfig struct {
 	Account  uint32
 
 	ToAddress string
+	// Decimal zatoshis, or "max" to send everything spendable (see ReserveZat).
 	AmountZat string
 	MemoHex   string
+	// Annotations of the output, echoed into the plan (see TxOutput).
+	Label     string
+	RequestID string
 
 	ChangeAddress string
 
@@ -47,10 +127,23 @@ type SendConfig struct {
 	FeeMultiplier uint64
 	FeeAddZat     uint64
 	MinChangeZat  uint64
+	// Deduct the fee from the output instead of adding it on top.
+	SubtractFee bool
+	// With AmountZat "max": zatoshis kept back as change.
+	ReserveZat uint64
+
+	PlanOptions
 }
 
+// PlanSend builds a withdrawal plan as types.TxPlan. BuildSend returns the
+// full plan, with the fields juno-txbuild adds to types.TxPlan.
 func PlanSend(ctx context.Context, cfg SendConfig) (types.TxPlan, error) {
-	return Plan(ctx, PlanConfig{
+	return basePlan(BuildSend(ctx, cfg))
+}
+
+// BuildSend builds a withdrawal plan paying cfg.AmountZat to cfg.ToAddress.
+func BuildSend(ctx context.Context, cfg SendConfig) (TxPlan, error) {
+	pcfg := PlanConfig{
 		RPCURL:  cfg.RPCURL,
 		RPCUser: cfg.RPCUser,
 		RPCPass: cfg.RPCPass,
@@ -63,9 +156,11 @@ func PlanSend(ctx context.Context, cfg SendConfig) (types.TxPlan, error) {
 		Account:  cfg.Account,
 
 		Kind: types.TxPlanKindWithdrawal,
-		Outputs: []types.TxOutput{
-			{ToAddress: cfg.ToAddress, AmountZat: cfg.AmountZat, MemoHex: cfg.MemoHex},
-		},
+		Outputs: []TxOutput{{
+			TxOutput:  types.TxOutput{ToAddress: cfg.ToAddress, AmountZat: cfg.AmountZat, MemoHex: cfg.MemoHex},
+			Label:     cfg.Label,
+			RequestID: cfg.RequestID,
+		}},
 		ChangeAddress: cfg.ChangeAddress,
 
 		MinConfirmations: cfg.MinConfirmations,
@@ -75,7 +170,14 @@ func PlanSend(ctx context.Context, cfg SendConfig) (types.TxPlan, error) {
 		FeeMultiplier: cfg.FeeMultiplier,
 		FeeAddZat:     cfg.FeeAddZat,
 		MinChangeZat:  cfg.MinChangeZat,
-	})
+		ReserveZat:    cfg.ReserveZat,
+
+		PlanOptions: cfg.PlanOptions,
+	}
+	if cfg.SubtractFee {
+		pcfg.SubtractFeeFrom = []int{0}
+	}
+	return Build(ctx, pcfg)
 }
 
 type PlanConfig struct {
@@ -93,7 +195,7 @@ type PlanConfig struct {
 	Account  uint32
 
 	Kind          types.TxPlanKind
-	Outputs       []types.TxOutput
+	Outputs       []TxOutput
 	ChangeAddress string
 
 	MinConfirmations int64
@@ -103,9 +205,39 @@ type PlanConfig struct {
 	FeeMultiplier uint64
 	FeeAddZat     uint64
 	MinChangeZat  uint64
+	// Indices of outputs that bear the fee. The fee is split across them in
+	// proportion to their amounts instead of being added on top.
+	SubtractFeeFrom []int
+	// Kept back as change when an output uses AmountZat "max". That output
+	// receives everything else spendable after fees.
+	ReserveZat uint64
+
+	PlanOptions
 }
 
+// AmountMax is the AmountZat value requesting the maximum spendable amount.
+const AmountMax = "max"
+
+// Plan builds a plan paying cfg.Outputs as types.TxPlan. Build returns the
+// full plan.
 func Plan(ctx context.Context, cfg PlanConfig) (types.TxPlan, error) {
+	return basePlan(Build(ctx, cfg))
+}
+
+// Build builds a plan paying cfg.Outputs.
+func Build(ctx context.Context, cfg PlanConfig) (TxPlan, error) {
+	version, err := ParsePlanVersion(cfg.PlanVersion)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	plan, err := draftPlan(ctx, cfg)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	return finishPlan(version, plan)
+}
+
+func draftPlan(ctx context.Context, cfg PlanConfig) (TxPlan, error) {
 	cfg.RPCURL = strings.TrimSpace(cfg.RPCURL)
 	cfg.RPCUser = strings.TrimSpace(cfg.RPCUser)
 	cfg.RPCPass = strings.TrimSpace(cfg.RPCPass)
@@ -115,21 +247,27 @@ func Plan(ctx context.Context, cfg PlanConfig) (types.TxPlan, error) {
 	cfg.ChangeAddress = strings.TrimSpace(cfg.ChangeAddress)
 
 	if cfg.RPCURL == "" {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "rpc url required"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "rpc url required"}
 	}
 	if cfg.WalletID == "" {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "wallet_id required"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "wallet_id required"}
 	}
 	switch cfg.Kind {
 	case types.TxPlanKindWithdrawal, types.TxPlanKindSweep, types.TxPlanKindRebalance:
 	default:
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "unsupported kind"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "unsupported kind"}
 	}
 	if len(cfg.Outputs) == 0 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "outputs required"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "outputs required"}
+	}
+	if err := cfg.PlanOptions.normalize(); err != nil {
+		return TxPlan{}, err
+	}
+	if cfg.ChangeAddress == "" && cfg.UFVK == "" {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "change_address required"}
 	}
-	if cfg.ChangeAddress == "" {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "change_address required"}
+	if err := validateChangeKey(cfg.UFVK, cfg.ChangeAddress, cfg.FreshChangeAddress); err != nil {
+		return TxPlan{}, err
 	}
 	if cfg.MinConfirmations <= 0 {
 		cfg.MinConfirmations = 1
@@ -138,39 +276,80 @@ func Plan(ctx context.Context, cfg PlanConfig) (types.TxPlan, error) {
 		cfg.ExpiryOffset = 40
 	}
 	if cfg.ExpiryOffset < 4 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "expiry_offset must be >= 4"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "expiry_offset must be >= 4"}
 	}
 	if cfg.FeeMultiplier == 0 {
 		cfg.FeeMultiplier = 1
 	}
+	if err := validateExactFee(cfg.FeeZat, cfg.FeeMultiplier, cfg.FeeAddZat, cfg.FeePerActionZat, cfg.FeeCapZat, cfg.MinChangeZat); err != nil {
+		return TxPlan{}, err
+	}
 
 	var totalOut uint64
+	maxIdx := -1
 	for i := range cfg.Outputs {
 		cfg.Outputs[i].ToAddress = strings.TrimSpace(cfg.Outputs[i].ToAddress)
 		cfg.Outputs[i].AmountZat = strings.TrimSpace(cfg.Outputs[i].AmountZat)
 		cfg.Outputs[i].MemoHex = strings.TrimSpace(cfg.Outputs[i].MemoHex)
 		if cfg.Outputs[i].ToAddress == "" {
-			return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].to_address required", i)}
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].to_address required", i)}
 		}
 		if cfg.Outputs[i].AmountZat == "" {
-			return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].amount_zat required", i)}
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].amount_zat required", i)}
+		}
+		if cfg.Outputs[i].MemoHex != "" {
+			m, err := memo.ParseHex(cfg.Outputs[i].MemoHex)
+			if err != nil {
+				return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].memo_hex: %v", i, err)}
+			}
+			cfg.Outputs[i].MemoHex = m
+		}
+		if err := validateMetadata(fmt.Sprintf("outputs[%d].metadata", i), cfg.Outputs[i].Metadata); err != nil {
+			return TxPlan{}, err
+		}
+		if strings.EqualFold(cfg.Outputs[i].AmountZat, AmountMax) {
+			if maxIdx >= 0 {
+				return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "only one output may use amount_zat max"}
+			}
+			maxIdx = i
+			cfg.Outputs[i].AmountZat = AmountMax
+			continue
 		}
 		amt, err := parseUint64Decimal(cfg.Outputs[i].AmountZat)
 		if err != nil || amt == 0 {
-			return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].amount_zat invalid", i)}
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].amount_zat invalid", i)}
 		}
 		var ok bool
 		totalOut, ok = addUint64(totalOut, amt)
 		if !ok {
-			return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "outputs sum overflow"}
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "outputs sum overflow"}
+		}
+	}
+	seenSubtract := make(map[int]struct{}, len(cfg.SubtractFeeFrom))
+	for _, idx := range cfg.SubtractFeeFrom {
+		if idx < 0 || idx >= len(cfg.Outputs) {
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("subtract_fee_from index %d out of range", idx)}
+		}
+		if _, ok := seenSubtract[idx]; ok {
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("subtract_fee_from index %d duplicated", idx)}
 		}
+		seenSubtract[idx] = struct{}{}
+	}
+	if maxIdx >= 0 && len(cfg.SubtractFeeFrom) > 0 {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "amount_zat max cannot be combined with subtract_fee_from"}
+	}
+	if maxIdx < 0 && cfg.ReserveZat > 0 {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "reserve_zat requires amount_zat max"}
+	}
+	if cfg.ReserveZat > 0 && cfg.ReserveZat < cfg.MinChangeZat {
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "reserve_zat must be >= min_change_zat"}
 	}
 
 	rpc := junocashd.New(cfg.RPCURL, cfg.RPCUser, cfg.RPCPass)
 
 	chainInfo, err := chain.GetChainInfo(ctx, rpc)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	coinType := cfg.CoinType
@@ -183,14 +362,22 @@ func Plan(ctx context.Context, cfg PlanConfig) (types.TxPlan, error) {
 		case "regtest":
 			coinType = 8135
 		default:
-			return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "unknown chain"}
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "unknown chain"}
 		}
 	}
+	addrs := make([]addressField, 0, len(cfg.Outputs))
+	for i, o := range cfg.Outputs {
+		addrs = append(addrs, addressField{Name: fmt.Sprintf("outputs[%d].to_address", i), Address: o.ToAddress})
+	}
+	cfg.ChangeAddress, err = resolveChangeAddress(chainInfo.Chain, addrs, cfg.ChangeAddress, cfg.UFVK, cfg.FreshChangeAddress)
+	if err != nil {
+		return TxPlan{}, err
+	}
 	if chainInfo.Height < 0 {
-		return types.TxPlan{}, errors.New("txbuild: invalid chain height")
+		return TxPlan{}, errors.New("txbuild: invalid chain height")
 	}
 	if chainInfo.Height > int64(^uint32(0)) {
-		return types.TxPlan{}, errors.New("txbuild: chain height too large")
+		return TxPlan{}, errors.New("txbuild: chain height too large")
 	}
 	anchorHeight := uint32(chainInfo.Height)
 
@@ -200,85 +387,76 @@ func Plan(ctx context.Context, cfg PlanConfig) (types.TxPlan, error) {
 
 	orchard, err := chain.BuildOrchardIndex(ctx, rpc, int64(anchorHeight))
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if len(orchard.CMXHex) == 0 {
-		return types.TxPlan{}, errors.New("txbuild: no orchard commitments")
+		return TxPlan{}, errors.New("txbuild: no orchard commitments")
 	}
 
 	notes, err := listUnspentOrchardNotes(ctx, rpc, cfg.MinConfirmations, cfg.Account)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	notes = logic.FilterNotesMinValue(notes, cfg.MinNoteZat)
 	if len(notes) == 0 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "no spendable notes"}
-	}
-
-	feePolicy := logic.FeePolicy{
-		Multiplier: cfg.FeeMultiplier,
-		AddZat:     cfg.FeeAddZat,
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "no spendable notes"}
 	}
 
-	selected, feeZat, err := logic.SelectNotesWithFeePolicy(notes, totalOut, len(cfg.Outputs), feePolicy)
+	selected, feeZat, outputs, err := selectNotesForOutputs(notes, cfg, totalOut)
 	if err != nil {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
+		return TxPlan{}, err
 	}
-
-	var totalIn uint64
-	for _, n := range selected {
-		var ok bool
-		totalIn, ok = addUint64(totalIn, n.ValueZat)
-		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: selected notes sum overflow")
-		}
-	}
-	feeZat, _, err = logic.SuppressDustChange(totalIn, totalOut, feeZat, cfg.MinChangeZat)
+	feeAnalysis, err := checkOutputFee(cfg, selected, feeZat, outputs)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	positions := make([]uint32, 0, len(selected))
-	planNotes := make([]types.OrchardSpendNote, 0, len(selected))
+	planNotes := make([]SpendNote, 0, len(selected))
 	for _, n := range selected {
 		key := fmt.Sprintf("%s:%d", n.TxID, n.ActionIndex)
 		act, ok := orchard.ByOutpoint[key]
 		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: missing orchard action for selected note")
-		}
-		planNotes = append(planNotes, types.OrchardSpendNote{
-			NoteID:          key,
-			ActionNullifier: act.Nullifier,
-			CMX:             act.CMX,
-			Position:        act.Position,
-			Path:            nil,
-			EphemeralKey:    act.EphemeralKey,
-			EncCiphertext:   act.EncCiphertext,
+			return TxPlan{}, errors.New("txbuild: missing orchard action for selected note")
+		}
+		planNotes = append(planNotes, SpendNote{
+			OrchardSpendNote: types.OrchardSpendNote{
+				NoteID:          key,
+				ActionNullifier: act.Nullifier,
+				CMX:             act.CMX,
+				Position:        act.Position,
+				Path:            nil,
+				EphemeralKey:    act.EphemeralKey,
+				EncCiphertext:   act.EncCiphertext,
+			},
+			ValueZat:  strconv.FormatUint(n.ValueZat, 10),
+			Height:    act.Height,
+			BlockHash: act.BlockHash,
 		})
 		positions = append(positions, act.Position)
 	}
 
 	wit, err := witness.OrchardWitness(orchard.CMXHex, positions)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if len(wit.Paths) != len(planNotes) {
-		return types.TxPlan{}, errors.New("txbuild: witness response mismatch")
+		return TxPlan{}, errors.New("txbuild: witness response mismatch")
 	}
 
 	for i := range planNotes {
 		if wit.Paths[i].Position != planNotes[i].Position {
-			return types.TxPlan{}, errors.New("txbuild: witness response mismatch")
+			return TxPlan{}, errors.New("txbuild: witness response mismatch")
 		}
 		planNotes[i].Path = wit.Paths[i].AuthPath
 	}
 
 	expiryHeight, err := logic.ExpiryHeightFromTip(anchorHeight, cfg.ExpiryOffset)
 	if err != nil {
-		return types.TxPlan{}, errors.New("txbuild: expiry height overflow")
+		return TxPlan{}, errors.New("txbuild: expiry height overflow")
 	}
 
-	plan := types.TxPlan{
+	plan := TxPlan{
 		Version:       types.V0,
 		Kind:          cfg.Kind,
 		WalletID:      cfg.WalletID,
@@ -287,16 +465,262 @@ func Plan(ctx context.Context, cfg PlanConfig) (types.TxPlan, error) {
 		Chain:         chainInfo.Chain,
 		BranchID:      chainInfo.BranchID,
 		AnchorHeight:  anchorHeight,
+		TipHeight:     uint32(chainInfo.Height),
+		TipHash:       chainInfo.BestBlockHash,
 		Anchor:        wit.Root,
 		ExpiryHeight:  expiryHeight,
-		Outputs:       cfg.Outputs,
+		Outputs:       outputs,
 		ChangeAddress: cfg.ChangeAddress,
 		FeeZat:        strconv.FormatUint(feeZat, 10),
 		Notes:         planNotes,
+		FeePolicy:     appliedFeePolicy(cfg.FeePriority, cfg.feePolicy(cfg.FeeMultiplier, cfg.FeeAddZat)),
+		FeeAnalysis:   feeAnalysis,
+		OVKPolicy:     cfg.OVKPolicy,
+		Metadata:      cfg.Metadata,
 	}
 	return plan, nil
 }
 
+// selectNotesForOutputs selects notes paying cfg.Outputs and returns the fee
+// and the outputs as they go into the plan. When cfg.SubtractFeeFrom is set,
+// the designated outputs are reduced by their share of the fee; an output with
+// AmountZat "max" is filled in from all notes.
+func selectNotesForOutputs(notes []logic.UnspentNote, cfg PlanConfig, totalOut uint64) ([]logic.UnspentNote, uint64, []TxOutput, error) {
+	feePolicy := cfg.feePolicy(cfg.FeeMultiplier, cfg.FeeAddZat)
+
+	if maxIdx := slices.IndexFunc(cfg.Outputs, func(o TxOutput) bool { return o.AmountZat == AmountMax }); maxIdx >= 0 {
+		// Spend every note; the max output takes what is left after the other
+		// outputs, the reserve and the fee.
+		amount, feeZat, err := logic.MaxSendable(notes, totalOut, cfg.ReserveZat, len(cfg.Outputs), feePolicy)
+		if err != nil {
+			return nil, 0, nil, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
+		}
+		outputs := append([]TxOutput(nil), cfg.Outputs...)
+		outputs[maxIdx].AmountZat = strconv.FormatUint(amount, 10)
+		return notes, feeZat, outputs, nil
+	}
+
+	if len(cfg.SubtractFeeFrom) == 0 {
+		selected, feeZat, err := logic.SelectNotesWithFeePolicy(notes, totalOut, len(cfg.Outputs), feePolicy)
+		if err != nil {
+			return nil, 0, nil, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
+		}
+		totalIn, err := sumNotes(selected)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		feeZat, _, err = logic.SuppressDustChange(totalIn, totalOut, feeZat, cfg.MinChangeZat)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		return selected, feeZat, cfg.Outputs, nil
+	}
+
+	selected, _, err := logic.SelectNotesFeeFromOutputs(notes, totalOut, len(cfg.Outputs), feePolicy)
+	if err != nil {
+		return nil, 0, nil, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
+	}
+	totalIn, err := sumNotes(selected)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	deductZat, feeZat, err := logic.FeeFromOutputs(totalIn, totalOut, len(selected), len(cfg.Outputs), feePolicy, cfg.MinChangeZat)
+	if err != nil {
+		return nil, 0, nil, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "fee exceeds outputs"}
+	}
+
+	amounts := make([]uint64, len(cfg.SubtractFeeFrom))
+	for i, idx := range cfg.SubtractFeeFrom {
+		amounts[i], err = parseUint64Decimal(cfg.Outputs[idx].AmountZat)
+		if err != nil {
+			return nil, 0, nil, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].amount_zat invalid", idx)}
+		}
+	}
+	shares, err := logic.SplitFee(amounts, deductZat)
+	if err != nil {
+		return nil, 0, nil, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "fee exceeds subtract_fee_from outputs"}
+	}
+
+	outputs := append([]TxOutput(nil), cfg.Outputs...)
+	for i, idx := range cfg.SubtractFeeFrom {
+		outputs[idx].AmountZat = strconv.FormatUint(amounts[i]-shares[i], 10)
+	}
+	return selected, feeZat, outputs, nil
+}
+
+type addressField struct {
+	Name    string
+	Address string
+}
+
+// validateAddresses checks that every address is a unified address for chain
+// with an Orchard receiver, reporting all invalid fields at once.
+func validateAddresses(chain string, fields []addressField) error {
+	var msgs []string
+	for _, f := range fields {
+		if err := address.ValidateOrchard(f.Address, chain); err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", f.Name, err))
+		}
+	}
+	if len(msgs) > 0 {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: strings.Join(msgs, "; ")}
+	}
+	return nil
+}
+
+func validateChangeKey(ufvk, changeAddr string, fresh bool) error {
+	if !fresh {
+		return nil
+	}
+	if ufvk == "" {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "fresh_change_address requires ufvk"}
+	}
+	if changeAddr != "" {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "fresh_change_address cannot be combined with change_address"}
+	}
+	return nil
+}
+
+// resolveChangeAddress derives the change address from ufvk when changeAddr
+// is empty, validates it together with fields, and checks that an explicit
+// changeAddr belongs to ufvk.
+func resolveChangeAddress(chain string, fields []addressField, changeAddr, ufvk string, fresh bool) (string, error) {
+	derived := changeAddr == ""
+	if derived {
+		var j keys.DiversifierIndex
+		if fresh {
+			var err error
+			if j, err = keys.RandomDiversifierIndex(); err != nil {
+				return "", fmt.Errorf("txbuild: diversifier index: %w", err)
+			}
+		}
+		addr, err := keys.ChangeAddress(ufvk, chain, j)
+		if err != nil {
+			return "", types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("ufvk: %v", err)}
+		}
+		changeAddr = addr
+	}
+
+	fields = append(fields, addressField{Name: "change_address", Address: changeAddr})
+	if err := validateAddresses(chain, fields); err != nil {
+		return "", err
+	}
+	if ufvk == "" || derived {
+		return changeAddr, nil
+	}
+
+	scope, err := keys.AddressScope(ufvk, changeAddr, chain)
+	if err != nil {
+		return "", types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("ufvk: %v", err)}
+	}
+	if scope == "" {
+		return "", types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "change_address does not belong to ufvk"}
+	}
+	return changeAddr, nil
+}
+
+// ErrCodeFeeLimitExceeded is returned instead of a plan whose fee exceeds
+// MaxFeeZat or MaxFeePercent.
+const ErrCodeFeeLimitExceeded types.ErrorCode = "fee_limit_exceeded"
+
+func checkFeeLimits(limits logic.FeeLimits, feeZat, amountZat uint64) error {
+	if err := limits.Check(feeZat, amountZat); err != nil {
+		return types.CodedError{Code: ErrCodeFeeLimitExceeded, Message: err.Error()}
+	}
+	return nil
+}
+
+// checkOutputFee checks the final fee of a plan paying outputs from selected
+// against the fee limits and the ZIP-317 unpaid action limit.
+func checkOutputFee(cfg PlanConfig, selected []logic.UnspentNote, feeZat uint64, outputs []TxOutput) (*FeeAnalysis, error) {
+	var amount uint64
+	for i, o := range outputs {
+		v, err := parseUint64Decimal(o.AmountZat)
+		if err != nil {
+			return nil, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("outputs[%d].amount_zat invalid", i)}
+		}
+		var ok bool
+		amount, ok = addUint64(amount, v)
+		if !ok {
+			return nil, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "outputs sum overflow"}
+		}
+	}
+	if err := checkFeeLimits(cfg.feeLimits(), feeZat, amount); err != nil {
+		return nil, err
+	}
+
+	totalIn, err := sumNotes(selected)
+	if err != nil {
+		return nil, err
+	}
+	outputCount := len(outputs)
+	if spent, ok := addUint64(amount, feeZat); ok && totalIn > spent {
+		outputCount++ // change
+	}
+	return analyzeFee(len(selected), outputCount, feeZat)
+}
+
+// analyzeFee computes the ZIP-317 unpaid actions of a transaction and refuses
+// fees that leave more than logic.BlockUnpaidActionLimit of them.
+func analyzeFee(spendCount, outputCount int, feeZat uint64) (*FeeAnalysis, error) {
+	actions := logic.LogicalActions(spendCount, outputCount)
+	unpaid := logic.UnpaidActions(feeZat, actions)
+	if unpaid > logic.BlockUnpaidActionLimit {
+		return nil, types.CodedError{
+			Code:    types.ErrCodeInvalidRequest,
+			Message: fmt.Sprintf("fee %d leaves %d unpaid ZIP-317 actions (block limit %d); the transaction would never be mined", feeZat, unpaid, logic.BlockUnpaidActionLimit),
+		}
+	}
+	return &FeeAnalysis{
+		LogicalActions:     actions,
+		ConventionalFeeZat: strconv.FormatUint(logic.RequiredFeeSend(spendCount, outputCount), 10),
+		UnpaidActions:      unpaid,
+		InclusionRisk:      logic.InclusionRisk(unpaid),
+	}, nil
+}
+
+// exactFee returns the parsed exact fee override, or nil. s must have been
+// checked by validateExactFee.
+func exactFee(s string) *uint64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	v, err := parseUint64Decimal(s)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func validateExactFee(feeZat string, multiplier, addZat, perActionZat, capZat, minChangeZat uint64) error {
+	if feeZat == "" {
+		return nil
+	}
+	if _, err := parseUint64Decimal(feeZat); err != nil {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "fee_zat invalid"}
+	}
+	if multiplier > 1 || addZat > 0 || perActionZat > 0 || capZat > 0 {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "fee_zat cannot be combined with a fee policy (multiplier, add, per-action, cap)"}
+	}
+	if minChangeZat > 0 {
+		return types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "fee_zat cannot be combined with min_change_zat"}
+	}
+	return nil
+}
+
+func sumNotes(notes []logic.UnspentNote) (uint64, error) {
+	var total uint64
+	for _, n := range notes {
+		var ok bool
+		total, ok = addUint64(total, n.ValueZat)
+		if !ok {
+			return 0, errors.New("txbuild: selected notes sum overflow")
+		}
+	}
+	return total, nil
+}
+
 type SweepConfig struct {
 	RPCURL  string
 	RPCUser string
@@ -311,8 +735,12 @@ type SweepConfig struct {
 	CoinType uint32
 	Account  uint32
 
-	ToAddress     string
-	MemoHex       string
+	ToAddress string
+	MemoHex   string
+	// Annotations of the output, echoed into the plan (see TxOutput).
+	Label     string
+	RequestID string
+
 	ChangeAddress string
 
 	MinConfirmations int64
@@ -321,9 +749,30 @@ type SweepConfig struct {
 
 	FeeMultiplier uint64
 	FeeAddZat     uint64
+
+	PlanOptions
 }
 
+// PlanSweep builds a sweep plan as types.TxPlan. BuildSweep returns the full
+// plan.
 func PlanSweep(ctx context.Context, cfg SweepConfig) (types.TxPlan, error) {
+	return basePlan(BuildSweep(ctx, cfg))
+}
+
+// BuildSweep builds a plan spending every eligible note to cfg.ToAddress.
+func BuildSweep(ctx context.Context, cfg SweepConfig) (TxPlan, error) {
+	version, err := ParsePlanVersion(cfg.PlanVersion)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	plan, err := draftSweep(ctx, cfg)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	return finishPlan(version, plan)
+}
+
+func draftSweep(ctx context.Context, cfg SweepConfig) (TxPlan, error) {
 	cfg.RPCURL = strings.TrimSpace(cfg.RPCURL)
 	cfg.RPCUser = strings.TrimSpace(cfg.RPCUser)
 	cfg.RPCPass = strings.TrimSpace(cfg.RPCPass)
@@ -335,15 +784,28 @@ func PlanSweep(ctx context.Context, cfg SweepConfig) (types.TxPlan, error) {
 	cfg.ChangeAddress = strings.TrimSpace(cfg.ChangeAddress)
 
 	if cfg.RPCURL == "" {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "rpc url required"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "rpc url required"}
 	}
 	if cfg.WalletID == "" {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "wallet_id required"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "wallet_id required"}
 	}
 	if cfg.ToAddress == "" {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "to required"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "to required"}
 	}
-	if cfg.ChangeAddress == "" {
+	if cfg.MemoHex != "" {
+		m, err := memo.ParseHex(cfg.MemoHex)
+		if err != nil {
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("memo_hex: %v", err)}
+		}
+		cfg.MemoHex = m
+	}
+	if err := cfg.PlanOptions.normalize(); err != nil {
+		return TxPlan{}, err
+	}
+	if err := validateChangeKey(cfg.UFVK, cfg.ChangeAddress, cfg.FreshChangeAddress); err != nil {
+		return TxPlan{}, err
+	}
+	if cfg.ChangeAddress == "" && cfg.UFVK == "" {
 		cfg.ChangeAddress = cfg.ToAddress
 	}
 	if cfg.MinConfirmations <= 0 {
@@ -353,17 +815,20 @@ func PlanSweep(ctx context.Context, cfg SweepConfig) (types.TxPlan, error) {
 		cfg.ExpiryOffset = 40
 	}
 	if cfg.ExpiryOffset < 4 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "expiry_offset must be >= 4"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "expiry_offset must be >= 4"}
 	}
 	if cfg.FeeMultiplier == 0 {
 		cfg.FeeMultiplier = 1
 	}
+	if err := validateExactFee(cfg.FeeZat, cfg.FeeMultiplier, cfg.FeeAddZat, cfg.FeePerActionZat, cfg.FeeCapZat, 0); err != nil {
+		return TxPlan{}, err
+	}
 
 	rpc := junocashd.New(cfg.RPCURL, cfg.RPCUser, cfg.RPCPass)
 
 	chainInfo, err := chain.GetChainInfo(ctx, rpc)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	coinType := cfg.CoinType
@@ -376,14 +841,20 @@ func PlanSweep(ctx context.Context, cfg SweepConfig) (types.TxPlan, error) {
 		case "regtest":
 			coinType = 8135
 		default:
-			return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "unknown chain"}
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "unknown chain"}
 		}
 	}
+	cfg.ChangeAddress, err = resolveChangeAddress(chainInfo.Chain, []addressField{
+		{Name: "to_address", Address: cfg.ToAddress},
+	}, cfg.ChangeAddress, cfg.UFVK, cfg.FreshChangeAddress)
+	if err != nil {
+		return TxPlan{}, err
+	}
 	if chainInfo.Height < 0 {
-		return types.TxPlan{}, errors.New("txbuild: invalid chain height")
+		return TxPlan{}, errors.New("txbuild: invalid chain height")
 	}
 	if chainInfo.Height > int64(^uint32(0)) {
-		return types.TxPlan{}, errors.New("txbuild: chain height too large")
+		return TxPlan{}, errors.New("txbuild: chain height too large")
 	}
 	anchorHeight := uint32(chainInfo.Height)
 
@@ -393,19 +864,19 @@ func PlanSweep(ctx context.Context, cfg SweepConfig) (types.TxPlan, error) {
 
 	orchard, err := chain.BuildOrchardIndex(ctx, rpc, int64(anchorHeight))
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if len(orchard.CMXHex) == 0 {
-		return types.TxPlan{}, errors.New("txbuild: no orchard commitments")
+		return TxPlan{}, errors.New("txbuild: no orchard commitments")
 	}
 
 	notes, err := listUnspentOrchardNotes(ctx, rpc, cfg.MinConfirmations, cfg.Account)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	notes = logic.FilterNotesMinValue(notes, cfg.MinNoteZat)
 	if len(notes) == 0 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "no spendable notes"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "no spendable notes"}
 	}
 
 	var totalIn uint64
@@ -413,64 +884,72 @@ func PlanSweep(ctx context.Context, cfg SweepConfig) (types.TxPlan, error) {
 		var ok bool
 		totalIn, ok = addUint64(totalIn, n.ValueZat)
 		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: notes sum overflow")
+			return TxPlan{}, errors.New("txbuild: notes sum overflow")
 		}
 	}
-	feePolicy := logic.FeePolicy{
-		Multiplier: cfg.FeeMultiplier,
-		AddZat:     cfg.FeeAddZat,
+	feePolicy := cfg.feePolicy(cfg.FeeMultiplier, cfg.FeeAddZat)
+	feeZat, err := feePolicy.Fee(len(notes), 1)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	if err := checkFeeLimits(cfg.feeLimits(), feeZat, totalIn-min(feeZat, totalIn)); err != nil {
+		return TxPlan{}, err
 	}
-	feeMin := logic.RequiredFeeSend(len(notes), 1)
-	feeZat, err := feePolicy.Apply(feeMin)
+	feeAnalysis, err := analyzeFee(len(notes), 1, feeZat)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if totalIn <= feeZat {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
 	}
 	amount := totalIn - feeZat
 
 	positions := make([]uint32, 0, len(notes))
-	planNotes := make([]types.OrchardSpendNote, 0, len(notes))
+	planNotes := make([]SpendNote, 0, len(notes))
 	for _, n := range notes {
 		key := fmt.Sprintf("%s:%d", n.TxID, n.ActionIndex)
 		act, ok := orchard.ByOutpoint[key]
 		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: missing orchard action for selected note")
-		}
-		planNotes = append(planNotes, types.OrchardSpendNote{
-			NoteID:          key,
-			ActionNullifier: act.Nullifier,
-			CMX:             act.CMX,
-			Position:        act.Position,
-			Path:            nil,
-			EphemeralKey:    act.EphemeralKey,
-			EncCiphertext:   act.EncCiphertext,
+			return TxPlan{}, errors.New("txbuild: missing orchard action for selected note")
+		}
+		planNotes = append(planNotes, SpendNote{
+			OrchardSpendNote: types.OrchardSpendNote{
+				NoteID:          key,
+				ActionNullifier: act.Nullifier,
+				CMX:             act.CMX,
+				Position:        act.Position,
+				Path:            nil,
+				EphemeralKey:    act.EphemeralKey,
+				EncCiphertext:   act.EncCiphertext,
+			},
+			ValueZat:  strconv.FormatUint(n.ValueZat, 10),
+			Height:    act.Height,
+			BlockHash: act.BlockHash,
 		})
 		positions = append(positions, act.Position)
 	}
 
 	wit, err := witness.OrchardWitness(orchard.CMXHex, positions)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if len(wit.Paths) != len(planNotes) {
-		return types.TxPlan{}, errors.New("txbuild: witness response mismatch")
+		return TxPlan{}, errors.New("txbuild: witness response mismatch")
 	}
 
 	for i := range planNotes {
 		if wit.Paths[i].Position != planNotes[i].Position {
-			return types.TxPlan{}, errors.New("txbuild: witness response mismatch")
+			return TxPlan{}, errors.New("txbuild: witness response mismatch")
 		}
 		planNotes[i].Path = wit.Paths[i].AuthPath
 	}
 
 	expiryHeight, err := logic.ExpiryHeightFromTip(anchorHeight, cfg.ExpiryOffset)
 	if err != nil {
-		return types.TxPlan{}, errors.New("txbuild: expiry height overflow")
+		return TxPlan{}, errors.New("txbuild: expiry height overflow")
 	}
 
-	plan := types.TxPlan{
+	plan := TxPlan{
 		Version:      types.V0,
 		Kind:         types.TxPlanKindSweep,
 		WalletID:     cfg.WalletID,
@@ -479,14 +958,22 @@ func PlanSweep(ctx context.Context, cfg SweepConfig) (types.TxPlan, error) {
 		Chain:        chainInfo.Chain,
 		BranchID:     chainInfo.BranchID,
 		AnchorHeight: anchorHeight,
+		TipHeight:    uint32(chainInfo.Height),
+		TipHash:      chainInfo.BestBlockHash,
 		Anchor:       wit.Root,
 		ExpiryHeight: expiryHeight,
-		Outputs: []types.TxOutput{
-			{ToAddress: cfg.ToAddress, AmountZat: strconv.FormatUint(amount, 10), MemoHex: cfg.MemoHex},
-		},
+		Outputs: []TxOutput{{
+			TxOutput:  types.TxOutput{ToAddress: cfg.ToAddress, AmountZat: strconv.FormatUint(amount, 10), MemoHex: cfg.MemoHex},
+			Label:     cfg.Label,
+			RequestID: cfg.RequestID,
+		}},
 		ChangeAddress: cfg.ChangeAddress,
 		FeeZat:        strconv.FormatUint(feeZat, 10),
 		Notes:         planNotes,
+		FeePolicy:     appliedFeePolicy(cfg.FeePriority, feePolicy),
+		FeeAnalysis:   feeAnalysis,
+		OVKPolicy:     cfg.OVKPolicy,
+		Metadata:      cfg.Metadata,
 	}
 	return plan, nil
 }
@@ -505,8 +992,12 @@ type ConsolidateConfig struct {
 	CoinType uint32
 	Account  uint32
 
-	ToAddress     string
-	MemoHex       string
+	ToAddress string
+	MemoHex   string
+	// Annotations of the output, echoed into the plan (see TxOutput).
+	Label     string
+	RequestID string
+
 	ChangeAddress string
 
 	MaxSpends int
@@ -517,9 +1008,31 @@ type ConsolidateConfig struct {
 
 	FeeMultiplier uint64
 	FeeAddZat     uint64
+
+	PlanOptions
 }
 
+// PlanConsolidate builds a consolidation plan as types.TxPlan.
+// BuildConsolidate returns the full plan.
 func PlanConsolidate(ctx context.Context, cfg ConsolidateConfig) (types.TxPlan, error) {
+	return basePlan(BuildConsolidate(ctx, cfg))
+}
+
+// BuildConsolidate builds a plan merging up to cfg.MaxSpends small notes into
+// one.
+func BuildConsolidate(ctx context.Context, cfg ConsolidateConfig) (TxPlan, error) {
+	version, err := ParsePlanVersion(cfg.PlanVersion)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	plan, err := draftConsolidate(ctx, cfg)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	return finishPlan(version, plan)
+}
+
+func draftConsolidate(ctx context.Context, cfg ConsolidateConfig) (TxPlan, error) {
 	cfg.RPCURL = strings.TrimSpace(cfg.RPCURL)
 	cfg.RPCUser = strings.TrimSpace(cfg.RPCUser)
 	cfg.RPCPass = strings.TrimSpace(cfg.RPCPass)
@@ -531,15 +1044,28 @@ func PlanConsolidate(ctx context.Context, cfg ConsolidateConfig) (types.TxPlan,
 	cfg.ChangeAddress = strings.TrimSpace(cfg.ChangeAddress)
 
 	if cfg.RPCURL == "" {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "rpc url required"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "rpc url required"}
 	}
 	if cfg.WalletID == "" {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "wallet_id required"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "wallet_id required"}
 	}
 	if cfg.ToAddress == "" {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "to required"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "to required"}
+	}
+	if cfg.MemoHex != "" {
+		m, err := memo.ParseHex(cfg.MemoHex)
+		if err != nil {
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: fmt.Sprintf("memo_hex: %v", err)}
+		}
+		cfg.MemoHex = m
+	}
+	if err := cfg.PlanOptions.normalize(); err != nil {
+		return TxPlan{}, err
+	}
+	if err := validateChangeKey(cfg.UFVK, cfg.ChangeAddress, cfg.FreshChangeAddress); err != nil {
+		return TxPlan{}, err
 	}
-	if cfg.ChangeAddress == "" {
+	if cfg.ChangeAddress == "" && cfg.UFVK == "" {
 		cfg.ChangeAddress = cfg.ToAddress
 	}
 	if cfg.MaxSpends <= 0 {
@@ -552,17 +1078,20 @@ func PlanConsolidate(ctx context.Context, cfg ConsolidateConfig) (types.TxPlan,
 		cfg.ExpiryOffset = 40
 	}
 	if cfg.ExpiryOffset < 4 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "expiry_offset must be >= 4"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "expiry_offset must be >= 4"}
 	}
 	if cfg.FeeMultiplier == 0 {
 		cfg.FeeMultiplier = 1
 	}
+	if err := validateExactFee(cfg.FeeZat, cfg.FeeMultiplier, cfg.FeeAddZat, cfg.FeePerActionZat, cfg.FeeCapZat, 0); err != nil {
+		return TxPlan{}, err
+	}
 
 	rpc := junocashd.New(cfg.RPCURL, cfg.RPCUser, cfg.RPCPass)
 
 	chainInfo, err := chain.GetChainInfo(ctx, rpc)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	coinType := cfg.CoinType
@@ -575,14 +1104,20 @@ func PlanConsolidate(ctx context.Context, cfg ConsolidateConfig) (types.TxPlan,
 		case "regtest":
 			coinType = 8135
 		default:
-			return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "unknown chain"}
+			return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "unknown chain"}
 		}
 	}
+	cfg.ChangeAddress, err = resolveChangeAddress(chainInfo.Chain, []addressField{
+		{Name: "to_address", Address: cfg.ToAddress},
+	}, cfg.ChangeAddress, cfg.UFVK, cfg.FreshChangeAddress)
+	if err != nil {
+		return TxPlan{}, err
+	}
 	if chainInfo.Height < 0 {
-		return types.TxPlan{}, errors.New("txbuild: invalid chain height")
+		return TxPlan{}, errors.New("txbuild: invalid chain height")
 	}
 	if chainInfo.Height > int64(^uint32(0)) {
-		return types.TxPlan{}, errors.New("txbuild: chain height too large")
+		return TxPlan{}, errors.New("txbuild: chain height too large")
 	}
 	anchorHeight := uint32(chainInfo.Height)
 
@@ -592,28 +1127,25 @@ func PlanConsolidate(ctx context.Context, cfg ConsolidateConfig) (types.TxPlan,
 
 	orchard, err := chain.BuildOrchardIndex(ctx, rpc, int64(anchorHeight))
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if len(orchard.CMXHex) == 0 {
-		return types.TxPlan{}, errors.New("txbuild: no orchard commitments")
+		return TxPlan{}, errors.New("txbuild: no orchard commitments")
 	}
 
 	notes, err := listUnspentOrchardNotes(ctx, rpc, cfg.MinConfirmations, cfg.Account)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	notes = logic.FilterNotesMinValue(notes, cfg.MinNoteZat)
 	if len(notes) < 2 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "not enough spendable notes to consolidate"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "not enough spendable notes to consolidate"}
 	}
 
-	feePolicy := logic.FeePolicy{
-		Multiplier: cfg.FeeMultiplier,
-		AddZat:     cfg.FeeAddZat,
-	}
+	feePolicy := cfg.feePolicy(cfg.FeeMultiplier, cfg.FeeAddZat)
 	selected, feeZat, err := selectNotesForConsolidation(notes, cfg.MaxSpends, feePolicy)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	var totalIn uint64
@@ -621,55 +1153,67 @@ func PlanConsolidate(ctx context.Context, cfg ConsolidateConfig) (types.TxPlan,
 		var ok bool
 		totalIn, ok = addUint64(totalIn, n.ValueZat)
 		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: selected notes sum overflow")
+			return TxPlan{}, errors.New("txbuild: selected notes sum overflow")
 		}
 	}
+	if err := checkFeeLimits(cfg.feeLimits(), feeZat, totalIn-min(feeZat, totalIn)); err != nil {
+		return TxPlan{}, err
+	}
+	feeAnalysis, err := analyzeFee(len(selected), 1, feeZat)
+	if err != nil {
+		return TxPlan{}, err
+	}
 	if totalIn <= feeZat {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
 	}
 	amount := totalIn - feeZat
 
 	positions := make([]uint32, 0, len(selected))
-	planNotes := make([]types.OrchardSpendNote, 0, len(selected))
+	planNotes := make([]SpendNote, 0, len(selected))
 	for _, n := range selected {
 		key := fmt.Sprintf("%s:%d", n.TxID, n.ActionIndex)
 		act, ok := orchard.ByOutpoint[key]
 		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: missing orchard action for selected note")
-		}
-		planNotes = append(planNotes, types.OrchardSpendNote{
-			NoteID:          key,
-			ActionNullifier: act.Nullifier,
-			CMX:             act.CMX,
-			Position:        act.Position,
-			Path:            nil,
-			EphemeralKey:    act.EphemeralKey,
-			EncCiphertext:   act.EncCiphertext,
+			return TxPlan{}, errors.New("txbuild: missing orchard action for selected note")
+		}
+		planNotes = append(planNotes, SpendNote{
+			OrchardSpendNote: types.OrchardSpendNote{
+				NoteID:          key,
+				ActionNullifier: act.Nullifier,
+				CMX:             act.CMX,
+				Position:        act.Position,
+				Path:            nil,
+				EphemeralKey:    act.EphemeralKey,
+				EncCiphertext:   act.EncCiphertext,
+			},
+			ValueZat:  strconv.FormatUint(n.ValueZat, 10),
+			Height:    act.Height,
+			BlockHash: act.BlockHash,
 		})
 		positions = append(positions, act.Position)
 	}
 
 	wit, err := witness.OrchardWitness(orchard.CMXHex, positions)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if len(wit.Paths) != len(planNotes) {
-		return types.TxPlan{}, errors.New("txbuild: witness response mismatch")
+		return TxPlan{}, errors.New("txbuild: witness response mismatch")
 	}
 
 	for i := range planNotes {
 		if wit.Paths[i].Position != planNotes[i].Position {
-			return types.TxPlan{}, errors.New("txbuild: witness response mismatch")
+			return TxPlan{}, errors.New("txbuild: witness response mismatch")
 		}
 		planNotes[i].Path = wit.Paths[i].AuthPath
 	}
 
 	expiryHeight, err := logic.ExpiryHeightFromTip(anchorHeight, cfg.ExpiryOffset)
 	if err != nil {
-		return types.TxPlan{}, errors.New("txbuild: expiry height overflow")
+		return TxPlan{}, errors.New("txbuild: expiry height overflow")
 	}
 
-	plan := types.TxPlan{
+	plan := TxPlan{
 		Version:      types.V0,
 		Kind:         types.TxPlanKindRebalance,
 		WalletID:     cfg.WalletID,
@@ -678,14 +1222,22 @@ func PlanConsolidate(ctx context.Context, cfg ConsolidateConfig) (types.TxPlan,
 		Chain:        chainInfo.Chain,
 		BranchID:     chainInfo.BranchID,
 		AnchorHeight: anchorHeight,
+		TipHeight:    uint32(chainInfo.Height),
+		TipHash:      chainInfo.BestBlockHash,
 		Anchor:       wit.Root,
 		ExpiryHeight: expiryHeight,
-		Outputs: []types.TxOutput{
-			{ToAddress: cfg.ToAddress, AmountZat: strconv.FormatUint(amount, 10), MemoHex: cfg.MemoHex},
-		},
+		Outputs: []TxOutput{{
+			TxOutput:  types.TxOutput{ToAddress: cfg.ToAddress, AmountZat: strconv.FormatUint(amount, 10), MemoHex: cfg.MemoHex},
+			Label:     cfg.Label,
+			RequestID: cfg.RequestID,
+		}},
 		ChangeAddress: cfg.ChangeAddress,
 		FeeZat:        strconv.FormatUint(feeZat, 10),
 		Notes:         planNotes,
+		FeePolicy:     appliedFeePolicy(cfg.FeePriority, feePolicy),
+		FeeAnalysis:   feeAnalysis,
+		OVKPolicy:     cfg.OVKPolicy,
+		Metadata:      cfg.Metadata,
 	}
 	return plan, nil
 }
@@ -698,42 +1250,29 @@ type spendableNote struct {
 	ValueZat    uint64
 }
 
-func planWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo chain.ChainInfo, coinType uint32, cfg PlanConfig, totalOut uint64) (types.TxPlan, error) {
+func planWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo chain.ChainInfo, coinType uint32, cfg PlanConfig, totalOut uint64) (TxPlan, error) {
 	sc, err := newScanClient(cfg.ScanURL, cfg.ScanBearerToken)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	notes, err := listSpendableNotesFromScan(ctx, sc, cfg.WalletID, chainInfo.Height, cfg.MinConfirmations, cfg.MinNoteZat)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	unspent := logic.FilterNotesMinValue(notesToUnspent(notes), cfg.MinNoteZat)
 	if len(unspent) == 0 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "no spendable notes"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "no spendable notes"}
 	}
 
-	feePolicy := logic.FeePolicy{
-		Multiplier: cfg.FeeMultiplier,
-		AddZat:     cfg.FeeAddZat,
-	}
-	selected, feeZat, err := logic.SelectNotesWithFeePolicy(unspent, totalOut, len(cfg.Outputs), feePolicy)
+	selected, feeZat, outputs, err := selectNotesForOutputs(unspent, cfg, totalOut)
 	if err != nil {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
+		return TxPlan{}, err
 	}
-
-	var totalIn uint64
-	for _, n := range selected {
-		var ok bool
-		totalIn, ok = addUint64(totalIn, n.ValueZat)
-		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: selected notes sum overflow")
-		}
-	}
-	feeZat, _, err = logic.SuppressDustChange(totalIn, totalOut, feeZat, cfg.MinChangeZat)
+	feeAnalysis, err := checkOutputFee(cfg, selected, feeZat, outputs)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	noteByOutpoint := make(map[string]spendableNote, len(notes))
@@ -743,41 +1282,46 @@ func planWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo chain.Ch
 	}
 
 	positions := make([]uint32, 0, len(selected))
-	planNotes := make([]types.OrchardSpendNote, 0, len(selected))
+	planNotes := make([]SpendNote, 0, len(selected))
 	blockCache := make(map[int64]blockV2)
 	for _, n := range selected {
 		key := fmt.Sprintf("%s:%d", n.TxID, n.ActionIndex)
 		meta, ok := noteByOutpoint[key]
 		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: missing note metadata from scan")
+			return TxPlan{}, errors.New("txbuild: missing note metadata from scan")
 		}
 
 		act, err := orchardActionForNote(ctx, rpc, blockCache, meta.Height, meta.TxID, meta.ActionIndex)
 		if err != nil {
-			return types.TxPlan{}, err
+			return TxPlan{}, err
 		}
 
 		positions = append(positions, meta.Position)
-		planNotes = append(planNotes, types.OrchardSpendNote{
-			NoteID:          key,
-			ActionNullifier: act.Nullifier,
-			CMX:             act.CMX,
-			Position:        meta.Position,
-			Path:            nil,
-			EphemeralKey:    act.EphemeralKey,
-			EncCiphertext:   act.EncCiphertext,
+		planNotes = append(planNotes, SpendNote{
+			OrchardSpendNote: types.OrchardSpendNote{
+				NoteID:          key,
+				ActionNullifier: act.Nullifier,
+				CMX:             act.CMX,
+				Position:        meta.Position,
+				Path:            nil,
+				EphemeralKey:    act.EphemeralKey,
+				EncCiphertext:   act.EncCiphertext,
+			},
+			ValueZat:  strconv.FormatUint(n.ValueZat, 10),
+			Height:    act.Height,
+			BlockHash: act.BlockHash,
 		})
 	}
 
 	wit, err := sc.OrchardWitness(ctx, nil, positions)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if strings.TrimSpace(wit.Root) == "" || len(wit.Paths) != len(positions) {
-		return types.TxPlan{}, errors.New("txbuild: invalid witness response")
+		return TxPlan{}, errors.New("txbuild: invalid witness response")
 	}
 	if wit.AnchorHeight < 0 || wit.AnchorHeight > int64(^uint32(0)) {
-		return types.TxPlan{}, errors.New("txbuild: invalid witness anchor_height")
+		return TxPlan{}, errors.New("txbuild: invalid witness anchor_height")
 	}
 
 	pathByPos := make(map[uint32][]string, len(wit.Paths))
@@ -787,17 +1331,17 @@ func planWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo chain.Ch
 	for i := range planNotes {
 		p, ok := pathByPos[planNotes[i].Position]
 		if !ok || len(p) != 32 {
-			return types.TxPlan{}, errors.New("txbuild: witness path missing")
+			return TxPlan{}, errors.New("txbuild: witness path missing")
 		}
 		planNotes[i].Path = p
 	}
 
 	expiryHeight, err := logic.ExpiryHeightFromTip(uint32(chainInfo.Height), cfg.ExpiryOffset)
 	if err != nil {
-		return types.TxPlan{}, errors.New("txbuild: expiry height overflow")
+		return TxPlan{}, errors.New("txbuild: expiry height overflow")
 	}
 
-	plan := types.TxPlan{
+	plan := TxPlan{
 		Version:       types.V0,
 		Kind:          cfg.Kind,
 		WalletID:      cfg.WalletID,
@@ -806,39 +1350,42 @@ func planWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo chain.Ch
 		Chain:         chainInfo.Chain,
 		BranchID:      chainInfo.BranchID,
 		AnchorHeight:  uint32(wit.AnchorHeight),
+		TipHeight:     uint32(chainInfo.Height),
+		TipHash:       chainInfo.BestBlockHash,
 		Anchor:        wit.Root,
 		ExpiryHeight:  expiryHeight,
-		Outputs:       cfg.Outputs,
+		Outputs:       outputs,
 		ChangeAddress: cfg.ChangeAddress,
 		FeeZat:        strconv.FormatUint(feeZat, 10),
 		Notes:         planNotes,
+		FeePolicy:     appliedFeePolicy(cfg.FeePriority, cfg.feePolicy(cfg.FeeMultiplier, cfg.FeeAddZat)),
+		FeeAnalysis:   feeAnalysis,
+		OVKPolicy:     cfg.OVKPolicy,
+		Metadata:      cfg.Metadata,
 	}
 	return plan, nil
 }
 
-func planConsolidateWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo chain.ChainInfo, coinType uint32, cfg ConsolidateConfig) (types.TxPlan, error) {
+func planConsolidateWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo chain.ChainInfo, coinType uint32, cfg ConsolidateConfig) (TxPlan, error) {
 	sc, err := newScanClient(cfg.ScanURL, cfg.ScanBearerToken)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	notes, err := listSpendableNotesFromScan(ctx, sc, cfg.WalletID, chainInfo.Height, cfg.MinConfirmations, cfg.MinNoteZat)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	unspent := logic.FilterNotesMinValue(notesToUnspent(notes), cfg.MinNoteZat)
 	if len(unspent) < 2 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "not enough spendable notes to consolidate"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInvalidRequest, Message: "not enough spendable notes to consolidate"}
 	}
 
-	feePolicy := logic.FeePolicy{
-		Multiplier: cfg.FeeMultiplier,
-		AddZat:     cfg.FeeAddZat,
-	}
+	feePolicy := cfg.feePolicy(cfg.FeeMultiplier, cfg.FeeAddZat)
 	selected, feeZat, err := selectNotesForConsolidation(unspent, cfg.MaxSpends, feePolicy)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	var totalIn uint64
@@ -846,11 +1393,18 @@ func planConsolidateWithScan(ctx context.Context, rpc *junocashd.Client, chainIn
 		var ok bool
 		totalIn, ok = addUint64(totalIn, n.ValueZat)
 		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: selected notes sum overflow")
+			return TxPlan{}, errors.New("txbuild: selected notes sum overflow")
 		}
 	}
+	if err := checkFeeLimits(cfg.feeLimits(), feeZat, totalIn-min(feeZat, totalIn)); err != nil {
+		return TxPlan{}, err
+	}
+	feeAnalysis, err := analyzeFee(len(selected), 1, feeZat)
+	if err != nil {
+		return TxPlan{}, err
+	}
 	if totalIn <= feeZat {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
 	}
 	amount := totalIn - feeZat
 
@@ -861,41 +1415,46 @@ func planConsolidateWithScan(ctx context.Context, rpc *junocashd.Client, chainIn
 	}
 
 	positions := make([]uint32, 0, len(selected))
-	planNotes := make([]types.OrchardSpendNote, 0, len(selected))
+	planNotes := make([]SpendNote, 0, len(selected))
 	blockCache := make(map[int64]blockV2)
 	for _, n := range selected {
 		key := fmt.Sprintf("%s:%d", n.TxID, n.ActionIndex)
 		meta, ok := noteByOutpoint[key]
 		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: missing note metadata from scan")
+			return TxPlan{}, errors.New("txbuild: missing note metadata from scan")
 		}
 
 		act, err := orchardActionForNote(ctx, rpc, blockCache, meta.Height, meta.TxID, meta.ActionIndex)
 		if err != nil {
-			return types.TxPlan{}, err
+			return TxPlan{}, err
 		}
 
 		positions = append(positions, meta.Position)
-		planNotes = append(planNotes, types.OrchardSpendNote{
-			NoteID:          key,
-			ActionNullifier: act.Nullifier,
-			CMX:             act.CMX,
-			Position:        meta.Position,
-			Path:            nil,
-			EphemeralKey:    act.EphemeralKey,
-			EncCiphertext:   act.EncCiphertext,
+		planNotes = append(planNotes, SpendNote{
+			OrchardSpendNote: types.OrchardSpendNote{
+				NoteID:          key,
+				ActionNullifier: act.Nullifier,
+				CMX:             act.CMX,
+				Position:        meta.Position,
+				Path:            nil,
+				EphemeralKey:    act.EphemeralKey,
+				EncCiphertext:   act.EncCiphertext,
+			},
+			ValueZat:  strconv.FormatUint(n.ValueZat, 10),
+			Height:    act.Height,
+			BlockHash: act.BlockHash,
 		})
 	}
 
 	wit, err := sc.OrchardWitness(ctx, nil, positions)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if strings.TrimSpace(wit.Root) == "" || len(wit.Paths) != len(positions) {
-		return types.TxPlan{}, errors.New("txbuild: invalid witness response")
+		return TxPlan{}, errors.New("txbuild: invalid witness response")
 	}
 	if wit.AnchorHeight < 0 || wit.AnchorHeight > int64(^uint32(0)) {
-		return types.TxPlan{}, errors.New("txbuild: invalid witness anchor_height")
+		return TxPlan{}, errors.New("txbuild: invalid witness anchor_height")
 	}
 
 	pathByPos := make(map[uint32][]string, len(wit.Paths))
@@ -905,17 +1464,17 @@ func planConsolidateWithScan(ctx context.Context, rpc *junocashd.Client, chainIn
 	for i := range planNotes {
 		p, ok := pathByPos[planNotes[i].Position]
 		if !ok || len(p) != 32 {
-			return types.TxPlan{}, errors.New("txbuild: witness path missing")
+			return TxPlan{}, errors.New("txbuild: witness path missing")
 		}
 		planNotes[i].Path = p
 	}
 
 	expiryHeight, err := logic.ExpiryHeightFromTip(uint32(chainInfo.Height), cfg.ExpiryOffset)
 	if err != nil {
-		return types.TxPlan{}, errors.New("txbuild: expiry height overflow")
+		return TxPlan{}, errors.New("txbuild: expiry height overflow")
 	}
 
-	plan := types.TxPlan{
+	plan := TxPlan{
 		Version:      types.V0,
 		Kind:         types.TxPlanKindRebalance,
 		WalletID:     cfg.WalletID,
@@ -924,30 +1483,38 @@ func planConsolidateWithScan(ctx context.Context, rpc *junocashd.Client, chainIn
 		Chain:        chainInfo.Chain,
 		BranchID:     chainInfo.BranchID,
 		AnchorHeight: uint32(wit.AnchorHeight),
+		TipHeight:    uint32(chainInfo.Height),
+		TipHash:      chainInfo.BestBlockHash,
 		Anchor:       wit.Root,
 		ExpiryHeight: expiryHeight,
-		Outputs: []types.TxOutput{
-			{ToAddress: cfg.ToAddress, AmountZat: strconv.FormatUint(amount, 10), MemoHex: cfg.MemoHex},
-		},
+		Outputs: []TxOutput{{
+			TxOutput:  types.TxOutput{ToAddress: cfg.ToAddress, AmountZat: strconv.FormatUint(amount, 10), MemoHex: cfg.MemoHex},
+			Label:     cfg.Label,
+			RequestID: cfg.RequestID,
+		}},
 		ChangeAddress: cfg.ChangeAddress,
 		FeeZat:        strconv.FormatUint(feeZat, 10),
 		Notes:         planNotes,
+		FeePolicy:     appliedFeePolicy(cfg.FeePriority, feePolicy),
+		FeeAnalysis:   feeAnalysis,
+		OVKPolicy:     cfg.OVKPolicy,
+		Metadata:      cfg.Metadata,
 	}
 	return plan, nil
 }
 
-func planSweepWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo chain.ChainInfo, coinType uint32, cfg SweepConfig) (types.TxPlan, error) {
+func planSweepWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo chain.ChainInfo, coinType uint32, cfg SweepConfig) (TxPlan, error) {
 	sc, err := newScanClient(cfg.ScanURL, cfg.ScanBearerToken)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 
 	notes, err := listSpendableNotesFromScan(ctx, sc, cfg.WalletID, chainInfo.Height, cfg.MinConfirmations, cfg.MinNoteZat)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if len(notes) == 0 {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "no spendable notes"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "no spendable notes"}
 	}
 
 	var totalIn uint64
@@ -955,52 +1522,60 @@ func planSweepWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo cha
 		var ok bool
 		totalIn, ok = addUint64(totalIn, n.ValueZat)
 		if !ok {
-			return types.TxPlan{}, errors.New("txbuild: notes sum overflow")
+			return TxPlan{}, errors.New("txbuild: notes sum overflow")
 		}
 	}
-	feePolicy := logic.FeePolicy{
-		Multiplier: cfg.FeeMultiplier,
-		AddZat:     cfg.FeeAddZat,
+	feePolicy := cfg.feePolicy(cfg.FeeMultiplier, cfg.FeeAddZat)
+	feeZat, err := feePolicy.Fee(len(notes), 1)
+	if err != nil {
+		return TxPlan{}, err
+	}
+	if err := checkFeeLimits(cfg.feeLimits(), feeZat, totalIn-min(feeZat, totalIn)); err != nil {
+		return TxPlan{}, err
 	}
-	feeMin := logic.RequiredFeeSend(len(notes), 1)
-	feeZat, err := feePolicy.Apply(feeMin)
+	feeAnalysis, err := analyzeFee(len(notes), 1, feeZat)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if totalIn <= feeZat {
-		return types.TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
+		return TxPlan{}, types.CodedError{Code: types.ErrCodeInsufficientBalance, Message: "insufficient funds"}
 	}
 	amount := totalIn - feeZat
 
 	positions := make([]uint32, 0, len(notes))
-	planNotes := make([]types.OrchardSpendNote, 0, len(notes))
+	planNotes := make([]SpendNote, 0, len(notes))
 	blockCache := make(map[int64]blockV2)
 	for _, n := range notes {
 		act, err := orchardActionForNote(ctx, rpc, blockCache, n.Height, n.TxID, n.ActionIndex)
 		if err != nil {
-			return types.TxPlan{}, err
+			return TxPlan{}, err
 		}
 		positions = append(positions, n.Position)
-		planNotes = append(planNotes, types.OrchardSpendNote{
-			NoteID:          fmt.Sprintf("%s:%d", n.TxID, n.ActionIndex),
-			ActionNullifier: act.Nullifier,
-			CMX:             act.CMX,
-			Position:        n.Position,
-			Path:            nil,
-			EphemeralKey:    act.EphemeralKey,
-			EncCiphertext:   act.EncCiphertext,
+		planNotes = append(planNotes, SpendNote{
+			OrchardSpendNote: types.OrchardSpendNote{
+				NoteID:          fmt.Sprintf("%s:%d", n.TxID, n.ActionIndex),
+				ActionNullifier: act.Nullifier,
+				CMX:             act.CMX,
+				Position:        n.Position,
+				Path:            nil,
+				EphemeralKey:    act.EphemeralKey,
+				EncCiphertext:   act.EncCiphertext,
+			},
+			ValueZat:  strconv.FormatUint(n.ValueZat, 10),
+			Height:    act.Height,
+			BlockHash: act.BlockHash,
 		})
 	}
 
 	wit, err := sc.OrchardWitness(ctx, nil, positions)
 	if err != nil {
-		return types.TxPlan{}, err
+		return TxPlan{}, err
 	}
 	if strings.TrimSpace(wit.Root) == "" || len(wit.Paths) != len(positions) {
-		return types.TxPlan{}, errors.New("txbuild: invalid witness response")
+		return TxPlan{}, errors.New("txbuild: invalid witness response")
 	}
 	if wit.AnchorHeight < 0 || wit.AnchorHeight > int64(^uint32(0)) {
-		return types.TxPlan{}, errors.New("txbuild: invalid witness anchor_height")
+		return TxPlan{}, errors.New("txbuild: invalid witness anchor_height")
 	}
 	pathByPos := make(map[uint32][]string, len(wit.Paths))
 	for _, p := range wit.Paths {
@@ -1009,17 +1584,17 @@ func planSweepWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo cha
 	for i := range planNotes {
 		p, ok := pathByPos[planNotes[i].Position]
 		if !ok || len(p) != 32 {
-			return types.TxPlan{}, errors.New("txbuild: witness path missing")
+			return TxPlan{}, errors.New("txbuild: witness path missing")
 		}
 		planNotes[i].Path = p
 	}
 
 	expiryHeight, err := logic.ExpiryHeightFromTip(uint32(chainInfo.Height), cfg.ExpiryOffset)
 	if err != nil {
-		return types.TxPlan{}, errors.New("txbuild: expiry height overflow")
+		return TxPlan{}, errors.New("txbuild: expiry height overflow")
 	}
 
-	plan := types.TxPlan{
+	plan := TxPlan{
 		Version:      types.V0,
 		Kind:         types.TxPlanKindSweep,
 		WalletID:     cfg.WalletID,
@@ -1028,14 +1603,22 @@ func planSweepWithScan(ctx context.Context, rpc *junocashd.Client, chainInfo cha
 		Chain:        chainInfo.Chain,
 		BranchID:     chainInfo.BranchID,
 		AnchorHeight: uint32(wit.AnchorHeight),
+		TipHeight:    uint32(chainInfo.Height),
+		TipHash:      chainInfo.BestBlockHash,
 		Anchor:       wit.Root,
 		ExpiryHeight: expiryHeight,
-		Outputs: []types.TxOutput{
-			{ToAddress: cfg.ToAddress, AmountZat: strconv.FormatUint(amount, 10), MemoHex: cfg.MemoHex},
-		},
+		Outputs: []TxOutput{{
+			TxOutput:  types.TxOutput{ToAddress: cfg.ToAddress, AmountZat: strconv.FormatUint(amount, 10), MemoHex: cfg.MemoHex},
+			Label:     cfg.Label,
+			RequestID: cfg.RequestID,
+		}},
 		ChangeAddress: cfg.ChangeAddress,
 		FeeZat:        strconv.FormatUint(feeZat, 10),
 		Notes:         planNotes,
+		FeePolicy:     appliedFeePolicy(cfg.FeePriority, feePolicy),
+		FeeAnalysis:   feeAnalysis,
+		OVKPolicy:     cfg.OVKPolicy,
+		Metadata:      cfg.Metadata,
 	}
 	return plan, nil
 }
@@ -1080,8 +1663,7 @@ func selectNotesForConsolidation(notes []logic.UnspentNote, maxSpends int, feePo
 	}
 
 	for k := maxSpends; k >= 2; k-- {
-		feeMin := logic.RequiredFeeSend(k, 1)
-		feeZat, err := feePolicy.Apply(feeMin)
+		feeZat, err := feePolicy.Fee(k, 1)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -1239,10 +1821,13 @@ type orchardAction struct {
 	CMX           string
 	EphemeralKey  string
 	EncCiphertext string
+	Height        uint32
+	BlockHash     string
 }
 
 type blockV2 struct {
-	Tx []struct {
+	Hash string `json:"hash"`
+	Tx   []struct {
 		TxID    string `json:"txid"`
 		Orchard struct {
 			Actions []struct {
@@ -1265,6 +1850,9 @@ func orchardActionForNote(ctx context.Context, rpc *junocashd.Client, cache map[
 		if err := rpc.Call(ctx, "getblock", []any{hash, 2}, &blk); err != nil {
 			return orchardAction{}, err
 		}
+		if blk.Hash == "" {
+			blk.Hash = hash
+		}
 		cache[height] = blk
 	}
 
@@ -1282,6 +1870,8 @@ func orchardActionForNote(ctx context.Context, rpc *junocashd.Client, cache map[
 			CMX:           strings.ToLower(strings.TrimSpace(a.CMX)),
 			EphemeralKey:  strings.ToLower(strings.TrimSpace(a.EphemeralKey)),
 			EncCiphertext: strings.ToLower(strings.TrimSpace(a.EncCiphertext)),
+			Height:        uint32(height),
+			BlockHash:     strings.ToLower(strings.TrimSpace(blk.Hash)),
 		}
 		if len(act.EncCiphertext) >= 104 {
 			act.EncCiphertext = act.EncCiphertext[:104]
@@ -15,7 +15,60 @@ extern "C" {
 // The returned pointer must be freed with `juno_txbuild_string_free`.
 char *juno_txbuild_orchard_witness_json(const char *req_json);
 
-// Frees a string returned by `juno_txbuild_orchard_witness_json`.
+// Derives an Orchard address from a full viewing key.
+//
+// Request: {"fvk_hex":"<96 bytes>","scope":"internal|external","diversifier_index_hex":"<11 bytes LE>"}
+// Returns {"status":"ok","address_hex":"<43-byte raw receiver>"} or {"status":"err","error":"..."}.
+char *juno_txbuild_orchard_derive_address_json(const char *req_json);
+
+// Reports which scope of a full viewing key an Orchard address belongs to.
+//
+// Request: {"fvk_hex":"<96 bytes>","address_hex":"<43-byte raw receiver>"}
+// Returns {"status":"ok","scope":"internal|external|"} ("" = not derived from
+// this key) or {"status":"err","error":"..."}.
+char *juno_txbuild_orchard_address_scope_json(const char *req_json);
+
+// Builds a PCZT (Partially Created Zcash Transaction) from a TxPlan. The
+// spent notes are decrypted with the full viewing key; change (the note
+// values beyond the outputs and the fee) goes to the change address. The
+// plan JSON is stored in the global proprietary field "juno-txbuild:txplan".
+//
+// Request: {"fvk_hex":"<96 bytes>","network":"main|test|regtest","branch_id":n,
+//   "expiry_height":n,"anchor_hex":"..","ovk_policy":"..","fee_zat":n,
+//   "spends":[{"position":n,"path":[".."],"cmx_hex":"..","nullifier_hex":"..",
+//     "ephemeral_key_hex":"..","enc_ciphertext_hex":".."},...],
+//   "outputs":[{"address_hex":"<43-byte raw receiver>","user_address":"j1..",
+//     "value_zat":n,"memo_hex":".."},...],
+//   "change_address_hex":"..","change_user_address":"..","seed_hex":"..",
+//   "plan_json":"..."}
+// seed_hex (optional, 32 bytes) seeds the ChaCha20 RNG all randomness of the
+// Orchard bundle is drawn from, so that a plan and seed always give the same
+// transaction.
+// Returns {"status":"ok","pczt_hex":"..."} or {"status":"err","error":"..."}.
+char *juno_txbuild_pczt_from_plan_json(const char *req_json);
+
+// Parses a PCZT built by juno_txbuild_pczt_from_plan_json.
+//
+// Request: {"pczt_hex":"..."}
+// Returns {"status":"ok","branch_id":n,"expiry_height":n,"anchor":"..",
+//   "plan_json":"...","spends":[{"position":n,"rho":"..","cmx":"..","value_zat":n},...],
+//   "outputs":[{"user_address":"..","value_zat":n},...]} (non-dummy spends,
+// outputs with a user address) or {"status":"err","error":"..."}.
+char *juno_txbuild_pczt_parse_json(const char *req_json);
+
+// Builds the unsigned v5 transaction of a plan (header and an Orchard bundle
+// without proofs and signatures) and its ZIP-244 digests.
+//
+// Request: as juno_txbuild_pczt_from_plan_json, with seed_hex required.
+// Returns {"status":"ok","skeleton":{"header":n,"version_group_id":n,
+//   "branch_id":n,"lock_time":n,"expiry_height":n,"orchard":{"flags":n,
+//   "value_balance":n,"anchor":"..","actions":[{"cv_net":"..","nullifier":"..",
+//   "rk":"..","cmx":"..","ephemeral_key":"..","enc_ciphertext":"..",
+//   "out_ciphertext":".."},...]}},"header_digest":"..","orchard_digest":"..",
+//   "sighash":"..","txid":".."} or {"status":"err","error":"..."}.
+char *juno_txbuild_tx_skeleton_json(const char *req_json);
+
+// Frees a string returned by any `juno_txbuild_*_json` function.
 void juno_txbuild_string_free(char *s);
 
 #ifdef __cplusplus
@@ -4,6 +4,7 @@
 use core::ffi::c_char;
 use incrementalmerkletree::frontier::CommitmentTree;
 use incrementalmerkletree::witness::IncrementalWitness;
+use orchard::keys::{DiversifierIndex, FullViewingKey, Scope};
 use orchard::note::ExtractedNoteCommitment;
 use orchard::tree::MerkleHashOrchard;
 use serde::{Deserialize, Serialize};
@@ -42,8 +43,13 @@ struct WitnessPathOut {
 #[derive(Debug, Serialize)]
 #[serde(tag = "status", rename_all = "snake_case")]
 enum WitnessResponse {
-    Ok { root: String, paths: Vec<WitnessPathOut> },
-    Err { error: String },
+    Ok {
+        root: String,
+        paths: Vec<WitnessPathOut>,
+    },
+    Err {
+        error: String,
+    },
 }
 
 fn parse_hex_32(s: &str) -> Result<[u8; 32], ()> {
@@ -143,7 +149,7 @@ fn orchard_witness_inner(req_json: *const c_char) -> Result<WitnessResponse, Err
     })
 }
 
-fn to_c_string(v: WitnessResponse) -> *mut c_char {
+fn to_c_string<T: Serialize>(v: T) -> *mut c_char {
     let json = serde_json::to_string(&v)
         .unwrap_or_else(|_| r#"{"status":"err","error":"serde_failed"}"#.to_string());
     std::ffi::CString::new(json).expect("json").into_raw()
@@ -163,6 +169,132 @@ pub extern "C" fn juno_txbuild_orchard_witness_json(req_json: *const c_char) ->
     }
 }
 
+#[derive(Debug, Deserialize)]
+struct OrchardAddressRequest {
+    fvk_hex: String,
+    // "internal" or "external"
+    #[serde(default)]
+    scope: String,
+    // 11-byte little-endian diversifier index (hex); empty = 0.
+    #[serde(default)]
+    diversifier_index_hex: String,
+    // Raw 43-byte Orchard receiver (hex), for the scope lookup.
+    #[serde(default)]
+    address_hex: String,
+}
+
+#[derive(Debug, Serialize)]
+#[serde(tag = "status", rename_all = "snake_case")]
+enum OrchardAddressResponse {
+    Ok {
+        #[serde(skip_serializing_if = "Option::is_none")]
+        address_hex: Option<String>,
+        #[serde(skip_serializing_if = "Option::is_none")]
+        scope: Option<String>,
+    },
+    Err {
+        error: String,
+    },
+}
+
+fn parse_address_request(
+    req_json: *const c_char,
+) -> Result<(OrchardAddressRequest, FullViewingKey), ErrorCode> {
+    if req_json.is_null() {
+        return Err(ErrorCode::ReqJSONInvalid);
+    }
+    let s = unsafe { std::ffi::CStr::from_ptr(req_json) }
+        .to_string_lossy()
+        .to_string();
+    let req: OrchardAddressRequest =
+        serde_json::from_str(&s).map_err(|_| ErrorCode::ReqJSONInvalid)?;
+
+    let fvk_bytes = hex::decode(req.fvk_hex.trim()).map_err(|_| ErrorCode::InvalidRequest)?;
+    let fvk_bytes: [u8; 96] = fvk_bytes
+        .try_into()
+        .map_err(|_| ErrorCode::InvalidRequest)?;
+    let fvk = FullViewingKey::from_bytes(&fvk_bytes).ok_or(ErrorCode::InvalidRequest)?;
+    Ok((req, fvk))
+}
+
+fn orchard_derive_address_inner(
+    req_json: *const c_char,
+) -> Result<OrchardAddressResponse, ErrorCode> {
+    let (req, fvk) = parse_address_request(req_json)?;
+
+    let scope = match req.scope.trim() {
+        "" | "internal" => Scope::Internal,
+        "external" => Scope::External,
+        _ => return Err(ErrorCode::InvalidRequest),
+    };
+    let mut index = [0u8; 11];
+    let index_hex = req.diversifier_index_hex.trim();
+    if !index_hex.is_empty() {
+        let b = hex::decode(index_hex).map_err(|_| ErrorCode::InvalidRequest)?;
+        if b.len() != 11 {
+            return Err(ErrorCode::InvalidRequest);
+        }
+        index.copy_from_slice(&b);
+    }
+
+    let addr = fvk.address_at(DiversifierIndex::from(index), scope);
+    Ok(OrchardAddressResponse::Ok {
+        address_hex: Some(hex::encode(addr.to_raw_address_bytes())),
+        scope: None,
+    })
+}
+
+fn orchard_address_scope_inner(
+    req_json: *const c_char,
+) -> Result<OrchardAddressResponse, ErrorCode> {
+    let (req, fvk) = parse_address_request(req_json)?;
+
+    let b = hex::decode(req.address_hex.trim()).map_err(|_| ErrorCode::InvalidRequest)?;
+    let raw: [u8; 43] = b.try_into().map_err(|_| ErrorCode::InvalidRequest)?;
+    let addr_ct = orchard::Address::from_raw_address_bytes(&raw);
+    if bool::from(addr_ct.is_none()) {
+        return Err(ErrorCode::InvalidRequest);
+    }
+    let addr = addr_ct.unwrap();
+
+    let scope = fvk.scope_for_address(&addr).map(|s| match s {
+        Scope::Internal => "internal".to_string(),
+        Scope::External => "external".to_string(),
+    });
+    Ok(OrchardAddressResponse::Ok {
+        address_hex: None,
+        scope: Some(scope.unwrap_or_default()),
+    })
+}
+
+fn address_response(
+    res: std::thread::Result<Result<OrchardAddressResponse, ErrorCode>>,
+) -> *mut c_char {
+    match res {
+        Ok(Ok(v)) => to_c_string(v),
+        Ok(Err(e)) => to_c_string(OrchardAddressResponse::Err {
+            error: e.as_str().to_string(),
+        }),
+        Err(_) => to_c_string(OrchardAddressResponse::Err {
+            error: ErrorCode::Panic.as_str().to_string(),
+        }),
+    }
+}
+
+#[no_mangle]
+pub extern "C" fn juno_txbuild_orchard_derive_address_json(req_json: *const c_char) -> *mut c_char {
+    address_response(std::panic::catch_unwind(|| {
+        orchard_derive_address_inner(req_json)
+    }))
+}
+
+#[no_mangle]
+pub extern "C" fn juno_txbuild_orchard_address_scope_json(req_json: *const c_char) -> *mut c_char {
+    address_response(std::panic::catch_unwind(|| {
+        orchard_address_scope_inner(req_json)
+    }))
+}
+
 #[no_mangle]
 pub extern "C" fn juno_txbuild_string_free(s: *mut c_char) {
     if s.is_null() {
@@ -172,3 +304,707 @@ pub extern "C" fn juno_txbuild_string_free(s: *mut c_char) {
         drop(std::ffi::CString::from_raw(s));
     }
 }
+
+// PCZT (Partially Created Zcash Transaction) export and import.
+
+// Global proprietary PCZT field holding the TxPlan JSON a PCZT was built
+// from, so that it can be imported back.
+const PCZT_PLAN_KEY: &str = "juno-txbuild:txplan";
+
+#[derive(Debug, Deserialize)]
+struct PcztSpendIn {
+    position: u32,
+    path: Vec<String>,
+    cmx_hex: String,
+    // Nullifier of the action that created the note (the note's rho).
+    nullifier_hex: String,
+    ephemeral_key_hex: String,
+    enc_ciphertext_hex: String,
+}
+
+#[derive(Debug, Deserialize)]
+struct PcztOutputIn {
+    // Raw 43-byte Orchard receiver (hex).
+    address_hex: String,
+    // Unified address, recorded as the output's user_address.
+    user_address: String,
+    value_zat: u64,
+    #[serde(default)]
+    memo_hex: String,
+}
+
+#[derive(Debug, Deserialize)]
+struct PcztFromPlanRequest {
+    fvk_hex: String,
+    // "main", "test" or "regtest"
+    network: String,
+    branch_id: u32,
+    expiry_height: u32,
+    anchor_hex: String,
+    #[serde(default)]
+    ovk_policy: String,
+    fee_zat: u64,
+    spends: Vec<PcztSpendIn>,
+    outputs: Vec<PcztOutputIn>,
+    change_address_hex: String,
+    change_user_address: String,
+    // 32-byte ChaCha20 seed of the bundle randomness (hex); empty = random.
+    #[serde(default)]
+    seed_hex: String,
+    #[serde(default)]
+    plan_json: String,
+}
+
+#[derive(Debug, Deserialize)]
+struct PcztParseRequest {
+    pczt_hex: String,
+}
+
+#[derive(Debug, Serialize)]
+struct PcztSpendOut {
+    position: u32,
+    rho: String,
+    cmx: String,
+    value_zat: u64,
+}
+
+#[derive(Debug, Serialize)]
+struct PcztOutputOut {
+    user_address: String,
+    // Raw 43-byte Orchard receiver (hex).
+    recipient: String,
+    value_zat: u64,
+    // Note commitment recomputed from the recipient, value and rseed.
+    cmx: String,
+    // The 512-byte memo, decrypted from the output ciphertext.
+    memo_hex: String,
+}
+
+// The fields of the plan embedded in a PCZT that its parsing checks.
+#[derive(Debug, Default, Deserialize)]
+struct EmbeddedPlan {
+    #[serde(default)]
+    ovk_policy: String,
+}
+
+#[derive(Debug, Serialize)]
+#[serde(tag = "status", rename_all = "snake_case")]
+enum PcztResponse {
+    Ok {
+        #[serde(skip_serializing_if = "Option::is_none")]
+        pczt_hex: Option<String>,
+        #[serde(skip_serializing_if = "Option::is_none")]
+        branch_id: Option<u32>,
+        #[serde(skip_serializing_if = "Option::is_none")]
+        expiry_height: Option<u32>,
+        #[serde(skip_serializing_if = "Option::is_none")]
+        anchor: Option<String>,
+        #[serde(skip_serializing_if = "Option::is_none")]
+        plan_json: Option<String>,
+        #[serde(skip_serializing_if = "Option::is_none")]
+        spends: Option<Vec<PcztSpendOut>>,
+        #[serde(skip_serializing_if = "Option::is_none")]
+        outputs: Option<Vec<PcztOutputOut>>,
+    },
+    Err {
+        error: String,
+    },
+}
+
+#[derive(Debug, Clone, Copy)]
+enum PcztError {
+    Code(ErrorCode),
+    UnsupportedBranchID,
+    NoteDecryptFailed,
+    InsufficientFunds,
+    NotFromPlan,
+    OutputMismatch,
+    OvkMismatch,
+}
+
+impl PcztError {
+    fn as_str(&self) -> &'static str {
+        match self {
+            PcztError::Code(c) => c.as_str(),
+            PcztError::UnsupportedBranchID => "unsupported_branch_id",
+            PcztError::NoteDecryptFailed => "note_decrypt_failed",
+            PcztError::InsufficientFunds => "insufficient_funds",
+            PcztError::NotFromPlan => "not_from_plan",
+            PcztError::OutputMismatch => "output_mismatch",
+            PcztError::OvkMismatch => "ovk_mismatch",
+        }
+    }
+}
+
+impl From<ErrorCode> for PcztError {
+    fn from(c: ErrorCode) -> Self {
+        PcztError::Code(c)
+    }
+}
+
+// Consensus parameters of a plan's network. Only the network type (and so
+// the PCZT coin type) is used when assembling a PCZT from parts.
+#[derive(Debug, Clone, Copy)]
+struct PlanParams(zcash_protocol::consensus::NetworkType);
+
+impl zcash_protocol::consensus::Parameters for PlanParams {
+    fn network_type(&self) -> zcash_protocol::consensus::NetworkType {
+        self.0
+    }
+
+    fn activation_height(
+        &self,
+        _nu: zcash_protocol::consensus::NetworkUpgrade,
+    ) -> Option<zcash_protocol::consensus::BlockHeight> {
+        None
+    }
+}
+
+fn parse_hex_n<const N: usize>(s: &str) -> Result<[u8; N], ErrorCode> {
+    let b = hex::decode(s.trim()).map_err(|_| ErrorCode::InvalidRequest)?;
+    b.try_into().map_err(|_| ErrorCode::InvalidRequest)
+}
+
+fn read_request<T: serde::de::DeserializeOwned>(req_json: *const c_char) -> Result<T, ErrorCode> {
+    if req_json.is_null() {
+        return Err(ErrorCode::ReqJSONInvalid);
+    }
+    let s = unsafe { std::ffi::CStr::from_ptr(req_json) }
+        .to_string_lossy()
+        .to_string();
+    serde_json::from_str(&s).map_err(|_| ErrorCode::ReqJSONInvalid)
+}
+
+// Decrypts a spent note with the external or internal incoming viewing key.
+fn decrypt_spend(fvk: &FullViewingKey, s: &PcztSpendIn) -> Result<orchard::Note, PcztError> {
+    use orchard::note::Nullifier;
+    use orchard::note_encryption::{CompactAction, OrchardDomain};
+    use zcash_note_encryption::{try_compact_note_decryption, EphemeralKeyBytes};
+
+    let nf_ct = Nullifier::from_bytes(&parse_hex_n::<32>(&s.nullifier_hex)?);
+    if bool::from(nf_ct.is_none()) {
+        return Err(ErrorCode::InvalidRequest.into());
+    }
+    let cmx_ct = ExtractedNoteCommitment::from_bytes(&parse_hex_n::<32>(&s.cmx_hex)?);
+    if bool::from(cmx_ct.is_none()) {
+        return Err(ErrorCode::InvalidRequest.into());
+    }
+    let epk = EphemeralKeyBytes(parse_hex_n::<32>(&s.ephemeral_key_hex)?);
+    let enc = hex::decode(s.enc_ciphertext_hex.trim()).map_err(|_| ErrorCode::InvalidRequest)?;
+    if enc.len() < 52 {
+        return Err(ErrorCode::InvalidRequest.into());
+    }
+    let mut compact_ct = [0u8; 52];
+    compact_ct.copy_from_slice(&enc[..52]);
+
+    let action = CompactAction::from_parts(nf_ct.unwrap(), cmx_ct.unwrap(), epk, compact_ct);
+    let domain = OrchardDomain::for_compact_action(&action);
+    for scope in [Scope::External, Scope::Internal] {
+        let ivk = orchard::keys::PreparedIncomingViewingKey::new(&fvk.to_ivk(scope));
+        if let Some((note, _)) = try_compact_note_decryption(&domain, &ivk, &action) {
+            return Ok(note);
+        }
+    }
+    Err(PcztError::NoteDecryptFailed)
+}
+
+fn parse_memo(s: &str) -> Result<[u8; 512], ErrorCode> {
+    let mut memo = [0u8; 512];
+    let b = hex::decode(s.trim()).map_err(|_| ErrorCode::InvalidRequest)?;
+    if b.is_empty() {
+        // No memo.
+        memo[0] = 0xF6;
+        return Ok(memo);
+    }
+    if b.len() > 512 {
+        return Err(ErrorCode::InvalidRequest);
+    }
+    memo[..b.len()].copy_from_slice(&b);
+    Ok(memo)
+}
+
+fn parse_orchard_address(s: &str) -> Result<orchard::Address, ErrorCode> {
+    let addr_ct = orchard::Address::from_raw_address_bytes(&parse_hex_n::<43>(s)?);
+    if bool::from(addr_ct.is_none()) {
+        return Err(ErrorCode::InvalidRequest);
+    }
+    Ok(addr_ct.unwrap())
+}
+
+// The outgoing viewing key a plan's ovk_policy selects: the wallet's
+// external or internal OVK (the default is external), none, or a custom
+// 32-byte OVK.
+fn plan_ovk(
+    fvk: &FullViewingKey,
+    policy: &str,
+) -> Result<Option<orchard::keys::OutgoingViewingKey>, ErrorCode> {
+    use orchard::keys::OutgoingViewingKey;
+
+    Ok(match policy.trim() {
+        "" | "sender" => Some(fvk.to_ovk(Scope::External)),
+        "internal" => Some(fvk.to_ovk(Scope::Internal)),
+        "none" => None,
+        s => Some(OutgoingViewingKey::from(parse_hex_n::<32>(s)?)),
+    })
+}
+
+// Builds the PCZT of a plan. All randomness of the Orchard bundle (action
+// order, dummy actions, value commitment and output note randomness, spend
+// authorization re-randomization) is drawn from a ChaCha20 RNG seeded with
+// seed, so that the same plan and seed give the same transaction.
+fn build_plan_pczt(req: &PcztFromPlanRequest, seed: [u8; 32]) -> Result<pczt::Pczt, PcztError> {
+    use orchard::builder::{Builder, BundleType};
+    use orchard::tree::{Anchor, MerklePath};
+    use orchard::value::NoteValue;
+    use zcash_primitives::transaction::builder::PcztParts;
+    use zcash_primitives::transaction::TxVersion;
+    use zcash_protocol::consensus::{BlockHeight, BranchId, NetworkType};
+
+    let fvk_bytes = parse_hex_n::<96>(&req.fvk_hex)?;
+    let fvk = FullViewingKey::from_bytes(&fvk_bytes).ok_or(ErrorCode::InvalidRequest)?;
+    let network = match req.network.trim() {
+        "main" => NetworkType::Main,
+        "test" => NetworkType::Test,
+        "regtest" => NetworkType::Regtest,
+        _ => return Err(ErrorCode::InvalidRequest.into()),
+    };
+    let branch_id =
+        BranchId::try_from(req.branch_id).map_err(|_| PcztError::UnsupportedBranchID)?;
+    let anchor_ct = Anchor::from_bytes(parse_hex_n::<32>(&req.anchor_hex)?);
+    if bool::from(anchor_ct.is_none()) {
+        return Err(ErrorCode::InvalidRequest.into());
+    }
+    let ovk = plan_ovk(&fvk, &req.ovk_policy)?;
+    if req.spends.is_empty() || req.spends.len() > 1000 {
+        return Err(ErrorCode::InvalidRequest.into());
+    }
+
+    let mut builder = Builder::new(BundleType::DEFAULT, anchor_ct.unwrap());
+    let mut total_in: u64 = 0;
+    for s in &req.spends {
+        let note = decrypt_spend(&fvk, s)?;
+        let mut path = Vec::with_capacity(32);
+        for h in &s.path {
+            let h_ct = MerkleHashOrchard::from_bytes(&parse_hex_n::<32>(h)?);
+            if bool::from(h_ct.is_none()) {
+                return Err(ErrorCode::InvalidRequest.into());
+            }
+            path.push(h_ct.unwrap());
+        }
+        let auth_path: [MerkleHashOrchard; 32] =
+            path.try_into().map_err(|_| ErrorCode::InvalidRequest)?;
+        total_in = total_in
+            .checked_add(note.value().inner())
+            .ok_or(ErrorCode::InvalidRequest)?;
+        builder
+            .add_spend(
+                fvk.clone(),
+                note,
+                MerklePath::from_parts(s.position, auth_path),
+            )
+            .map_err(|_| ErrorCode::InvalidRequest)?;
+    }
+
+    // Change is what the notes hold beyond the outputs and the fee.
+    let mut total_out = req.fee_zat;
+    let mut outputs = Vec::with_capacity(req.outputs.len() + 1);
+    for o in &req.outputs {
+        total_out = total_out
+            .checked_add(o.value_zat)
+            .ok_or(ErrorCode::InvalidRequest)?;
+        outputs.push((
+            parse_orchard_address(&o.address_hex)?,
+            o.user_address.clone(),
+            o.value_zat,
+            parse_memo(&o.memo_hex)?,
+        ));
+    }
+    let change = total_in
+        .checked_sub(total_out)
+        .ok_or(PcztError::InsufficientFunds)?;
+    if change > 0 {
+        outputs.push((
+            parse_orchard_address(&req.change_address_hex)?,
+            req.change_user_address.clone(),
+            change,
+            parse_memo("")?,
+        ));
+    }
+    for (addr, _, value, memo) in &outputs {
+        builder
+            .add_output(ovk.clone(), *addr, NoteValue::from_raw(*value), *memo)
+            .map_err(|_| ErrorCode::InvalidRequest)?;
+    }
+
+    let (mut bundle, meta) = builder
+        .build_for_pczt(<rand_chacha::ChaCha20Rng as rand::SeedableRng>::from_seed(
+            seed,
+        ))
+        .map_err(|_| ErrorCode::Internal)?;
+    bundle
+        .update_with(|mut updater| {
+            for (i, (_, user_address, _, _)) in outputs.iter().enumerate() {
+                let index = meta.output_action_index(i).expect("output index");
+                updater.update_action_with(index, |mut action| {
+                    action.set_output_user_address(user_address.clone());
+                    Ok(())
+                })?;
+            }
+            Ok(())
+        })
+        .map_err(|_| ErrorCode::Internal)?;
+
+    let pczt = pczt::roles::creator::Creator::build_from_parts(PcztParts {
+        params: PlanParams(network),
+        version: TxVersion::suggested_for_branch(branch_id),
+        consensus_branch_id: branch_id,
+        lock_time: 0,
+        expiry_height: BlockHeight::from_u32(req.expiry_height),
+        transparent: None,
+        sapling: None,
+        orchard: Some(bundle),
+    })
+    .ok_or(ErrorCode::Internal)?;
+    Ok(pczt)
+}
+
+fn request_seed(req: &PcztFromPlanRequest) -> Result<[u8; 32], ErrorCode> {
+    if req.seed_hex.trim().is_empty() {
+        let mut seed = [0u8; 32];
+        rand::RngCore::fill_bytes(&mut rand::rngs::OsRng, &mut seed);
+        return Ok(seed);
+    }
+    parse_hex_n::<32>(&req.seed_hex)
+}
+
+fn pczt_from_plan_inner(req_json: *const c_char) -> Result<PcztResponse, PcztError> {
+    let req: PcztFromPlanRequest = read_request(req_json)?;
+    let pczt = build_plan_pczt(&req, request_seed(&req)?)?;
+    let pczt = pczt::roles::updater::Updater::new(pczt)
+        .update_global_with(|mut global| {
+            global.set_proprietary(PCZT_PLAN_KEY.to_string(), req.plan_json.into_bytes());
+        })
+        .finish();
+
+    Ok(PcztResponse::Ok {
+        pczt_hex: Some(hex::encode(pczt.serialize())),
+        branch_id: None,
+        expiry_height: None,
+        anchor: None,
+        plan_json: None,
+        spends: None,
+        outputs: None,
+    })
+}
+
+fn pczt_parse_inner(req_json: *const c_char) -> Result<PcztResponse, PcztError> {
+    use orchard::note::{RandomSeed, Rho};
+    use orchard::note_encryption::OrchardDomain;
+    use orchard::value::NoteValue;
+    use zcash_note_encryption::{
+        try_output_recovery_with_ovk, try_output_recovery_with_pkd_esk, Domain,
+    };
+
+    let req: PcztParseRequest = read_request(req_json)?;
+    let b = hex::decode(req.pczt_hex.trim()).map_err(|_| ErrorCode::InvalidRequest)?;
+    let pczt = pczt::Pczt::parse(&b).map_err(|_| ErrorCode::InvalidRequest)?;
+
+    let plan_json = pczt
+        .global()
+        .proprietary()
+        .get(PCZT_PLAN_KEY)
+        .ok_or(PcztError::NotFromPlan)?;
+    let plan_json = String::from_utf8(plan_json.clone()).map_err(|_| ErrorCode::InvalidRequest)?;
+    let plan: EmbeddedPlan =
+        serde_json::from_str(&plan_json).map_err(|_| ErrorCode::InvalidRequest)?;
+
+    // The transaction the PCZT describes: its actions carry the output
+    // ciphertexts, in the order of the PCZT's actions.
+    let tx = pczt::Pczt::parse(&b)
+        .map_err(|_| ErrorCode::InvalidRequest)?
+        .into_effects()
+        .ok_or(ErrorCode::InvalidRequest)?;
+    let bundle = tx.orchard_bundle().ok_or(ErrorCode::InvalidRequest)?;
+    if bundle.actions().len() != pczt.orchard().actions().len() {
+        return Err(ErrorCode::InvalidRequest.into());
+    }
+
+    let mut fvk = None;
+    let mut spends = Vec::new();
+    for action in pczt.orchard().actions() {
+        let spend = action.spend();
+        if spend.dummy_sk().is_none() {
+            let (recipient, value, rho, rseed, witness) = match (
+                spend.recipient(),
+                spend.value(),
+                spend.rho(),
+                spend.rseed(),
+                spend.witness(),
+            ) {
+                (Some(a), Some(v), Some(r), Some(s), Some(w)) => (a, *v, r, s, w),
+                _ => return Err(ErrorCode::InvalidRequest.into()),
+            };
+            let rho_ct = Rho::from_bytes(rho);
+            if bool::from(rho_ct.is_none()) {
+                return Err(ErrorCode::InvalidRequest.into());
+            }
+            let rho_v = rho_ct.unwrap();
+            let rseed_ct = RandomSeed::from_bytes(*rseed, &rho_v);
+            if bool::from(rseed_ct.is_none()) {
+                return Err(ErrorCode::InvalidRequest.into());
+            }
+            let addr_ct = orchard::Address::from_raw_address_bytes(recipient);
+            if bool::from(addr_ct.is_none()) {
+                return Err(ErrorCode::InvalidRequest.into());
+            }
+            let note_ct = orchard::Note::from_parts(
+                addr_ct.unwrap(),
+                NoteValue::from_raw(value),
+                rho_v,
+                rseed_ct.unwrap(),
+            );
+            if bool::from(note_ct.is_none()) {
+                return Err(ErrorCode::InvalidRequest.into());
+            }
+            let cmx = ExtractedNoteCommitment::from(note_ct.unwrap().commitment());
+            spends.push(PcztSpendOut {
+                position: witness.0,
+                rho: hex::encode(rho),
+                cmx: hex::encode(cmx.to_bytes()),
+                value_zat: value,
+            });
+            if fvk.is_none() {
+                let b = spend.fvk().as_ref().ok_or(ErrorCode::InvalidRequest)?;
+                fvk = Some(FullViewingKey::from_bytes(b).ok_or(ErrorCode::InvalidRequest)?);
+            }
+        }
+    }
+    let fvk = fvk.ok_or(ErrorCode::InvalidRequest)?;
+    let ovk = plan_ovk(&fvk, &plan.ovk_policy)?;
+
+    // Every output with a user_address is one of the plan's (dummy outputs
+    // have none). Its note must be the one the action commits to, and its
+    // ciphertexts must carry the memo and be recoverable with the plan's OVK.
+    let mut outputs = Vec::new();
+    for (action, effect) in pczt.orchard().actions().iter().zip(bundle.actions().iter()) {
+        let output = action.output();
+        let user_address = match output.user_address() {
+            Some(a) => a,
+            None => continue,
+        };
+        let (recipient, value, rseed) = match (output.recipient(), output.value(), output.rseed()) {
+            (Some(a), Some(v), Some(s)) => (a, *v, s),
+            _ => return Err(ErrorCode::InvalidRequest.into()),
+        };
+        // The output note's rho is the nullifier spent by the same action.
+        let rho_ct = Rho::from_bytes(&effect.nullifier().to_bytes());
+        if bool::from(rho_ct.is_none()) {
+            return Err(ErrorCode::InvalidRequest.into());
+        }
+        let rho_v = rho_ct.unwrap();
+        let rseed_ct = RandomSeed::from_bytes(*rseed, &rho_v);
+        if bool::from(rseed_ct.is_none()) {
+            return Err(ErrorCode::InvalidRequest.into());
+        }
+        let addr_ct = orchard::Address::from_raw_address_bytes(recipient);
+        if bool::from(addr_ct.is_none()) {
+            return Err(ErrorCode::InvalidRequest.into());
+        }
+        let note_ct = orchard::Note::from_parts(
+            addr_ct.unwrap(),
+            NoteValue::from_raw(value),
+            rho_v,
+            rseed_ct.unwrap(),
+        );
+        if bool::from(note_ct.is_none()) {
+            return Err(ErrorCode::InvalidRequest.into());
+        }
+        let note = note_ct.unwrap();
+        let cmx = ExtractedNoteCommitment::from(note.commitment());
+        if cmx != *effect.cmx() {
+            return Err(PcztError::OutputMismatch);
+        }
+
+        let domain = OrchardDomain::for_action(effect);
+        let esk = OrchardDomain::derive_esk(&note).ok_or(PcztError::OutputMismatch)?;
+        let (_, _, memo) =
+            try_output_recovery_with_pkd_esk(&domain, OrchardDomain::get_pk_d(&note), esk, effect)
+                .ok_or(PcztError::OutputMismatch)?;
+
+        let out_ciphertext = &effect.encrypted_note().out_ciphertext;
+        let recovers = |k: &orchard::keys::OutgoingViewingKey| {
+            try_output_recovery_with_ovk(&domain, k, effect, effect.cv_net(), out_ciphertext)
+                .is_some()
+        };
+        let ovk_ok = match &ovk {
+            Some(k) => recovers(k),
+            None => {
+                !recovers(&fvk.to_ovk(Scope::External)) && !recovers(&fvk.to_ovk(Scope::Internal))
+            }
+        };
+        if !ovk_ok {
+            return Err(PcztError::OvkMismatch);
+        }
+
+        outputs.push(PcztOutputOut {
+            user_address: user_address.clone(),
+            recipient: hex::encode(recipient),
+            value_zat: value,
+            cmx: hex::encode(cmx.to_bytes()),
+            memo_hex: hex::encode(memo),
+        });
+    }
+
+    Ok(PcztResponse::Ok {
+        pczt_hex: None,
+        branch_id: Some(*pczt.global().consensus_branch_id()),
+        expiry_height: Some(*pczt.global().expiry_height()),
+        anchor: Some(hex::encode(pczt.orchard().anchor())),
+        plan_json: Some(plan_json),
+        spends: Some(spends),
+        outputs: Some(outputs),
+    })
+}
+
+fn pczt_response(res: std::thread::Result<Result<PcztResponse, PcztError>>) -> *mut c_char {
+    match res {
+        Ok(Ok(v)) => to_c_string(v),
+        Ok(Err(e)) => to_c_string(PcztResponse::Err {
+            error: e.as_str().to_string(),
+        }),
+        Err(_) => to_c_string(PcztResponse::Err {
+            error: ErrorCode::Panic.as_str().to_string(),
+        }),
+    }
+}
+
+#[no_mangle]
+pub extern "C" fn juno_txbuild_pczt_from_plan_json(req_json: *const c_char) -> *mut c_char {
+    pczt_response(std::panic::catch_unwind(|| pczt_from_plan_inner(req_json)))
+}
+
+#[no_mangle]
+pub extern "C" fn juno_txbuild_pczt_parse_json(req_json: *const c_char) -> *mut c_char {
+    pczt_response(std::panic::catch_unwind(|| pczt_parse_inner(req_json)))
+}
+
+// Unsigned v5 transaction skeleton and ZIP-244 digests.
+
+#[derive(Debug, Serialize)]
+struct SkeletonAction {
+    cv_net: String,
+    nullifier: String,
+    rk: String,
+    cmx: String,
+    ephemeral_key: String,
+    enc_ciphertext: String,
+    out_ciphertext: String,
+}
+
+#[derive(Debug, Serialize)]
+struct SkeletonOrchard {
+    flags: u8,
+    value_balance: i64,
+    anchor: String,
+    actions: Vec<SkeletonAction>,
+}
+
+#[derive(Debug, Serialize)]
+struct Skeleton {
+    header: u32,
+    version_group_id: u32,
+    branch_id: u32,
+    lock_time: u32,
+    expiry_height: u32,
+    orchard: SkeletonOrchard,
+}
+
+#[derive(Debug, Serialize)]
+#[serde(tag = "status", rename_all = "snake_case")]
+enum SkeletonResponse {
+    Ok {
+        skeleton: Skeleton,
+        header_digest: String,
+        orchard_digest: String,
+        // For a transaction without transparent inputs, the ZIP-244 txid
+        // digest is also the sighash the spend authorizations sign.
+        sighash: String,
+        txid: String,
+    },
+    Err {
+        error: String,
+    },
+}
+
+fn tx_skeleton_inner(req_json: *const c_char) -> Result<SkeletonResponse, PcztError> {
+    use zcash_primitives::transaction::sighash::{signature_hash, SignableInput};
+    use zcash_primitives::transaction::txid::TxIdDigester;
+
+    let req: PcztFromPlanRequest = read_request(req_json)?;
+    if req.seed_hex.trim().is_empty() {
+        return Err(ErrorCode::InvalidRequest.into());
+    }
+    let pczt = build_plan_pczt(&req, request_seed(&req)?)?;
+
+    let tx = pczt.into_effects().ok_or(ErrorCode::Internal)?;
+    let digests = tx.digest(TxIdDigester);
+    let sighash = signature_hash(&tx, &SignableInput::Shielded, &digests);
+    let sighash: [u8; 32] = sighash
+        .as_ref()
+        .try_into()
+        .map_err(|_| ErrorCode::Internal)?;
+    let mut txid = sighash;
+    txid.reverse();
+    let orchard_digest = digests.orchard_digest.ok_or(ErrorCode::Internal)?;
+
+    let bundle = tx.orchard_bundle().ok_or(ErrorCode::Internal)?;
+    let actions = bundle
+        .actions()
+        .iter()
+        .map(|a| {
+            let note = a.encrypted_note();
+            SkeletonAction {
+                cv_net: hex::encode(a.cv_net().to_bytes()),
+                nullifier: hex::encode(a.nullifier().to_bytes()),
+                rk: hex::encode(<[u8; 32]>::from(a.rk().clone())),
+                cmx: hex::encode(a.cmx().to_bytes()),
+                ephemeral_key: hex::encode(note.epk_bytes),
+                enc_ciphertext: hex::encode(note.enc_ciphertext),
+                out_ciphertext: hex::encode(note.out_ciphertext),
+            }
+        })
+        .collect::<Vec<_>>();
+
+    Ok(SkeletonResponse::Ok {
+        skeleton: Skeleton {
+            header: tx.version().header(),
+            version_group_id: tx.version().version_group_id(),
+            branch_id: u32::from(tx.consensus_branch_id()),
+            lock_time: tx.lock_time(),
+            expiry_height: u32::from(tx.expiry_height()),
+            orchard: SkeletonOrchard {
+                flags: bundle.flags().to_byte(),
+                value_balance: i64::from(*bundle.value_balance()),
+                anchor: hex::encode(bundle.anchor().to_bytes()),
+                actions,
+            },
+        },
+        header_digest: hex::encode(digests.header_digest.as_bytes()),
+        orchard_digest: hex::encode(orchard_digest.as_bytes()),
+        sighash: hex::encode(sighash),
+        txid: hex::encode(txid),
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn juno_txbuild_tx_skeleton_json(req_json: *const c_char) -> *mut c_char {
+    match std::panic::catch_unwind(|| tx_skeleton_inner(req_json)) {
+        Ok(Ok(v)) => to_c_string(v),
+        Ok(Err(e)) => to_c_string(SkeletonResponse::Err {
+            error: e.as_str().to_string(),
+        }),
+        Err(_) => to_c_string(SkeletonResponse::Err {
+            error: ErrorCode::Panic.as_str().to_string(),
+        }),
+    }
+}